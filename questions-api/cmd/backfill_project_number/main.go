@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gerdinv/questions-api/database"
+	"github.com/joho/godotenv"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Backfills properties.projectNumber on runner_events from the string
+// properties.projectId, matching what CreateRunnerEvent now writes.
+// Dry run by default; pass -dry-run=false to apply.
+
+var (
+	dryRun     bool
+	batchSize  int
+	maxUpdates int
+	includeDev bool
+)
+
+func main() {
+	flag.BoolVar(&dryRun, "dry-run", true, "Perform a dry run without updating documents")
+	flag.IntVar(&batchSize, "batch-size", 5000, "Number of documents to process in a batch")
+	flag.IntVar(&maxUpdates, "max-updates", 0, "Maximum number of documents to update (0 = unlimited)")
+	flag.BoolVar(&includeDev, "include-dev", true, "Also backfill runner_events in the dev database")
+	flag.Parse()
+
+	// Load env vars
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found, relying on system env vars")
+	}
+
+	log.Printf("🚀 Starting runner_events projectNumber backfill")
+	log.Printf("==================================================")
+	log.Printf("   Dry Run:           %v", dryRun)
+	log.Printf("   Batch Size:        %d", batchSize)
+	log.Printf("   Max Updates:       %d (0=unlimited)", maxUpdates)
+	log.Printf("   Include Dev DB:    %v", includeDev)
+	log.Printf("==================================================")
+	if !dryRun {
+		log.Println("⚠️  RUNNING IN NON-DRY-RUN MODE. CHANGES WILL BE APPLIED.")
+		log.Println("   Waiting 5 seconds before starting...")
+		time.Sleep(5 * time.Second)
+	}
+
+	database.ConnectMongoDB()
+
+	appDb := database.GetAppDb()
+	log.Printf("✅ Connected to App DB: %s", appDb.Name())
+	if err := backfillProjectNumbers(appDb.Collection("runner_events"), appDb.Name()); err != nil {
+		log.Fatalf("❌ Failed to backfill %s.runner_events: %v", appDb.Name(), err)
+	}
+
+	if includeDev {
+		devDb := database.GetDevDb()
+		if devDb.Name() != appDb.Name() {
+			if err := backfillProjectNumbers(devDb.Collection("runner_events"), devDb.Name()); err != nil {
+				log.Fatalf("❌ Failed to backfill %s.runner_events: %v", devDb.Name(), err)
+			}
+		}
+	}
+
+	log.Println("✨ Backfill completed successfully")
+}
+
+func backfillProjectNumbers(coll *mongo.Collection, dbName string) error {
+	log.Printf("Start processing %s.runner_events...", dbName)
+	ctx := context.Background()
+
+	filter := bson.M{
+		"properties.projectId":     bson.M{"$exists": true, "$ne": ""},
+		"properties.projectNumber": bson.M{"$exists": false},
+	}
+
+	total, err := coll.CountDocuments(ctx, filter)
+	if err != nil {
+		return err
+	}
+	log.Printf("   Found %d documents needing backfill", total)
+	if total == 0 {
+		return nil
+	}
+
+	cursor, err := coll.Find(ctx, filter)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	processed := 0
+	updated := 0
+	unparseable := 0
+	var operations []mongo.WriteModel
+
+	flush := func() error {
+		if len(operations) == 0 {
+			return nil
+		}
+		if !dryRun {
+			if _, err := coll.BulkWrite(ctx, operations); err != nil {
+				return fmt.Errorf("bulk write error: %w", err)
+			}
+		}
+		operations = nil
+		return nil
+	}
+
+	for cursor.Next(ctx) {
+		var doc struct {
+			ID         bson.RawValue          `bson:"_id"`
+			Properties map[string]interface{} `bson:"properties"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			log.Printf("   Error decoding doc: %v", err)
+			continue
+		}
+		processed++
+
+		projectNumber, ok := database.DeriveProjectNumber(doc.Properties)
+		if !ok {
+			unparseable++
+			if unparseable <= 10 {
+				log.Printf("      [Unparseable sample] ID: %v | projectId: %v", doc.ID, doc.Properties["projectId"])
+			}
+			continue
+		}
+
+		operations = append(operations, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": doc.ID}).
+			SetUpdate(bson.M{"$set": bson.M{"properties.projectNumber": projectNumber}}))
+		updated++
+
+		if len(operations) >= batchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+			log.Printf("   Processed %d/%d...", processed, total)
+		}
+
+		if maxUpdates > 0 && updated >= maxUpdates {
+			log.Printf("🛑 Reached max-updates limit (%d). Stopping early.", maxUpdates)
+			break
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+
+	log.Printf("   Finished %s: Scanned %d, To Update %d, Unparseable %d", dbName, processed, updated, unparseable)
+	return nil
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/gerdinv/questions-api/database"
+	"github.com/joho/godotenv"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Sets environment on decision_trace_sessions written before sessions were keyed by
+// environment. Session lookups still accept environment-less sessions, but this lets
+// that fallback be removed. An active legacy session that collides with an active
+// session already created in the same environment is ended instead of tagged.
+// Dry run by default; pass -dry-run=false to apply.
+
+var (
+	dryRun      bool
+	environment string
+	maxUpdates  int
+)
+
+func main() {
+	flag.BoolVar(&dryRun, "dry-run", true, "Perform a dry run without updating documents")
+	flag.StringVar(&environment, "environment", "", `Environment to record (default: APP_ENV, else "production" when NODE_ENV=production, else "development")`)
+	flag.IntVar(&maxUpdates, "max-updates", 0, "Maximum number of sessions to update (0 = unlimited)")
+	flag.Parse()
+
+	// Load env vars
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found, relying on system env vars")
+	}
+	if environment == "" {
+		environment = defaultEnvironment()
+	}
+
+	log.Printf("🚀 Starting decision_trace_sessions environment backfill")
+	log.Printf("==================================================")
+	log.Printf("   Dry Run:           %v", dryRun)
+	log.Printf("   Environment:       %s", environment)
+	log.Printf("   Max Updates:       %d (0=unlimited)", maxUpdates)
+	log.Printf("==================================================")
+	if !dryRun {
+		log.Println("⚠️  RUNNING IN NON-DRY-RUN MODE. CHANGES WILL BE APPLIED.")
+		log.Println("   Waiting 5 seconds before starting...")
+		time.Sleep(5 * time.Second)
+	}
+
+	database.ConnectMongoDB()
+
+	appDb := database.GetAppDb()
+	log.Printf("✅ Connected to App DB: %s", appDb.Name())
+	if err := backfillEnvironment(appDb.Collection("decision_trace_sessions")); err != nil {
+		log.Fatalf("❌ Failed to backfill %s.decision_trace_sessions: %v", appDb.Name(), err)
+	}
+
+	log.Println("✨ Backfill completed successfully")
+}
+
+// defaultEnvironment mirrors the server's resolveAppEnvironment
+func defaultEnvironment() string {
+	if env := os.Getenv("APP_ENV"); env != "" {
+		return env
+	}
+	if os.Getenv("NODE_ENV") == "production" {
+		return "production"
+	}
+	return "development"
+}
+
+func backfillEnvironment(coll *mongo.Collection) error {
+	ctx := context.Background()
+	legacy := bson.M{"environment": bson.M{"$exists": false}}
+
+	total, err := coll.CountDocuments(ctx, legacy)
+	if err != nil {
+		return err
+	}
+	log.Printf("   Found %d sessions without environment", total)
+	if total == 0 {
+		return nil
+	}
+
+	// Ended sessions are outside the partial unique index, so they can be tagged in one write
+	ended := bson.M{"environment": bson.M{"$exists": false}, "status": bson.M{"$ne": "active"}}
+	endedCount, err := coll.CountDocuments(ctx, ended)
+	if err != nil {
+		return err
+	}
+	if !dryRun && endedCount > 0 {
+		if _, err := coll.UpdateMany(ctx, ended, bson.M{"$set": bson.M{"environment": environment}}); err != nil {
+			return fmt.Errorf("failed to tag ended sessions: %w", err)
+		}
+	}
+	log.Printf("   Ended sessions tagged: %d", endedCount)
+
+	// Active sessions one at a time: tagging one may collide with a newer active session
+	cursor, err := coll.Find(ctx, bson.M{"environment": bson.M{"$exists": false}, "status": "active"})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	tagged, closed := 0, 0
+	for cursor.Next(ctx) {
+		var doc struct {
+			ID primitive.ObjectID `bson:"_id"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			log.Printf("   Error decoding doc: %v", err)
+			continue
+		}
+		if maxUpdates > 0 && tagged+closed >= maxUpdates {
+			log.Printf("🛑 Reached max-updates limit (%d). Stopping early.", maxUpdates)
+			break
+		}
+		if dryRun {
+			tagged++
+			continue
+		}
+
+		_, err := coll.UpdateOne(ctx, bson.M{"_id": doc.ID}, bson.M{"$set": bson.M{"environment": environment}})
+		if mongo.IsDuplicateKeyError(err) {
+			// A session was already started in this environment; it is the current one
+			now := time.Now()
+			_, err = coll.UpdateOne(ctx, bson.M{"_id": doc.ID}, bson.M{"$set": bson.M{
+				"environment": environment,
+				"status":      "ended",
+				"endedAt":     now,
+			}})
+			if err != nil {
+				return fmt.Errorf("failed to end duplicate session %s: %w", doc.ID.Hex(), err)
+			}
+			if closed < 10 {
+				log.Printf("      [Ended] %s duplicates an active %s session", doc.ID.Hex(), environment)
+			}
+			closed++
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to tag session %s: %w", doc.ID.Hex(), err)
+		}
+		tagged++
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+
+	if dryRun {
+		log.Printf("   Active sessions to tag: %d (collisions are only detected on a real run)", tagged)
+	} else {
+		log.Printf("   Active sessions tagged: %d, ended as duplicates: %d", tagged, closed)
+	}
+	return nil
+}
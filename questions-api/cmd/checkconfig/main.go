@@ -0,0 +1,35 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/gerdinv/questions-api/config"
+)
+
+// Validates an env file (plus the process environment) against the .env.example
+// contract without connecting to MongoDB or starting the server, so CI and deploy
+// scripts can catch missing keys before boot. Exits 1 on failure.
+//
+// Usage: go run ./cmd/checkconfig [-contract .env.example] [-env .env]
+
+func main() {
+	contractPath := flag.String("contract", ".env.example", "Path to the env contract file")
+	envPath := flag.String("env", ".env", "Path to the env file (optional; process env vars are always included)")
+	flag.Parse()
+
+	contract, err := os.ReadFile(*contractPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ failed to read contract %q: %v\n", *contractPath, err)
+		os.Exit(1)
+	}
+	config.Init(string(contract))
+
+	if err := config.Check(*envPath); err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+
+	fmt.Printf("✅ %s satisfies contract %s\n", *envPath, *contractPath)
+}
@@ -15,6 +15,7 @@ import (
 	"time"
 
 	"github.com/gerdinv/questions-api/database"
+	"github.com/gerdinv/questions-api/shared"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -236,6 +237,7 @@ func computeSessionSignals(sessions []database.SessionArtifactDocument) sessionS
 	totalRuns := 0.0
 	fullPass := 0
 	narrativeFlags := 0
+	claimPhrases := shared.NarrativeClaimPhrases(os.Getenv("NARRATIVE_CLAIM_PHRASES"))
 	for _, s := range sessions {
 		runCount := numFromMap(s.Summary, "runCount")
 		if runCount == 0 {
@@ -254,7 +256,7 @@ func computeSessionSignals(sessions []database.SessionArtifactDocument) sessionS
 		}
 		narrative := strings.ToLower(strings.TrimSpace(strFromNestedMap(s.Summary, "narratives", "narrative")))
 		if narrative != "" {
-			claimsAllPass := strings.Contains(narrative, "all tests passed") || strings.Contains(narrative, "full pass")
+			claimsAllPass := shared.NarrativeClaimsFullPass(narrative, claimPhrases)
 			if claimsAllPass {
 				passed := false
 				if len(outcomes) > 0 {
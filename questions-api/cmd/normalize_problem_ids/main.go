@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gerdinv/questions-api/database"
+	"github.com/joho/godotenv"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Normalizes legacy project browser_submissions.problemId values with surrounding
+// whitespace or leading zeros (" 7", "07" -> "7"), matching what
+// CreateBrowserSubmission now writes. Funnel queries match problemId exactly,
+// so these rows were silently undercounted. Non-project submissions are left alone.
+// Dry run by default; pass -dry-run=false to apply.
+
+var (
+	dryRun     bool
+	batchSize  int
+	maxUpdates int
+	includeDev bool
+)
+
+func main() {
+	flag.BoolVar(&dryRun, "dry-run", true, "Perform a dry run without updating documents")
+	flag.IntVar(&batchSize, "batch-size", 5000, "Number of documents to process in a batch")
+	flag.IntVar(&maxUpdates, "max-updates", 0, "Maximum number of documents to update (0 = unlimited)")
+	flag.BoolVar(&includeDev, "include-dev", true, "Also normalize browser_submissions in the dev database")
+	flag.Parse()
+
+	// Load env vars
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found, relying on system env vars")
+	}
+
+	log.Printf("🚀 Starting browser_submissions problemId normalization")
+	log.Printf("==================================================")
+	log.Printf("   Dry Run:           %v", dryRun)
+	log.Printf("   Batch Size:        %d", batchSize)
+	log.Printf("   Max Updates:       %d (0=unlimited)", maxUpdates)
+	log.Printf("   Include Dev DB:    %v", includeDev)
+	log.Printf("==================================================")
+	if !dryRun {
+		log.Println("⚠️  RUNNING IN NON-DRY-RUN MODE. CHANGES WILL BE APPLIED.")
+		log.Println("   Waiting 5 seconds before starting...")
+		time.Sleep(5 * time.Second)
+	}
+
+	database.ConnectMongoDB()
+
+	appDb := database.GetAppDb()
+	log.Printf("✅ Connected to App DB: %s", appDb.Name())
+	if err := normalizeProblemIDs(appDb.Collection("browser_submissions"), appDb.Name()); err != nil {
+		log.Fatalf("❌ Failed to normalize %s.browser_submissions: %v", appDb.Name(), err)
+	}
+
+	if includeDev {
+		devDb := database.GetDevDb()
+		if devDb.Name() != appDb.Name() {
+			if err := normalizeProblemIDs(devDb.Collection("browser_submissions"), devDb.Name()); err != nil {
+				log.Fatalf("❌ Failed to normalize %s.browser_submissions: %v", devDb.Name(), err)
+			}
+		}
+	}
+
+	log.Println("✨ Normalization completed successfully")
+}
+
+func normalizeProblemIDs(coll *mongo.Collection, dbName string) error {
+	log.Printf("Start processing %s.browser_submissions...", dbName)
+	ctx := context.Background()
+
+	// Project rows with leading/trailing whitespace, or a leading zero followed by more digits
+	filter := bson.M{
+		"sourceType": "project",
+		"problemId":  primitive.Regex{Pattern: `^\s|\s$|^0[0-9]`},
+	}
+
+	total, err := coll.CountDocuments(ctx, filter)
+	if err != nil {
+		return err
+	}
+	log.Printf("   Found %d documents needing normalization", total)
+	if total == 0 {
+		return nil
+	}
+
+	cursor, err := coll.Find(ctx, filter)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	processed := 0
+	updated := 0
+	unchanged := 0
+	var operations []mongo.WriteModel
+
+	flush := func() error {
+		if len(operations) == 0 {
+			return nil
+		}
+		if !dryRun {
+			if _, err := coll.BulkWrite(ctx, operations); err != nil {
+				return fmt.Errorf("bulk write error: %w", err)
+			}
+		}
+		operations = nil
+		return nil
+	}
+
+	for cursor.Next(ctx) {
+		var doc struct {
+			ID        bson.RawValue `bson:"_id"`
+			ProblemID string        `bson:"problemId"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			log.Printf("   Error decoding doc: %v", err)
+			continue
+		}
+		processed++
+
+		normalized := database.NormalizeProblemID(doc.ProblemID)
+		if normalized == doc.ProblemID {
+			unchanged++
+			continue
+		}
+		if updated < 10 {
+			log.Printf("      [Sample] ID: %v | %q -> %q", doc.ID, doc.ProblemID, normalized)
+		}
+
+		operations = append(operations, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": doc.ID}).
+			SetUpdate(bson.M{"$set": bson.M{"problemId": normalized}}))
+		updated++
+
+		if len(operations) >= batchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+			log.Printf("   Processed %d/%d...", processed, total)
+		}
+
+		if maxUpdates > 0 && updated >= maxUpdates {
+			log.Printf("🛑 Reached max-updates limit (%d). Stopping early.", maxUpdates)
+			break
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+
+	log.Printf("   Finished %s: Scanned %d, To Update %d, Unchanged %d", dbName, processed, updated, unchanged)
+	return nil
+}
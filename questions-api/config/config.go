@@ -9,6 +9,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // envExampleContract holds the embedded .env.example contract.
@@ -90,7 +91,7 @@ type Config struct {
 	RunnerContractVersion string
 	EnableLegacyRunner    bool
 
-	// Commonly used elsewhere; harmless if unused (can delete if you want).
+	// Selects the app DB; must be one of AllowedNodeEnvs (empty means development).
 	NodeEnv string
 	Port    int
 
@@ -101,6 +102,9 @@ type Config struct {
 	MongoDbAppDev  string
 	MongoDbAppStaging string
 
+	// Optional read replica for heavy admin analytics (falls back to MONGO_URI when empty)
+	MongoAnalyticsUri string
+
 	// Supabase configuration
 	SupabaseUrl            string
 	SupabaseServiceRoleKey string
@@ -114,6 +118,62 @@ type Config struct {
 	ReferralWebhookSecret  string
 	WhitelistWebhookSecret string
 
+	// Report cards (optional; zero values fall back to handler defaults)
+	ReportCardMaxSessionWindow int
+	// Kill switch for LLM report generation; empty means enabled (string so unset != false)
+	ReportCardsEnabled string
+	// Comma-separated Gemini models callers may request; empty allows only the default model
+	ReportCardAllowedModels string
+	// Comma-separated Gemini models tried in order when the requested model is unavailable
+	ReportCardFallbackModels string
+	// Comma-separated phrases replacing the embedded narrative success-claim list
+	NarrativeClaimPhrases string
+
+	// Background reaper for stale decision trace sessions (optional, off by default;
+	// zero minutes fall back to database defaults)
+	DecisionTraceReaperEnabled         bool
+	DecisionTraceReaperIntervalMinutes int
+	DecisionTraceSessionMaxIdleMinutes int
+
+	// Max bytes of Mermaid text stored per decision trace event (optional; 0 uses the handler default)
+	DecisionTraceMaxMermaidBytes int
+
+	// Skip a decision trace event identical (type + code SHA) to the session's last event
+	// if it arrives within this many milliseconds (optional; 0 disables dedup)
+	DecisionTraceDedupWindowMs int
+
+	// Expire decision trace events this many days after createdAt via a TTL index
+	// (optional; 0 or unset keeps events forever)
+	DecisionTraceEventRetentionDays int
+
+	// Max changed characters vs starter files for a passing submission to be flagged
+	// passedWithStarter (optional; 0 means only byte-identical code is flagged)
+	StarterCodeMaxDiffChars int
+
+	// Max entries in the shared project title LRU used by analytics
+	// (optional; 0 uses the database default, negative disables caching)
+	ProjectTitleCacheSize int
+
+	// Funnel activation definition: "ran", "submitted" or "passed" a real project
+	// (optional; empty or unknown means "submitted")
+	ActivationDefinition string
+
+	// Exit at startup in production when a critical index is missing
+	// (optional, off by default; missing indexes are always logged)
+	IndexVerificationFailFast bool
+
+	// Comma-separated problemIds (e.g. tutorials, demos) left out of execution-time and
+	// pass-rate analytics (optional; empty excludes nothing, ?exclude_problem_ids= overrides)
+	AnalyticsExcludedProblemIds string
+
+	// API-wide page sizes for list endpoints without their own limits
+	// (optional; 0 uses 20 / 100)
+	PaginationDefaultLimit int
+	PaginationMaxLimit     int
+
+	// IANA timezone for analytics day/week buckets (optional; empty means UTC)
+	ReportingTimezone string
+
 	// Deployment metadata (optional, may be empty locally)
 	GitCommitSha string
 	DeployedAt   string
@@ -125,7 +185,22 @@ type Config struct {
 // - Step 1C/3/4/5: validate requiredKeys against envMap (missing/empty policy)
 // - Step 6: return a useful config object (typed Config) for the app
 func GetConfig() Config {
-	const envPath = ".env"
+	cfg, err := loadConfig(".env")
+	if err != nil {
+		fatal(err)
+	}
+	return cfg
+}
+
+// Check runs the same load + contract validation as GetConfig against envPath
+// (plus the process environment) and returns the error instead of exiting.
+// Used by cmd/checkconfig to verify configuration before deploying.
+func Check(envPath string) error {
+	_, err := loadConfig(envPath)
+	return err
+}
+
+func loadConfig(envPath string) (Config, error) {
 	const allowEmptyValues = false
 
 	// (1A) Initialize envMap
@@ -137,7 +212,7 @@ func GetConfig() Config {
 		// If the error is anything OTHER than "file not found", crash.
 		// If it IS "file not found", just log it and proceed (Cloud mode).
 		if !os.IsNotExist(err) {
-			fatal(fmt.Errorf("failed to parse env file %q: %w", envPath, err))
+			return Config{}, fmt.Errorf("failed to parse env file %q: %w", envPath, err)
 		}
 		// Optional: Log that we are running without a .env file
 		// fmt.Println("No .env file found; using system environment variables.")
@@ -159,27 +234,47 @@ func GetConfig() Config {
 
 	// (1B/2) Contract: parse embedded .env.example -> requiredKeys (no file I/O needed)
 	if envExampleContract == "" {
-		fatal(fmt.Errorf("config.Init() must be called before GetConfig() - no embedded contract set"))
+		return Config{}, fmt.Errorf("config.Init() must be called before GetConfig() - no embedded contract set")
 	}
 	requiredKeys, err := readKeysFromExample()
 	if err != nil {
-		fatal(fmt.Errorf("failed to read embedded contract: %w", err))
+		return Config{}, fmt.Errorf("failed to read embedded contract: %w", err)
 	}
 	if len(requiredKeys) == 0 {
-		fatal(fmt.Errorf("embedded contract contained no keys"))
+		return Config{}, fmt.Errorf("embedded contract contained no keys")
 	}
 
 	// (1C/3/4/5) Validate: requiredKeys vs envMap (now contains both File + System vars)
 	if err := validateEnvMap(requiredKeys, envMap, envPath, allowEmptyValues); err != nil {
-		fatal(err)
+		return Config{}, err
 	}
 
 	// (6) Return useful config object
 	cfg, err := loadStructFromEnvMap[Config](envMap)
 	if err != nil {
-		fatal(err)
+		return Config{}, err
 	}
-	return cfg
+	if err := ValidateNodeEnv(cfg.NodeEnv); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// AllowedNodeEnvs are the only accepted NODE_ENV values. A typo such as "prod"
+// would otherwise fall through to development and connect to the dev DB.
+var AllowedNodeEnvs = []string{"production", "staging", "development"}
+
+// ValidateNodeEnv returns an error unless nodeEnv is empty or exactly one of AllowedNodeEnvs
+func ValidateNodeEnv(nodeEnv string) error {
+	if nodeEnv == "" {
+		return nil
+	}
+	for _, allowed := range AllowedNodeEnvs {
+		if nodeEnv == allowed {
+			return nil
+		}
+	}
+	return fmt.Errorf("❌ NODE_ENV=%q is not allowed; must be one of: %s", nodeEnv, strings.Join(AllowedNodeEnvs, ", "))
 }
 
 // -------------------- Step 1C/3/4/5: Validation --------------------
@@ -302,6 +397,8 @@ func stripQuotes(v string) string {
 
 // loadStructFromEnvMap fills struct fields by converting field name -> SCREAMING_SNAKE env key.
 // Example: RunnerContractVersion -> RUNNER_CONTRACT_VERSION
+// Supported field types: string, bool, int, float64 and time.Duration (Go duration
+// strings like "15s" or "1m30s"). Empty values leave the zero value.
 func loadStructFromEnvMap[T any](envMap map[string]string) (T, error) {
 	var out T
 	val := reflect.ValueOf(&out).Elem()
@@ -314,6 +411,20 @@ func loadStructFromEnvMap[T any](envMap map[string]string) (T, error) {
 		envKey := camelToScreamingSnake(sf.Name)
 		raw := envMap[envKey] // validation ensures required keys exist if they are in the contract
 
+		// time.Duration is an int64 kind, so match the type before the kind switch
+		if fv.Type() == durationType {
+			if raw == "" {
+				fv.SetInt(0)
+				continue
+			}
+			d, err := time.ParseDuration(strings.TrimSpace(raw))
+			if err != nil {
+				return out, fmt.Errorf("%s must be a duration like \"15s\" or \"1m30s\" (got %q)", envKey, raw)
+			}
+			fv.SetInt(int64(d))
+			continue
+		}
+
 		switch fv.Kind() {
 		case reflect.String:
 			fv.SetString(raw)
@@ -332,6 +443,17 @@ func loadStructFromEnvMap[T any](envMap map[string]string) (T, error) {
 			}
 			fv.SetInt(int64(n))
 
+		case reflect.Float64:
+			if raw == "" {
+				fv.SetFloat(0)
+				continue
+			}
+			f, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+			if err != nil {
+				return out, fmt.Errorf("%s must be float (got %q)", envKey, raw)
+			}
+			fv.SetFloat(f)
+
 		default:
 			return out, fmt.Errorf("unsupported field type %s for %s", fv.Kind(), sf.Name)
 		}
@@ -339,6 +461,8 @@ func loadStructFromEnvMap[T any](envMap map[string]string) (T, error) {
 	return out, nil
 }
 
+var durationType = reflect.TypeOf(time.Duration(0))
+
 func isTruthy(s string) bool {
 	s = strings.TrimSpace(strings.ToLower(s))
 	return s == "1" || s == "true" || s == "yes" || s == "y" || s == "on"
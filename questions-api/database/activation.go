@@ -0,0 +1,45 @@
+package database
+
+import (
+	"context"
+	"log"
+	"strings"
+)
+
+// Activation definitions for the funnel's "activated" stage. All consider real
+// projects only (projectNumber >= 1).
+const (
+	ActivationRan       = "ran"       // Ran code on a real project
+	ActivationSubmitted = "submitted" // Submitted a real project (default)
+	ActivationPassed    = "passed"    // Completed a real project (meets requiredPasses)
+)
+
+// DefaultActivationDefinition is used when ACTIVATION_DEFINITION is unset or invalid
+const DefaultActivationDefinition = ActivationSubmitted
+
+// ResolveActivationDefinition normalizes a configured activation definition,
+// falling back to DefaultActivationDefinition for empty or unknown values
+func ResolveActivationDefinition(raw string) string {
+	definition := strings.ToLower(strings.TrimSpace(raw))
+	switch definition {
+	case ActivationRan, ActivationSubmitted, ActivationPassed:
+		return definition
+	case "":
+		return DefaultActivationDefinition
+	default:
+		log.Printf("WARNING: unknown activation definition %q, using %q", raw, DefaultActivationDefinition)
+		return DefaultActivationDefinition
+	}
+}
+
+// CountActivatedUsers counts users meeting the given activation definition
+func CountActivatedUsers(ctx context.Context, definition string, excludedSupabaseUserIDs []string) (int, error) {
+	switch definition {
+	case ActivationRan:
+		return CountUsersWhoEnteredCurriculum(ctx, excludedSupabaseUserIDs)
+	case ActivationPassed:
+		return CountDistinctCompletedRealProjects(ctx, excludedSupabaseUserIDs)
+	default:
+		return CountDistinctActivatedUsers(ctx, excludedSupabaseUserIDs)
+	}
+}
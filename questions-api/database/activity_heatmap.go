@@ -0,0 +1,87 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ActivityHeatmapDays labels the heatmap rows (ISO 8601 order, Monday first)
+var ActivityHeatmapDays = [7]string{"Mon", "Tue", "Wed", "Thu", "Fri", "Sat", "Sun"}
+
+// ActivityHeatmap is a day-of-week x hour-of-day grid of submission counts.
+// Rows follow ActivityHeatmapDays; columns are hours 0-23 in the requested timezone.
+type ActivityHeatmap [7][24]int64
+
+// GetSubmissionActivityHeatmap counts browser submissions since the given time,
+// bucketed by ISO weekday and hour in loc. Test submissions are excluded.
+func GetSubmissionActivityHeatmap(ctx context.Context, since time.Time, loc *time.Location, excludedSupabaseUserIDs []string) (*ActivityHeatmap, error) {
+	collection := GetAnalyticsBrowserSubmissionsCollection()
+
+	match := ExcludeTestSubmissions(bson.M{
+		"createdAt": bson.M{"$gte": since},
+	})
+	if len(excludedSupabaseUserIDs) > 0 {
+		match["supabaseUserId"] = bson.M{"$nin": excludedSupabaseUserIDs}
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		// "%u" = ISO weekday (1 = Monday ... 7 = Sunday), "%H" = hour 00-23
+		{{Key: "$group", Value: bson.M{
+			"_id": bson.M{"$dateToString": bson.M{
+				"format":   "%u-%H",
+				"date":     "$createdAt",
+				"timezone": loc.String(),
+			}},
+			"count": bson.M{"$sum": 1},
+		}}},
+	}
+
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate activity heatmap: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		Bucket string `bson:"_id"`
+		Count  int64  `bson:"count"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, fmt.Errorf("failed to decode activity heatmap: %w", err)
+	}
+
+	heatmap := &ActivityHeatmap{}
+	for _, row := range rows {
+		day, hour, ok := parseHeatmapBucket(row.Bucket)
+		if !ok {
+			// Legacy string createdAt values format to null; nothing to place
+			continue
+		}
+		heatmap[day][hour] += row.Count
+	}
+	return heatmap, nil
+}
+
+// parseHeatmapBucket turns "<isoWeekday>-<hour>" into zero-based row/column indexes
+func parseHeatmapBucket(bucket string) (int, int, bool) {
+	dayStr, hourStr, found := strings.Cut(bucket, "-")
+	if !found {
+		return 0, 0, false
+	}
+	day, err := strconv.Atoi(dayStr)
+	if err != nil || day < 1 || day > 7 {
+		return 0, 0, false
+	}
+	hour, err := strconv.Atoi(hourStr)
+	if err != nil || hour < 0 || hour > 23 {
+		return 0, 0, false
+	}
+	return day - 1, hour, true
+}
@@ -2,31 +2,128 @@ package database
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // BrowserSubmissionDocument represents how we store browser submissions
 type BrowserSubmissionDocument struct {
-	ID               primitive.ObjectID     `bson:"_id,omitempty" json:"_id"`
-	ProblemID        string                 `bson:"problemId" json:"problemId"`
-	SupabaseUserID   string                 `bson:"supabaseUserId,omitempty" json:"supabaseUserId,omitempty"`   // New UUID
-	UserID           string                 `bson:"userId" json:"userId"`                                       // Legacy ID (email or uuid)
-	Email            string                 `bson:"email,omitempty" json:"email,omitempty"`                     // Original email
-	EmailNormalized  string                 `bson:"emailNormalized,omitempty" json:"emailNormalized,omitempty"` // Lowercase, trimmed email for queries
-	Language         string                 `bson:"language" json:"language"`
-	SourceType       string                 `bson:"sourceType" json:"sourceType"`
-	Files            map[string]string      `bson:"files,omitempty" json:"files,omitempty"`
-	UserTestsCode    string                 `bson:"userTestsCode,omitempty" json:"userTestsCode,omitempty"`
-	UserTestsResults []UserTestResult       `bson:"userTestsResults,omitempty" json:"userTestsResults,omitempty"`
-	Result           BrowserExecutionResult `bson:"result" json:"result"`
-	Meta             BrowserExecutionMeta   `bson:"meta" json:"meta"`
-	Passed           bool                   `bson:"passed" json:"passed"`
-	UserAgent        string                 `bson:"userAgent,omitempty" json:"userAgent,omitempty"`
-	Environment      string                 `bson:"environment,omitempty" json:"environment,omitempty"` // "production", "staging", "development"
-	CreatedAt        time.Time              `bson:"createdAt" json:"createdAt"`
+	ID                primitive.ObjectID     `bson:"_id,omitempty" json:"_id"`
+	ProblemID         string                 `bson:"problemId" json:"problemId"`
+	SupabaseUserID    string                 `bson:"supabaseUserId,omitempty" json:"supabaseUserId,omitempty"`   // New UUID
+	UserID            string                 `bson:"userId" json:"userId"`                                       // Legacy ID (email or uuid)
+	Email             string                 `bson:"email,omitempty" json:"email,omitempty"`                     // Original email
+	EmailNormalized   string                 `bson:"emailNormalized,omitempty" json:"emailNormalized,omitempty"` // Lowercase, trimmed email for queries
+	Language          string                 `bson:"language" json:"language"`
+	SourceType        string                 `bson:"sourceType" json:"sourceType"`
+	Files             map[string]string      `bson:"files,omitempty" json:"files,omitempty"`
+	UserTestsCode     string                 `bson:"userTestsCode,omitempty" json:"userTestsCode,omitempty"`
+	UserTestsResults  []UserTestResult       `bson:"userTestsResults,omitempty" json:"userTestsResults,omitempty"`
+	Result            BrowserExecutionResult `bson:"result" json:"result"`
+	Meta              BrowserExecutionMeta   `bson:"meta" json:"meta"`
+	Passed            bool                   `bson:"passed" json:"passed"`
+	MinTestCount      int                    `bson:"minTestCount,omitempty" json:"minTestCount,omitempty"`           // Effective minimum test total applied when computing passed
+	PassedWithStarter bool                   `bson:"passedWithStarter,omitempty" json:"passedWithStarter,omitempty"` // Passed with (nearly) unchanged starter files
+	UserAgent         string                 `bson:"userAgent,omitempty" json:"userAgent,omitempty"`
+	Environment       string                 `bson:"environment,omitempty" json:"environment,omitempty"` // "production", "staging", "development"
+	IsTest            bool                   `bson:"isTest,omitempty" json:"isTest,omitempty"`           // Staff QA submission, hidden from feeds and metrics
+	CreatedAt         time.Time              `bson:"createdAt" json:"createdAt"`
+}
+
+// ExcludeTestSubmissions adds a clause to a browser_submissions filter that drops
+// staff-flagged test submissions. Documents without the field are kept.
+func ExcludeTestSubmissions(filter bson.M) bson.M {
+	filter["isTest"] = bson.M{"$ne": true}
+	return filter
+}
+
+// ExcludeProblemIDs drops submissions for the given problemIds (e.g. tutorial or demo
+// projects) from a browser_submissions filter. IDs match exactly; project submissions
+// also match the normalized form (see NormalizeSubmissionProblemID), so "07" excludes
+// project "7" but not code problem "7". An existing problemId condition or $and
+// (as []bson.M or bson.A) is kept. A no-op for an empty list.
+func ExcludeProblemIDs(filter bson.M, problemIDs []string) bson.M {
+	if len(problemIDs) == 0 {
+		return filter
+	}
+	exclusion := bson.M{"problemId": bson.M{"$nin": problemIDs}}
+	if projectIDs := normalizedProjectProblemIDs(problemIDs); len(projectIDs) > 0 {
+		exclusion = bson.M{"$nor": bson.A{
+			bson.M{"problemId": bson.M{"$in": problemIDs}},
+			bson.M{"sourceType": "project", "problemId": bson.M{"$in": projectIDs}},
+		}}
+	}
+	if cond, ok := exclusion["problemId"]; ok {
+		if _, taken := filter["problemId"]; !taken {
+			filter["problemId"] = cond
+			return filter
+		}
+	}
+	filter["$and"] = appendAndClause(filter["$and"], exclusion)
+	return filter
+}
+
+// normalizedProjectProblemIDs returns the project-normalized forms of ids that differ
+// from every id as given (e.g. "7" for "07"), without duplicates.
+func normalizedProjectProblemIDs(ids []string) []string {
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		seen[id] = true
+	}
+	normalized := make([]string, 0)
+	for _, id := range ids {
+		if n := NormalizeSubmissionProblemID("project", id); !seen[n] {
+			seen[n] = true
+			normalized = append(normalized, n)
+		}
+	}
+	return normalized
+}
+
+// appendAndClause appends clause to an existing $and value, whichever slice type it
+// was built with. A missing $and starts a new one.
+func appendAndClause(existing interface{}, clause bson.M) bson.A {
+	var and bson.A
+	switch clauses := existing.(type) {
+	case bson.A:
+		and = append(and, clauses...)
+	case []interface{}:
+		and = append(and, clauses...)
+	case []bson.M:
+		for _, c := range clauses {
+			and = append(and, c)
+		}
+	case []bson.D:
+		for _, c := range clauses {
+			and = append(and, c)
+		}
+	}
+	return append(and, clause)
+}
+
+// ParseProblemIDList splits a comma-separated problemId list, trimming each entry and
+// dropping blanks and duplicates. IDs are not normalized here because the source type
+// is unknown; ExcludeProblemIDs applies project normalization where it belongs.
+func ParseProblemIDList(raw string) []string {
+	seen := make(map[string]bool)
+	ids := make([]string, 0)
+	for _, part := range strings.Split(raw, ",") {
+		id := strings.TrimSpace(part)
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	return ids
 }
 
 // UserTestResult represents a single user test result
@@ -118,6 +215,7 @@ type RunnerEventDocument struct {
 	ID              primitive.ObjectID     `bson:"_id,omitempty"`
 	Event           string                 `bson:"event"`
 	Properties      map[string]interface{} `bson:"properties,omitempty"`
+	Tags            map[string]string      `bson:"tags,omitempty"` // Low-cardinality, indexed dimensions (see NormalizeEventTags)
 	UserID          string                 `bson:"userId,omitempty"`
 	Email           string                 `bson:"email,omitempty"`           // User's email for routing and analytics
 	EmailNormalized string                 `bson:"emailNormalized,omitempty"` // Lowercase, trimmed email for consistent queries
@@ -130,10 +228,14 @@ type RunnerEventDocument struct {
 
 // CreateBrowserSubmission inserts a new browser submission into MongoDB
 // Runtime data - writes to app DB (or dev DB for internal users)
-func CreateBrowserSubmission(submission *BrowserSubmissionDocument) (string, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+// The write is bounded by ctx, so a cancelled request aborts the insert.
+func CreateBrowserSubmission(ctx context.Context, submission *BrowserSubmissionDocument) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
+	// Funnel queries match problemId exactly against strconv'd projectNumbers
+	submission.ProblemID = NormalizeSubmissionProblemID(submission.SourceType, submission.ProblemID)
+
 	// Route internal users to dev database to avoid polluting production metrics
 	var collection *mongo.Collection
 	if IsInternalUser(submission.Email) || IsInternalUser(submission.EmailNormalized) {
@@ -157,10 +259,15 @@ func CreateBrowserSubmission(submission *BrowserSubmissionDocument) (string, err
 
 // CreateRunnerEvent inserts a new telemetry event into MongoDB
 // Runtime data - writes to app DB (or dev DB for internal users)
-func CreateRunnerEvent(event *RunnerEventDocument) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+func CreateRunnerEvent(ctx context.Context, event *RunnerEventDocument) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
+	// Store a numeric copy of properties.projectId so queries can range-filter on it
+	if n, ok := DeriveProjectNumber(event.Properties); ok {
+		event.Properties["projectNumber"] = n
+	}
+
 	// Route internal users to dev database to avoid polluting production metrics
 	// Check Email field first, then fall back to UserID (which may be an email in legacy data)
 	var collection *mongo.Collection
@@ -173,3 +280,114 @@ func CreateRunnerEvent(event *RunnerEventDocument) error {
 	_, err := collection.InsertOne(ctx, event)
 	return err
 }
+
+// NormalizeProblemID trims whitespace and strips leading zeros from numeric
+// problemIds (" 7", "07" -> "7") so they match ProblemIDForProjectNumber.
+// Non-numeric IDs are only trimmed.
+func NormalizeProblemID(problemID string) string {
+	trimmed := strings.TrimSpace(problemID)
+	if trimmed == "" || len(trimmed) > maxNumericProblemIDLen {
+		return trimmed
+	}
+	for _, r := range trimmed {
+		if r < '0' || r > '9' {
+			return trimmed
+		}
+	}
+	n, err := strconv.Atoi(trimmed)
+	if err != nil {
+		return trimmed
+	}
+	return strconv.Itoa(n)
+}
+
+// NormalizeSubmissionProblemID applies NormalizeProblemID to project submissions only.
+// Other content (e.g. code problem "007") keeps its problemId exactly as sent.
+func NormalizeSubmissionProblemID(sourceType, problemID string) string {
+	if sourceType != "project" {
+		return problemID
+	}
+	return NormalizeProblemID(problemID)
+}
+
+// maxNumericProblemIDLen bounds which all-digit IDs are treated as project numbers
+const maxNumericProblemIDLen = 9
+
+// ProblemIDForProjectNumber returns the canonical problemId string for a projectNumber
+func ProblemIDForProjectNumber(projectNumber int) string {
+	return strconv.Itoa(projectNumber)
+}
+
+// DeriveProjectNumber returns properties.projectId as an int. Telemetry stores the
+// projectNumber as a string ("0", "7"), but older clients sent a number.
+// Returns false when projectId is missing or not an integer.
+func DeriveProjectNumber(properties map[string]interface{}) (int, bool) {
+	switch v := properties["projectId"].(type) {
+	case string:
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		return n, err == nil
+	case int:
+		return v, true
+	case int32:
+		return int(v), true
+	case int64:
+		return int(v), true
+	case float64:
+		if v == float64(int(v)) {
+			return int(v), true
+		}
+	}
+	return 0, false
+}
+
+// CreateBrowserSubmissionExtraIndexes ensures indexes for the clientSubmissionId
+// (idempotency) and isBest (best-submission lookup) fields on browser_submissions in
+// both the app and dev databases. Safe to call on every startup: indexes that
+// already exist, including with different options, are left in place.
+func CreateBrowserSubmissionExtraIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			// Sparse so legacy documents without a client ID don't collide on null
+			Keys: bson.D{{Key: "clientSubmissionId", Value: 1}},
+			Options: options.Index().
+				SetName("clientSubmissionId_unique").
+				SetUnique(true).
+				SetSparse(true),
+		},
+		{
+			Keys: bson.D{{Key: "supabaseUserId", Value: 1}, {Key: "problemId", Value: 1}, {Key: "isBest", Value: 1}},
+			Options: options.Index().
+				SetName("supabaseUserId_problemId_isBest").
+				SetPartialFilterExpression(bson.M{"isBest": true}),
+		},
+	}
+
+	dbs := []*mongo.Database{GetAppDb()}
+	if cachedDevDbName != "" && cachedDevDbName != activeAppDBName {
+		dbs = append(dbs, GetDevDb())
+	}
+
+	for _, db := range dbs {
+		collection := db.Collection("browser_submissions")
+		for _, index := range indexes {
+			if _, err := collection.Indexes().CreateOne(ctx, index); err != nil {
+				if isIndexAlreadyExistsError(err) {
+					continue
+				}
+				return fmt.Errorf("failed to create browser_submissions index in %s: %w", db.Name(), err)
+			}
+		}
+	}
+	return nil
+}
+
+// isIndexAlreadyExistsError reports whether err means an equivalent index (same
+// name or same keys) is already present, which callers treat as success
+func isIndexAlreadyExistsError(err error) bool {
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) {
+		// 85 IndexOptionsConflict, 86 IndexKeySpecsConflict
+		return cmdErr.Code == 85 || cmdErr.Code == 86
+	}
+	return false
+}
@@ -0,0 +1,124 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestNormalizeSubmissionProblemID(t *testing.T) {
+	tests := []struct {
+		name       string
+		sourceType string
+		problemID  string
+		want       string
+	}{
+		{"project unpadded", "project", "7", "7"},
+		{"project leading zero", "project", "07", "7"},
+		{"project many leading zeros", "project", "007", "7"},
+		{"project surrounding whitespace", "project", " 7 ", "7"},
+		{"project whitespace and zeros", "project", "\t012\n", "12"},
+		{"project zero", "project", "000", "0"},
+		{"project non-numeric is trimmed only", "project", " two-sum ", "two-sum"},
+		{"project oversized number is trimmed only", "project", "0123456789", "0123456789"},
+		{"code problem keeps leading zeros", "code", "007", "007"},
+		{"code problem keeps whitespace", "code", " 7", " 7"},
+		{"empty source type untouched", "", "07", "07"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeSubmissionProblemID(tt.sourceType, tt.problemID); got != tt.want {
+				t.Errorf("NormalizeSubmissionProblemID(%q, %q) = %q, want %q", tt.sourceType, tt.problemID, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseProblemIDList(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want []string
+	}{
+		{"", []string{}},
+		{" , ,", []string{}},
+		{"1,7", []string{"1", "7"}},
+		{" 07 , two-sum,07", []string{"07", "two-sum"}},
+	}
+	for _, tt := range tests {
+		if got := ParseProblemIDList(tt.raw); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseProblemIDList(%q) = %#v, want %#v", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestExcludeProblemIDs(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter bson.M
+		ids    []string
+		want   bson.M
+	}{
+		{
+			name:   "empty list is a no-op",
+			filter: bson.M{"sourceType": "project"},
+			ids:    nil,
+			want:   bson.M{"sourceType": "project"},
+		},
+		{
+			name:   "no existing problemId condition",
+			filter: bson.M{},
+			ids:    []string{"1", "two-sum"},
+			want:   bson.M{"problemId": bson.M{"$nin": []string{"1", "two-sum"}}},
+		},
+		{
+			name:   "existing problemId condition is kept",
+			filter: bson.M{"problemId": "1"},
+			ids:    []string{"7"},
+			want: bson.M{
+				"problemId": "1",
+				"$and":      bson.A{bson.M{"problemId": bson.M{"$nin": []string{"7"}}}},
+			},
+		},
+		{
+			name:   "existing $and as []bson.M",
+			filter: bson.M{"problemId": "1", "$and": []bson.M{{"userId": "u1"}}},
+			ids:    []string{"7"},
+			want: bson.M{
+				"problemId": "1",
+				"$and":      bson.A{bson.M{"userId": "u1"}, bson.M{"problemId": bson.M{"$nin": []string{"7"}}}},
+			},
+		},
+		{
+			name:   "existing $and as bson.A",
+			filter: bson.M{"problemId": "1", "$and": bson.A{bson.M{"userId": "u1"}}},
+			ids:    []string{"7"},
+			want: bson.M{
+				"problemId": "1",
+				"$and":      bson.A{bson.M{"userId": "u1"}, bson.M{"problemId": bson.M{"$nin": []string{"7"}}}},
+			},
+		},
+		{
+			name:   "padded id also excludes the normalized project id only",
+			filter: bson.M{},
+			ids:    []string{"07", "two-sum"},
+			want: bson.M{"$and": bson.A{bson.M{"$nor": bson.A{
+				bson.M{"problemId": bson.M{"$in": []string{"07", "two-sum"}}},
+				bson.M{"sourceType": "project", "problemId": bson.M{"$in": []string{"7"}}},
+			}}}},
+		},
+		{
+			name:   "padded and unpadded ids need no extra project clause",
+			filter: bson.M{},
+			ids:    []string{"07", "7"},
+			want:   bson.M{"problemId": bson.M{"$nin": []string{"07", "7"}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExcludeProblemIDs(tt.filter, tt.ids); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ExcludeProblemIDs() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,9 +2,13 @@ package database
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
+	"math"
 	"time"
 
+	"github.com/gerdinv/questions-api/config"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -19,11 +23,12 @@ import (
 // for a single user on a single content item (project, problem, or module problem).
 type DecisionTraceSessionDocument struct {
 	ID                      primitive.ObjectID  `bson:"_id,omitempty" json:"_id"`
-	UserID                  string              `bson:"userId" json:"userId"`           // Supabase UUID
-	ContentID               string              `bson:"contentId" json:"contentId"`     // project/problem/module ID
-	ContentType             string              `bson:"contentType" json:"contentType"` // "project" | "problem" | "module_problem"
-	Language                string              `bson:"language" json:"language"`       // "python" | "java" | "cpp" etc.
-	Status                  string              `bson:"status" json:"status"`           // "active" | "ended"
+	UserID                  string              `bson:"userId" json:"userId"`                               // Supabase UUID
+	ContentID               string              `bson:"contentId" json:"contentId"`                         // project/problem/module ID
+	ContentType             string              `bson:"contentType" json:"contentType"`                     // "project" | "problem" | "module_problem"
+	Language                string              `bson:"language" json:"language"`                           // "python" | "java" | "cpp" etc.
+	Environment             string              `bson:"environment,omitempty" json:"environment,omitempty"` // "production" | "staging" | "development"
+	Status                  string              `bson:"status" json:"status"`                               // "active" | "ended"
 	StartedAt               time.Time           `bson:"startedAt" json:"startedAt"`
 	EndedAt                 *time.Time          `bson:"endedAt,omitempty" json:"endedAt"`
 	SchemaVersion           int                 `bson:"schemaVersion" json:"schemaVersion"`
@@ -31,6 +36,18 @@ type DecisionTraceSessionDocument struct {
 	LastEventID             *primitive.ObjectID `bson:"lastEventId,omitempty" json:"lastEventId"`
 	TotalEvents             int                 `bson:"totalEvents" json:"totalEvents"`
 	LastBrowserSubmissionID *string             `bson:"lastBrowserSubmissionId,omitempty" json:"lastBrowserSubmissionId"`
+	// LastDedupClaim is the latest event written while DECISION_TRACE_DEDUP_WINDOW_MS was on;
+	// swapped atomically so concurrent double-fires can't both insert
+	LastDedupClaim *DTDedupClaim `bson:"lastDedupClaim,omitempty" json:"-"`
+}
+
+// DTDedupClaim identifies a session's most recent event for consecutive-duplicate checks.
+// EventID is allocated before the insert, so a duplicate can return it right away.
+type DTDedupClaim struct {
+	EventID    primitive.ObjectID `bson:"eventId"`
+	EventType  string             `bson:"eventType"`
+	CodeSHA256 string             `bson:"codeSha256"`
+	At         time.Time          `bson:"at"`
 }
 
 // ============================================================
@@ -71,6 +88,7 @@ type DTEventExecution struct {
 	MemoryKb           *int                `bson:"memoryKb,omitempty" json:"memoryKb"`
 	Tests              DTEventTestSummary  `bson:"tests" json:"tests"`
 	TestResults        []DTEventTestResult `bson:"testResults,omitempty" json:"testResults"`
+	TimedOut           bool                `bson:"timedOut,omitempty" json:"timedOut"` // run hit the time limit; tests may be partial
 }
 
 // DTEventTestSummary holds pass/fail counts.
@@ -78,22 +96,35 @@ type DTEventTestSummary struct {
 	Total  *int `bson:"total,omitempty" json:"total"`
 	Passed *int `bson:"passed,omitempty" json:"passed"`
 	Failed *int `bson:"failed,omitempty" json:"failed"`
+	NotRun *int `bson:"notRun,omitempty" json:"notRun"` // tests that never ran because the run timed out
 }
 
 // DTEventTestResult stores a single test case result (capped to 10 in V1).
 type DTEventTestResult struct {
 	TestName     string  `bson:"testName" json:"testName"`
-	Status       string  `bson:"status" json:"status"` // "passed" | "failed"
+	Status       string  `bson:"status" json:"status"` // "passed" | "failed" | "not_run"
 	Message      *string `bson:"message,omitempty" json:"message"`
 	ErrorCode    *string `bson:"errorCode,omitempty" json:"errorCode"`
 	ErrorTooltip *string `bson:"errorTooltip,omitempty" json:"errorTooltip"`
 }
 
+// Test result statuses. DTTestStatusNotRun marks tests cut off by a timeout.
+const (
+	DTTestStatusPassed = "passed"
+	DTTestStatusFailed = "failed"
+	DTTestStatusNotRun = "not_run"
+)
+
+// DTErrorCodeTimeout is the universalErrorCode the runner reports for timed-out runs
+const DTErrorCodeTimeout = "TIMEOUT"
+
 // DTEventVisualization holds optional Mermaid visualization and state snapshot.
 type DTEventVisualization struct {
-	Kind          *string                `bson:"kind,omitempty" json:"kind"` // "MERMAID" | null
-	MermaidText   *string                `bson:"mermaidText,omitempty" json:"mermaidText"`
-	StateSnapshot map[string]interface{} `bson:"stateSnapshot,omitempty" json:"stateSnapshot,omitempty"`
+	Kind        *string `bson:"kind,omitempty" json:"kind"` // "MERMAID" | null
+	MermaidText *string `bson:"mermaidText,omitempty" json:"mermaidText"`
+	// MermaidOmitted is set when mermaidText exceeded the size cap and was not stored
+	MermaidOmitted bool                   `bson:"mermaidOmitted,omitempty" json:"mermaidOmitted,omitempty"`
+	StateSnapshot  map[string]interface{} `bson:"stateSnapshot,omitempty" json:"stateSnapshot,omitempty"`
 }
 
 // DTEventAI holds AI artifacts (nano + gemini layers).
@@ -117,6 +148,8 @@ type DTEventAIGemini struct {
 	NudgeType       *string                 `bson:"nudgeType,omitempty" json:"nudgeType"`
 	ResponseText    *string                 `bson:"responseText,omitempty" json:"responseText"`
 	CitedLineRanges []DTEventCitedLineRange `bson:"citedLineRanges,omitempty" json:"citedLineRanges"`
+	// CitedLineRangesDropped counts cited ranges discarded for lying entirely outside the code
+	CitedLineRangesDropped int `bson:"citedLineRangesDropped,omitempty" json:"citedLineRangesDropped,omitempty"`
 }
 
 // DTEventCitedLineRange identifies a line range for highlighting.
@@ -137,6 +170,48 @@ type DecisionTraceTimelineEntry struct {
 	EventType          string             `json:"eventType"`
 	TestsFailed        *int               `json:"testsFailed"`
 	UniversalErrorCode *string            `json:"universalErrorCode"`
+	Outcome            string             `json:"outcome"` // "passed" | "failed" | "timed_out" | "error" | "unknown"
+}
+
+// newTimelineEntry builds the timeline header for an event.
+func newTimelineEntry(event *DecisionTraceEventDocument) DecisionTraceTimelineEntry {
+	return DecisionTraceTimelineEntry{
+		EventID:            event.ID,
+		CreatedAt:          event.CreatedAt,
+		EventType:          event.EventType,
+		TestsFailed:        event.Execution.Tests.Failed,
+		UniversalErrorCode: event.Execution.UniversalErrorCode,
+		Outcome:            executionOutcome(&event.Execution),
+	}
+}
+
+// executionOutcome classifies an execution so the timeline can show
+// "timed out" separately from "failed".
+func executionOutcome(exec *DTEventExecution) string {
+	if exec.TimedOut || (exec.UniversalErrorCode != nil && *exec.UniversalErrorCode == DTErrorCodeTimeout) {
+		return "timed_out"
+	}
+	tests := exec.Tests
+	if tests.Failed != nil && *tests.Failed > 0 {
+		return "failed"
+	}
+	if exec.UniversalErrorCode != nil && *exec.UniversalErrorCode != "" {
+		return "error"
+	}
+	if tests.Total != nil && *tests.Total > 0 && tests.Failed != nil {
+		return "passed"
+	}
+	return "unknown"
+}
+
+// DecisionTraceRecentEntry is a timeline header plus the owner/content fields
+// needed for the cross-platform live feed (GET /admin/decision-trace/recent).
+type DecisionTraceRecentEntry struct {
+	DecisionTraceTimelineEntry
+	SessionID   primitive.ObjectID `json:"sessionId"`
+	UserID      string             `json:"userId"`
+	ContentID   string             `json:"contentId"`
+	ContentType string             `json:"contentType"`
 }
 
 // ============================================================
@@ -153,12 +228,31 @@ type DecisionTraceEventsCollection struct {
 	collection *mongo.Collection
 }
 
+// GetAnalyticsDecisionTraceEventsCollection returns decision_trace_events on the analytics connection
+func GetAnalyticsDecisionTraceEventsCollection() *DecisionTraceEventsCollection {
+	return &DecisionTraceEventsCollection{
+		collection: GetAnalyticsDb().Collection("decision_trace_events"),
+	}
+}
+
 // ============================================================
 // Index Creation
 // ============================================================
 
+// legacyActiveSessionIndexName is the pre-environment unique index. It must be
+// dropped, otherwise it keeps rejecting a second active session in another environment.
+const legacyActiveSessionIndexName = "uidx_sessions_one_active_per_user_content_language"
+
 // EnsureIndexes creates required indexes for decision_trace_sessions.
 func (c *DecisionTraceSessionsCollection) EnsureIndexes(ctx context.Context) error {
+	if _, err := c.collection.Indexes().DropOne(ctx, legacyActiveSessionIndexName); err != nil {
+		var cmdErr mongo.CommandError
+		// 27 = IndexNotFound, 26 = NamespaceNotFound (already dropped / fresh collection)
+		if !errors.As(err, &cmdErr) || (cmdErr.Code != 27 && cmdErr.Code != 26) {
+			return fmt.Errorf("failed to drop legacy active session index: %w", err)
+		}
+	}
+
 	indexes := []mongo.IndexModel{
 		// 1) Fast lookup for current active session
 		{
@@ -171,16 +265,17 @@ func (c *DecisionTraceSessionsCollection) EnsureIndexes(ctx context.Context) err
 			},
 			Options: options.Index().SetName("idx_sessions_user_content_status_lastEventAt"),
 		},
-		// 2) Prevent multiple active sessions per (userId, contentId, contentType, language)
+		// 2) Prevent multiple active sessions per (userId, contentId, contentType, language, environment)
 		{
 			Keys: bson.D{
 				{Key: "userId", Value: 1},
 				{Key: "contentId", Value: 1},
 				{Key: "contentType", Value: 1},
 				{Key: "language", Value: 1},
+				{Key: "environment", Value: 1},
 			},
 			Options: options.Index().
-				SetName("uidx_sessions_one_active_per_user_content_language").
+				SetName("uidx_sessions_one_active_per_user_content_language_env").
 				SetUnique(true).
 				SetPartialFilterExpression(bson.M{"status": "active"}),
 		},
@@ -237,21 +332,117 @@ func (c *DecisionTraceEventsCollection) EnsureIndexes(ctx context.Context) error
 			},
 			Options: options.Index().SetName("idx_events_session_eventType_createdAt"),
 		},
+		// 5) Platform-wide live feed (newest first)
+		{
+			Keys: bson.D{
+				{Key: "createdAt", Value: -1},
+			},
+			Options: options.Index().SetName("idx_events_createdAt"),
+		},
+		// 6) Live feed filtered to one content item
+		{
+			Keys: bson.D{
+				{Key: "contentId", Value: 1},
+				{Key: "createdAt", Value: -1},
+			},
+			Options: options.Index().SetName("idx_events_content_createdAt"),
+		},
 	}
 
-	_, err := c.collection.Indexes().CreateMany(ctx, indexes)
-	return err
+	if _, err := c.collection.Indexes().CreateMany(ctx, indexes); err != nil {
+		return err
+	}
+	return c.ensureRetentionIndex(ctx, config.GetConfig().DecisionTraceEventRetentionDays)
+}
+
+// eventRetentionIndexName is the opt-in TTL index that expires old decision_trace_events
+const eventRetentionIndexName = "ttl_events_createdAt"
+
+// ensureRetentionIndex makes the TTL index on createdAt match retentionDays: created when
+// positive, its expiry updated in place (collMod) when the setting changes, and dropped
+// when retention is 0 or unset so events are kept forever again.
+func (c *DecisionTraceEventsCollection) ensureRetentionIndex(ctx context.Context, retentionDays int) error {
+	cursor, err := c.collection.Indexes().List(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list decision_trace_events indexes: %w", err)
+	}
+	var existing []struct {
+		Name               string `bson:"name"`
+		ExpireAfterSeconds *int64 `bson:"expireAfterSeconds"`
+	}
+	if err := cursor.All(ctx, &existing); err != nil {
+		return fmt.Errorf("failed to decode decision_trace_events indexes: %w", err)
+	}
+	var current *int64
+	found := false
+	for _, idx := range existing {
+		if idx.Name == eventRetentionIndexName {
+			current, found = idx.ExpireAfterSeconds, true
+			break
+		}
+	}
+
+	if retentionDays <= 0 {
+		if !found {
+			return nil
+		}
+		if _, err := c.collection.Indexes().DropOne(ctx, eventRetentionIndexName); err != nil {
+			return fmt.Errorf("failed to drop decision trace retention index: %w", err)
+		}
+		log.Printf("DecisionTrace: retention disabled, dropped %s (events are kept forever)", eventRetentionIndexName)
+		return nil
+	}
+
+	expireAfter := int64(retentionDays) * 24 * 60 * 60
+	if expireAfter > math.MaxInt32 {
+		return fmt.Errorf("decision trace retention of %d days is too large", retentionDays)
+	}
+	if !found {
+		_, err := c.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+			Keys:    bson.D{{Key: "createdAt", Value: 1}},
+			Options: options.Index().SetName(eventRetentionIndexName).SetExpireAfterSeconds(int32(expireAfter)),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create decision trace retention index: %w", err)
+		}
+		log.Printf("DecisionTrace: events now expire %d days after createdAt", retentionDays)
+		return nil
+	}
+	if current != nil && *current == expireAfter {
+		return nil
+	}
+
+	err = c.collection.Database().RunCommand(ctx, bson.D{
+		{Key: "collMod", Value: c.collection.Name()},
+		{Key: "index", Value: bson.D{
+			{Key: "name", Value: eventRetentionIndexName},
+			{Key: "expireAfterSeconds", Value: expireAfter},
+		}},
+	}).Err()
+	if err != nil {
+		return fmt.Errorf("failed to update decision trace retention index: %w", err)
+	}
+	log.Printf("DecisionTrace: events now expire %d days after createdAt", retentionDays)
+	return nil
 }
 
 // ============================================================
 // Session CRUD
 // ============================================================
 
+// sessionEnvironmentFilter matches sessions in environment, plus legacy sessions written
+// before the field existed (cmd/backfill_session_environment sets it on those).
+func sessionEnvironmentFilter(environment string) bson.M {
+	return bson.M{"$in": bson.A{environment, nil}}
+}
+
 // GetOrCreateActiveSession finds an existing active session or creates a new one.
+// Sessions are isolated per environment, so staging and production never share one;
+// a legacy active session without an environment is resumed rather than duplicated.
 // Returns (session, created, error).
 func (c *DecisionTraceSessionsCollection) GetOrCreateActiveSession(
 	ctx context.Context,
-	userID, contentID, contentType, language string,
+	userID, contentID, contentType, language, environment string,
 ) (*DecisionTraceSessionDocument, bool, error) {
 	now := time.Now()
 
@@ -260,12 +451,15 @@ func (c *DecisionTraceSessionsCollection) GetOrCreateActiveSession(
 		"contentId":   contentID,
 		"contentType": contentType,
 		"language":    language,
+		"environment": sessionEnvironmentFilter(environment),
 		"status":      "active",
 	}
+	// Prefer the environment-tagged session if a legacy one is also still active
+	findOpts := options.FindOne().SetSort(bson.D{{Key: "environment", Value: -1}, {Key: "lastEventAt", Value: -1}})
 
 	// Try to find existing active session
 	var session DecisionTraceSessionDocument
-	err := c.collection.FindOne(ctx, filter).Decode(&session)
+	err := c.collection.FindOne(ctx, filter, findOpts).Decode(&session)
 	if err == nil {
 		return &session, false, nil
 	}
@@ -279,6 +473,7 @@ func (c *DecisionTraceSessionsCollection) GetOrCreateActiveSession(
 		ContentID:     contentID,
 		ContentType:   contentType,
 		Language:      language,
+		Environment:   environment,
 		Status:        "active",
 		StartedAt:     now,
 		SchemaVersion: 1,
@@ -291,7 +486,7 @@ func (c *DecisionTraceSessionsCollection) GetOrCreateActiveSession(
 		// Race condition: another request created the session between FindOne and InsertOne.
 		// The partial unique index will produce a duplicate key error. Retry the find.
 		if mongo.IsDuplicateKeyError(err) {
-			err = c.collection.FindOne(ctx, filter).Decode(&session)
+			err = c.collection.FindOne(ctx, filter, findOpts).Decode(&session)
 			if err != nil {
 				return nil, false, fmt.Errorf("failed to find session after duplicate key: %w", err)
 			}
@@ -317,17 +512,23 @@ func (c *DecisionTraceSessionsCollection) FindSessionByID(ctx context.Context, s
 	return &session, nil
 }
 
-// FindActiveSession finds the active session for a user + content item.
+// FindActiveSession finds the active session for a user + content item in one environment
+// (or a legacy session without one). An empty language matches any language; the most
+// recently active session wins.
 func (c *DecisionTraceSessionsCollection) FindActiveSession(
 	ctx context.Context,
-	userID, contentID, contentType string,
+	userID, contentID, contentType, language, environment string,
 ) (*DecisionTraceSessionDocument, error) {
 	filter := bson.M{
 		"userId":      userID,
 		"contentId":   contentID,
 		"contentType": contentType,
+		"environment": sessionEnvironmentFilter(environment),
 		"status":      "active",
 	}
+	if language != "" {
+		filter["language"] = language
+	}
 	opts := options.FindOne().SetSort(bson.D{{Key: "lastEventAt", Value: -1}})
 
 	var session DecisionTraceSessionDocument
@@ -363,6 +564,57 @@ func (c *DecisionTraceSessionsCollection) UpdateSessionRollingFields(
 	return err
 }
 
+// maxDedupClaimAttempts bounds compare-and-swap retries when concurrent events race for a session
+const maxDedupClaimAttempts = 5
+
+// ClaimDedupSlot makes claim the session's LastDedupClaim unless isDuplicate reports the
+// current one as a duplicate of it, in which case that earlier claim is returned and
+// nothing is written. The swap is conditional on the claim not having changed since it
+// was read, so of two concurrent identical events exactly one wins.
+func (c *DecisionTraceSessionsCollection) ClaimDedupSlot(
+	ctx context.Context,
+	session *DecisionTraceSessionDocument,
+	claim DTDedupClaim,
+	isDuplicate func(prev *DTDedupClaim) bool,
+) (*DTDedupClaim, error) {
+	prev := session.LastDedupClaim
+	for attempt := 0; attempt < maxDedupClaimAttempts; attempt++ {
+		if isDuplicate(prev) {
+			return prev, nil
+		}
+
+		filter := bson.M{"_id": session.ID, "lastDedupClaim": bson.M{"$exists": false}}
+		if prev != nil {
+			filter = bson.M{"_id": session.ID, "lastDedupClaim.eventId": prev.EventID}
+		}
+		result, err := c.collection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"lastDedupClaim": claim}})
+		if err != nil {
+			return nil, fmt.Errorf("failed to claim dedup slot: %w", err)
+		}
+		if result.MatchedCount == 1 {
+			return nil, nil
+		}
+
+		// Another event swapped in first; re-check against it
+		current, err := c.FindSessionByID(ctx, session.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to reload session for dedup: %w", err)
+		}
+		prev = current.LastDedupClaim
+	}
+	return nil, fmt.Errorf("dedup slot for session %s still contended after %d attempts", session.ID.Hex(), maxDedupClaimAttempts)
+}
+
+// ReleaseDedupClaim clears the session's dedup claim if it still points at eventID,
+// used when the claimed event could not be inserted.
+func (c *DecisionTraceSessionsCollection) ReleaseDedupClaim(ctx context.Context, sessionID, eventID primitive.ObjectID) error {
+	_, err := c.collection.UpdateOne(ctx,
+		bson.M{"_id": sessionID, "lastDedupClaim.eventId": eventID},
+		bson.M{"$unset": bson.M{"lastDedupClaim": ""}},
+	)
+	return err
+}
+
 // EndSession marks a session as "ended" and sets endedAt.
 func (c *DecisionTraceSessionsCollection) EndSession(ctx context.Context, sessionID primitive.ObjectID) error {
 	now := time.Now()
@@ -429,13 +681,7 @@ func (c *DecisionTraceEventsCollection) GetTimelineForSession(ctx context.Contex
 		if err := cursor.Decode(&event); err != nil {
 			continue // skip malformed docs
 		}
-		entries = append(entries, DecisionTraceTimelineEntry{
-			EventID:            event.ID,
-			CreatedAt:          event.CreatedAt,
-			EventType:          event.EventType,
-			TestsFailed:        event.Execution.Tests.Failed,
-			UniversalErrorCode: event.Execution.UniversalErrorCode,
-		})
+		entries = append(entries, newTimelineEntry(&event))
 	}
 
 	if err := cursor.Err(); err != nil {
@@ -449,3 +695,90 @@ func (c *DecisionTraceEventsCollection) GetTimelineForSession(ctx context.Contex
 
 	return entries, nil
 }
+
+// FindSessionsByUser returns every decision trace session for a user, oldest first.
+func (c *DecisionTraceSessionsCollection) FindSessionsByUser(ctx context.Context, userID string) ([]DecisionTraceSessionDocument, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "startedAt", Value: 1}})
+	cursor, err := c.collection.Find(ctx, bson.M{"userId": userID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find sessions for user: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	sessions := []DecisionTraceSessionDocument{}
+	if err := cursor.All(ctx, &sessions); err != nil {
+		return nil, fmt.Errorf("failed to decode sessions: %w", err)
+	}
+	return sessions, nil
+}
+
+// FindEventsByUser returns every decision trace event for a user, oldest first.
+func (c *DecisionTraceEventsCollection) FindEventsByUser(ctx context.Context, userID string) ([]DecisionTraceEventDocument, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}})
+	cursor, err := c.collection.Find(ctx, bson.M{"userId": userID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find events for user: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	events := []DecisionTraceEventDocument{}
+	if err := cursor.All(ctx, &events); err != nil {
+		return nil, fmt.Errorf("failed to decode events: %w", err)
+	}
+	return events, nil
+}
+
+// GetRecentEvents returns the newest events across all users (createdAt DESC) as
+// feed entries. contentID optionally narrows to one content item; events owned by
+// excludedUserIDs are skipped. skip and limit select the page.
+func (c *DecisionTraceEventsCollection) GetRecentEvents(ctx context.Context, contentID string, excludedUserIDs []string, skip, limit int64) ([]DecisionTraceRecentEntry, error) {
+	filter := bson.M{}
+	if contentID != "" {
+		filter["contentId"] = contentID
+	}
+	if len(excludedUserIDs) > 0 {
+		filter["userId"] = bson.M{"$nin": excludedUserIDs}
+	}
+
+	// Skip code/AI payloads; the feed only renders headers
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: -1}}).
+		SetSkip(skip).
+		SetLimit(limit).
+		SetProjection(bson.M{
+			"sessionId":                    1,
+			"userId":                       1,
+			"contentId":                    1,
+			"contentType":                  1,
+			"eventType":                    1,
+			"createdAt":                    1,
+			"execution.tests":              1,
+			"execution.universalErrorCode": 1,
+			"execution.timedOut":           1,
+		})
+
+	cursor, err := c.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query recent events: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	entries := []DecisionTraceRecentEntry{}
+	for cursor.Next(ctx) {
+		var event DecisionTraceEventDocument
+		if err := cursor.Decode(&event); err != nil {
+			continue // skip malformed docs
+		}
+		entries = append(entries, DecisionTraceRecentEntry{
+			DecisionTraceTimelineEntry: newTimelineEntry(&event),
+			SessionID:                  event.SessionID,
+			UserID:                     event.UserID,
+			ContentID:                  event.ContentID,
+			ContentType:                event.ContentType,
+		})
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("cursor error: %w", err)
+	}
+	return entries, nil
+}
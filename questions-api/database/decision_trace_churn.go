@@ -0,0 +1,115 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DTEventChurn is the line diff between an event's code and the previous event's code
+type DTEventChurn struct {
+	EventID      primitive.ObjectID `json:"eventId"`
+	CreatedAt    time.Time          `json:"createdAt"`
+	EventType    string             `json:"eventType"`
+	LinesAdded   int                `json:"linesAdded"`
+	LinesRemoved int                `json:"linesRemoved"`
+}
+
+// DTSessionChurn summarizes code churn across a decision trace session.
+// ThrashScore = (LinesAdded + LinesRemoved) / max(NetLinesChanged, 1): high values
+// mean lots of edits that largely cancel out (guessing), ~1 means deliberate edits.
+type DTSessionChurn struct {
+	SessionID       primitive.ObjectID `json:"sessionId"`
+	EventCount      int                `json:"eventCount"`
+	LinesAdded      int                `json:"linesAdded"`
+	LinesRemoved    int                `json:"linesRemoved"`
+	Churn           int                `json:"churn"`
+	NetLinesChanged int                `json:"netLinesChanged"` // first vs last snapshot
+	ThrashScore     float64            `json:"thrashScore"`
+	Events          []DTEventChurn     `json:"events"`
+}
+
+// dtCodeSnapshot is the projection of an event needed for churn
+type dtCodeSnapshot struct {
+	ID        primitive.ObjectID `bson:"_id"`
+	CreatedAt time.Time          `bson:"createdAt"`
+	EventType string             `bson:"eventType"`
+	Code      struct {
+		Text string `bson:"text"`
+	} `bson:"code"`
+}
+
+// GetSessionChurn loads a session's code snapshots in order and diffs each against
+// the previous one. The first event has no predecessor and contributes no churn.
+func (c *DecisionTraceEventsCollection) GetSessionChurn(ctx context.Context, sessionID primitive.ObjectID) (*DTSessionChurn, error) {
+	opts := options.Find().
+		SetProjection(bson.M{"createdAt": 1, "eventType": 1, "code.text": 1}).
+		SetSort(bson.D{{Key: "createdAt", Value: 1}})
+
+	cursor, err := c.collection.Find(ctx, bson.M{"sessionId": sessionID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query session events: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var snapshots []dtCodeSnapshot
+	if err := cursor.All(ctx, &snapshots); err != nil {
+		return nil, fmt.Errorf("failed to decode session events: %w", err)
+	}
+
+	churn := &DTSessionChurn{
+		SessionID:  sessionID,
+		EventCount: len(snapshots),
+		Events:     make([]DTEventChurn, 0, len(snapshots)),
+	}
+	for i, snap := range snapshots {
+		event := DTEventChurn{EventID: snap.ID, CreatedAt: snap.CreatedAt, EventType: snap.EventType}
+		if i > 0 {
+			event.LinesAdded, event.LinesRemoved = diffLineCounts(snapshots[i-1].Code.Text, snap.Code.Text)
+		}
+		churn.LinesAdded += event.LinesAdded
+		churn.LinesRemoved += event.LinesRemoved
+		churn.Events = append(churn.Events, event)
+	}
+	churn.Churn = churn.LinesAdded + churn.LinesRemoved
+
+	if len(snapshots) > 1 {
+		added, removed := diffLineCounts(snapshots[0].Code.Text, snapshots[len(snapshots)-1].Code.Text)
+		churn.NetLinesChanged = added + removed
+	}
+	if churn.Churn > 0 {
+		score := float64(churn.Churn) / math.Max(float64(churn.NetLinesChanged), 1)
+		churn.ThrashScore = math.Round(score*100) / 100
+	}
+	return churn, nil
+}
+
+// diffLineCounts compares two code snapshots as multisets of trimmed, non-blank lines.
+// Reordered lines and whitespace-only edits are not counted as churn.
+func diffLineCounts(prev, next string) (added, removed int) {
+	counts := make(map[string]int)
+	for _, line := range strings.Split(prev, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			counts[line]++
+		}
+	}
+	for _, line := range strings.Split(next, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			counts[line]--
+		}
+	}
+	for _, n := range counts {
+		if n > 0 {
+			removed += n
+		} else {
+			added -= n
+		}
+	}
+	return added, removed
+}
@@ -0,0 +1,117 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DTCodeSnapshot is one code snapshot in a user's history for a content item
+type DTCodeSnapshot struct {
+	EventID   primitive.ObjectID `json:"eventId"`
+	SessionID primitive.ObjectID `json:"sessionId"`
+	CreatedAt time.Time          `json:"createdAt"`
+	EventType string             `json:"eventType"`
+	Language  string             `json:"language"`
+	SHA256    string             `json:"sha256"`
+	Changed   bool               `json:"changed"` // code differs from the previous snapshot (true for the first)
+	Outcome   string             `json:"outcome"`
+	CodeText  *string            `json:"codeText,omitempty"`
+}
+
+// DTCodeEvolution is the ordered code history of one user on one content item across sessions
+type DTCodeEvolution struct {
+	ContentID    string           `json:"contentId"`
+	ContentType  string           `json:"contentType"`
+	SessionCount int              `json:"sessionCount"`
+	UniqueCodes  int              `json:"uniqueCodes"`
+	Truncated    bool             `json:"truncated"` // older snapshots were dropped by the limit
+	Snapshots    []DTCodeSnapshot `json:"snapshots"`
+}
+
+// GetCodeEvolution returns the user's code snapshots for a content item across all of
+// their sessions, oldest first. Only the newest limit events are kept (limit <= 0 means all);
+// code text is loaded only when includeText is set.
+func (c *DecisionTraceEventsCollection) GetCodeEvolution(
+	ctx context.Context,
+	userID, contentID, contentType string,
+	limit int64,
+	includeText bool,
+) (*DTCodeEvolution, error) {
+	filter := bson.M{
+		"userId":      userID,
+		"contentId":   contentID,
+		"contentType": contentType,
+	}
+	projection := bson.M{
+		"sessionId":   1,
+		"createdAt":   1,
+		"eventType":   1,
+		"language":    1,
+		"code.sha256": 1,
+		"execution":   1,
+	}
+	if includeText {
+		projection["code.text"] = 1
+	}
+	// Newest first so the limit keeps the most recent snapshots; reversed below
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}}).
+		SetProjection(projection)
+	if limit > 0 {
+		opts.SetLimit(limit + 1)
+	}
+
+	cursor, err := c.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find events: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var events []DecisionTraceEventDocument
+	if err := cursor.All(ctx, &events); err != nil {
+		return nil, fmt.Errorf("failed to decode events: %w", err)
+	}
+
+	evolution := &DTCodeEvolution{
+		ContentID:   contentID,
+		ContentType: contentType,
+		Snapshots:   make([]DTCodeSnapshot, 0, len(events)),
+	}
+	if limit > 0 && int64(len(events)) > limit {
+		events = events[:limit]
+		evolution.Truncated = true
+	}
+
+	sessions := make(map[primitive.ObjectID]bool)
+	codes := make(map[string]bool)
+	prevSHA := ""
+	for i := len(events) - 1; i >= 0; i-- {
+		event := events[i]
+		snapshot := DTCodeSnapshot{
+			EventID:   event.ID,
+			SessionID: event.SessionID,
+			CreatedAt: event.CreatedAt,
+			EventType: event.EventType,
+			Language:  event.Language,
+			SHA256:    event.Code.SHA256,
+			Changed:   event.Code.SHA256 != prevSHA,
+			Outcome:   executionOutcome(&event.Execution),
+		}
+		if includeText {
+			text := event.Code.Text
+			snapshot.CodeText = &text
+		}
+		evolution.Snapshots = append(evolution.Snapshots, snapshot)
+		sessions[event.SessionID] = true
+		codes[event.Code.SHA256] = true
+		prevSHA = event.Code.SHA256
+	}
+	evolution.SessionCount = len(sessions)
+	evolution.UniqueCodes = len(codes)
+	return evolution, nil
+}
@@ -0,0 +1,69 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// DefaultSessionReaperInterval is how often the background reaper runs when
+// DECISION_TRACE_REAPER_INTERVAL_MINUTES is unset
+const DefaultSessionReaperInterval = 15 * time.Minute
+
+// DefaultSessionMaxIdle is how long an active session may go without events
+// before the reaper ends it, when DECISION_TRACE_SESSION_MAX_IDLE_MINUTES is unset
+const DefaultSessionMaxIdle = 24 * time.Hour
+
+// ReapStaleSessions ends every active session whose last event is older than cutoff.
+// Returns the number of sessions ended.
+func (c *DecisionTraceSessionsCollection) ReapStaleSessions(ctx context.Context, cutoff time.Time) (int64, error) {
+	result, err := c.collection.UpdateMany(ctx,
+		bson.M{
+			"status":      "active",
+			"lastEventAt": bson.M{"$lt": cutoff},
+		},
+		bson.M{"$set": bson.M{
+			"status":  "ended",
+			"endedAt": time.Now(),
+		}},
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to reap stale sessions: %w", err)
+	}
+	return result.ModifiedCount, nil
+}
+
+// RunStaleSessionReaper reaps stale decision trace sessions every interval until
+// ctx is cancelled. It blocks, so callers run it in its own goroutine.
+func RunStaleSessionReaper(ctx context.Context, interval, maxIdle time.Duration) {
+	if interval <= 0 {
+		interval = DefaultSessionReaperInterval
+	}
+	if maxIdle <= 0 {
+		maxIdle = DefaultSessionMaxIdle
+	}
+
+	log.Printf("🧹 Decision trace session reaper started (interval %s, max idle %s)", interval, maxIdle)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("🧹 Decision trace session reaper stopped")
+			return
+		case <-ticker.C:
+			reapCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+			ended, err := AppCollections.DecisionTraceSessions.ReapStaleSessions(reapCtx, time.Now().Add(-maxIdle))
+			cancel()
+			if err != nil {
+				log.Printf("⚠️  Decision trace session reaper: %v", err)
+			} else if ended > 0 {
+				log.Printf("🧹 Decision trace session reaper ended %d stale session(s)", ended)
+			}
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// DefaultSuspiciousPasteSubmitMs is the paste-to-submit delta under which a
+// submission is flagged as a likely paste-then-submit
+const DefaultSuspiciousPasteSubmitMs int64 = 30000
+
+// EditorSignalStats aggregates clipboard signals across a project's submissions.
+// Submissions without editorSignals are excluded from every denominator.
+type EditorSignalStats struct {
+	ProjectID              string  `json:"projectId"`
+	SubmissionsWithSignals int     `json:"submissionsWithSignals"`
+	AvgPasteCount          float64 `json:"avgPasteCount"`
+	MedianPastedChars      float64 `json:"medianPastedChars"`
+	SuspiciousSubmissions  int     `json:"suspiciousSubmissions"`
+	SuspiciousFraction     float64 `json:"suspiciousFraction"`
+	SuspiciousThresholdMs  int64   `json:"suspiciousThresholdMs"`
+	// Passing submissions whose code was (nearly) the unchanged starter files.
+	// Counted over all submissions, not only those with signals.
+	PassedWithStarterSubmissions int `json:"passedWithStarterSubmissions"`
+}
+
+// CountPassedWithStarterSubmissions counts a project's submissions flagged passedWithStarter
+func CountPassedWithStarterSubmissions(ctx context.Context, projectID string, excludedSupabaseUserIDs []string) (int, error) {
+	filter := ExcludeTestSubmissions(bson.M{
+		"problemId":         projectID,
+		"passedWithStarter": true,
+	})
+	if len(excludedSupabaseUserIDs) > 0 {
+		filter["supabaseUserId"] = bson.M{"$nin": excludedSupabaseUserIDs}
+	}
+
+	count, err := GetAnalyticsBrowserSubmissionsCollection().CountDocuments(ctx, filter)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count passed-with-starter submissions: %w", err)
+	}
+	return int(count), nil
+}
+
+// GetEditorSignalStatsByProject aggregates meta.editorSignals for a project's submissions.
+// projectID is the projectNumber as a string (same as problemId).
+func GetEditorSignalStatsByProject(ctx context.Context, projectID string, thresholdMs int64, excludedSupabaseUserIDs []string) (*EditorSignalStats, error) {
+	collection := GetAnalyticsBrowserSubmissionsCollection()
+
+	match := bson.M{
+		"problemId":          projectID,
+		"meta.editorSignals": bson.M{"$exists": true, "$ne": nil},
+	}
+	if len(excludedSupabaseUserIDs) > 0 {
+		match["supabaseUserId"] = bson.M{"$nin": excludedSupabaseUserIDs}
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: bson.M{
+			"_id":           nil,
+			"count":         bson.M{"$sum": 1},
+			"avgPasteCount": bson.M{"$avg": bson.M{"$ifNull": bson.A{"$meta.editorSignals.pasteCount", 0}}},
+			"pastedChars":   bson.M{"$push": bson.M{"$ifNull": bson.A{"$meta.editorSignals.pastedCharsTotal", 0}}},
+			"suspicious": bson.M{"$sum": bson.M{"$cond": bson.A{
+				bson.M{"$and": bson.A{
+					bson.M{"$gte": bson.A{"$meta.editorSignals.submitAfterPasteDeltaMs", 0}},
+					bson.M{"$lte": bson.A{"$meta.editorSignals.submitAfterPasteDeltaMs", thresholdMs}},
+				}},
+				1, 0,
+			}}},
+		}}},
+	}
+
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate editor signals: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	stats := &EditorSignalStats{
+		ProjectID:             projectID,
+		SuspiciousThresholdMs: thresholdMs,
+	}
+
+	var result struct {
+		Count         int       `bson:"count"`
+		AvgPasteCount float64   `bson:"avgPasteCount"`
+		PastedChars   []float64 `bson:"pastedChars"`
+		Suspicious    int       `bson:"suspicious"`
+	}
+	if !cursor.Next(ctx) {
+		return stats, cursor.Err()
+	}
+	if err := cursor.Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode editor signal stats: %w", err)
+	}
+
+	stats.SubmissionsWithSignals = result.Count
+	stats.AvgPasteCount = result.AvgPasteCount
+	stats.SuspiciousSubmissions = result.Suspicious
+	if result.Count > 0 {
+		stats.SuspiciousFraction = float64(result.Suspicious) / float64(result.Count)
+	}
+
+	sort.Float64s(result.PastedChars)
+	if n := len(result.PastedChars); n > 0 {
+		if n%2 == 0 {
+			stats.MedianPastedChars = (result.PastedChars[n/2-1] + result.PastedChars[n/2]) / 2
+		} else {
+			stats.MedianPastedChars = result.PastedChars[n/2]
+		}
+	}
+	return stats, nil
+}
@@ -0,0 +1,203 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Common runner event tag keys; these have indexes (see CreateTelemetryIndexes)
+const (
+	EventTagFeature    = "feature"
+	EventTagExperiment = "experiment"
+	EventTagVariant    = "variant"
+	EventTagSource     = "source" // Acquisition channel, e.g. "linkedin", "newsletter"
+)
+
+// UnknownEventTagValue groups events that do not carry the requested tag
+const UnknownEventTagValue = "unknown"
+
+const (
+	maxEventTags        = 10
+	maxEventTagValueLen = 64
+)
+
+// eventTagKeyPattern keeps tag keys safe to use in a dotted field path
+var eventTagKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,31}$`)
+
+// NormalizeEventTags lowercases and trims tag keys, trims values and drops invalid
+// entries (bad key, empty or oversized value) so telemetry ingestion never fails on
+// tags. Keeps at most maxEventTags entries; returns nil when nothing is left.
+func NormalizeEventTags(tags map[string]string) map[string]string {
+	if len(tags) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(tags))
+	for k, v := range tags {
+		key := strings.ToLower(strings.TrimSpace(k))
+		value := strings.TrimSpace(v)
+		if !eventTagKeyPattern.MatchString(key) || value == "" || len(value) > maxEventTagValueLen {
+			continue
+		}
+		out[key] = value
+		if len(out) >= maxEventTags {
+			break
+		}
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+// CountDistinctUsersByTag returns tag value -> distinct users for events carrying tag key,
+// optionally limited to one event type
+func (tc *TelemetryCollection) CountDistinctUsersByTag(ctx context.Context, key string, eventType string, excludedSupabaseUserIDs []string) (map[string]int, error) {
+	if !eventTagKeyPattern.MatchString(key) {
+		return nil, fmt.Errorf("invalid tag key %q", key)
+	}
+	if err := ValidateEventTypeFilter(eventType); err != nil {
+		return nil, err
+	}
+
+	field := "tags." + key
+	match := bson.M{
+		field:    bson.M{"$exists": true},
+		"userId": bson.M{"$exists": true, "$ne": ""},
+	}
+	if eventType != "" {
+		match["event"] = eventType
+	}
+	if len(excludedSupabaseUserIDs) > 0 {
+		match["userId"] = bson.M{"$nin": excludedSupabaseUserIDs, "$exists": true, "$ne": ""}
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: bson.M{
+			"_id": bson.M{"value": "$" + field, "userId": "$userId"},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   "$_id.value",
+			"users": bson.M{"$sum": 1},
+		}}},
+	}
+
+	cursor, err := tc.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate users by tag: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	counts := make(map[string]int)
+	for cursor.Next(ctx) {
+		var row struct {
+			Value string `bson:"_id"`
+			Users int    `bson:"users"`
+		}
+		if err := cursor.Decode(&row); err != nil {
+			continue
+		}
+		counts[row.Value] = row.Users
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("cursor error: %w", err)
+	}
+	return counts, nil
+}
+
+// WarmupSourceStats is warmup (Project 0) engagement for one acquisition source
+type WarmupSourceStats struct {
+	Source       string  `json:"source"`
+	RunUsers     int     `json:"runUsers"`
+	RunEvents    int     `json:"runEvents"`
+	SubmitUsers  int     `json:"submitUsers"`
+	SubmitEvents int     `json:"submitEvents"`
+	SubmitRate   float64 `json:"submitRate"` // submitUsers / runUsers, 0 when nobody ran
+}
+
+// GetWarmupStatsBySource counts warmup run and submit attempts per tags.source value,
+// with untagged events under UnknownEventTagValue. Sorted by runUsers desc, then source.
+// A user whose events carry different sources is counted once under each of them.
+func (tc *TelemetryCollection) GetWarmupStatsBySource(ctx context.Context, excludedSupabaseUserIDs []string) ([]WarmupSourceStats, error) {
+	match := bson.M{
+		"event":                bson.M{"$in": []string{EventProjectRunAttempt, EventProjectSubmitAttempt}},
+		"properties.projectId": ProblemIDForProjectNumber(0),
+		"userId":               bson.M{"$exists": true, "$ne": ""},
+	}
+	if len(excludedSupabaseUserIDs) > 0 {
+		match["userId"] = bson.M{"$nin": excludedSupabaseUserIDs, "$exists": true, "$ne": ""}
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: bson.M{
+			"_id": bson.M{
+				"source": bson.M{"$ifNull": bson.A{"$tags." + EventTagSource, UnknownEventTagValue}},
+				"event":  "$event",
+				"userId": "$userId",
+			},
+			"events": bson.M{"$sum": 1},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":    bson.M{"source": "$_id.source", "event": "$_id.event"},
+			"users":  bson.M{"$sum": 1},
+			"events": bson.M{"$sum": "$events"},
+		}}},
+	}
+
+	cursor, err := tc.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate warmup events by source: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	bySource := make(map[string]*WarmupSourceStats)
+	for cursor.Next(ctx) {
+		var row struct {
+			ID struct {
+				Source string `bson:"source"`
+				Event  string `bson:"event"`
+			} `bson:"_id"`
+			Users  int `bson:"users"`
+			Events int `bson:"events"`
+		}
+		if err := cursor.Decode(&row); err != nil {
+			continue
+		}
+		stats, ok := bySource[row.ID.Source]
+		if !ok {
+			stats = &WarmupSourceStats{Source: row.ID.Source}
+			bySource[row.ID.Source] = stats
+		}
+		if row.ID.Event == EventProjectRunAttempt {
+			stats.RunUsers, stats.RunEvents = row.Users, row.Events
+		} else {
+			stats.SubmitUsers, stats.SubmitEvents = row.Users, row.Events
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("cursor error: %w", err)
+	}
+
+	results := make([]WarmupSourceStats, 0, len(bySource))
+	for _, stats := range bySource {
+		if stats.RunUsers > 0 {
+			stats.SubmitRate = math.Round(float64(stats.SubmitUsers)/float64(stats.RunUsers)*100) / 100
+		}
+		results = append(results, *stats)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].RunUsers != results[j].RunUsers {
+			return results[i].RunUsers > results[j].RunUsers
+		}
+		return results[i].Source < results[j].Source
+	})
+	return results, nil
+}
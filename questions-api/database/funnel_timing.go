@@ -0,0 +1,135 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// WarmupToCurriculumTiming pairs a user's first warmup submission with their first
+// run on a real project. FirstCurriculumRunAt is nil when they never entered the curriculum.
+type WarmupToCurriculumTiming struct {
+	UserID               string
+	WarmupSubmittedAt    time.Time
+	FirstCurriculumRunAt *time.Time
+}
+
+// GetWarmupToCurriculumTimings returns, for every user who submitted Project 0 (warmup),
+// the time of that first submission and of their first project_run_attempt on a real
+// project (projectNumber >= 1).
+func GetWarmupToCurriculumTimings(ctx context.Context, excludedSupabaseUserIDs []string) ([]WarmupToCurriculumTiming, error) {
+	// Step 1: first warmup submission per user (problemId is projectNumber as string)
+	submissionFilter := ExcludeTestSubmissions(bson.M{
+		"sourceType": "project",
+		"problemId":  "0",
+		"userId":     bson.M{"$exists": true, "$ne": ""},
+	})
+	if len(excludedSupabaseUserIDs) > 0 {
+		submissionFilter["$nor"] = []bson.M{
+			{"userId": bson.M{"$in": excludedSupabaseUserIDs}},
+			{"supabaseUserId": bson.M{"$in": excludedSupabaseUserIDs}},
+		}
+	}
+	warmupFirst, err := firstEventTimeByUser(ctx, GetAnalyticsBrowserSubmissionsCollection(), submissionFilter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate warmup submissions: %w", err)
+	}
+	if len(warmupFirst) == 0 {
+		return []WarmupToCurriculumTiming{}, nil
+	}
+
+	// Step 2: first real-project run per warmup user (telemetry projectId is projectNumber as string)
+	realProjectIDs, err := getRealProjectIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	warmupUserIDs := make([]string, 0, len(warmupFirst))
+	for userID := range warmupFirst {
+		warmupUserIDs = append(warmupUserIDs, userID)
+	}
+
+	curriculumFirst := map[string]time.Time{}
+	if len(realProjectIDs) > 0 {
+		runFilter := bson.M{
+			"event":                EventProjectRunAttempt,
+			"properties.projectId": bson.M{"$in": realProjectIDs},
+			"userId":               bson.M{"$in": warmupUserIDs},
+		}
+		curriculumFirst, err = firstEventTimeByUser(ctx, GetAnalyticsTelemetryCollection().collection, runFilter)
+		if err != nil {
+			return nil, fmt.Errorf("failed to aggregate curriculum runs: %w", err)
+		}
+	}
+
+	timings := make([]WarmupToCurriculumTiming, 0, len(warmupFirst))
+	for userID, submittedAt := range warmupFirst {
+		timing := WarmupToCurriculumTiming{UserID: userID, WarmupSubmittedAt: submittedAt}
+		if runAt, ok := curriculumFirst[userID]; ok {
+			runAt := runAt
+			timing.FirstCurriculumRunAt = &runAt
+		}
+		timings = append(timings, timing)
+	}
+	return timings, nil
+}
+
+// firstEventTimeByUser groups matching documents by userId and returns the earliest createdAt
+func firstEventTimeByUser(ctx context.Context, collection *mongo.Collection, filter bson.M) (map[string]time.Time, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   "$userId",
+			"first": bson.M{"$min": "$createdAt"},
+		}}},
+	}
+
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	result := make(map[string]time.Time)
+	for cursor.Next(ctx) {
+		var doc struct {
+			UserID string    `bson:"_id"`
+			First  time.Time `bson:"first"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			continue
+		}
+		if doc.UserID != "" {
+			result[doc.UserID] = doc.First
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// getRealProjectIDs returns the projectNumber (as string) of every real project (projectNumber >= 1)
+func getRealProjectIDs(ctx context.Context) ([]string, error) {
+	cursor, err := GetContentDb().Collection("projects").Find(ctx, bson.M{"projectNumber": bson.M{"$gte": 1}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query real projects: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var projectIDs []string
+	for cursor.Next(ctx) {
+		var doc struct {
+			ProjectNumber int `bson:"projectNumber"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			continue
+		}
+		projectIDs = append(projectIDs, strconv.Itoa(doc.ProjectNumber))
+	}
+	return projectIDs, cursor.Err()
+}
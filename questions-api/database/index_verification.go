@@ -0,0 +1,131 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// criticalIndex is an app DB index that hot or analytics queries depend on.
+// Matched by key pattern, not name, so indexes created by hand still count.
+type criticalIndex struct {
+	collection string
+	keys       bson.D
+}
+
+// criticalIndexes lists the compound indexes whose absence turns common queries
+// into collection scans. Keep in sync with the EnsureIndexes/Create*Indexes functions.
+var criticalIndexes = []criticalIndex{
+	// decision_trace_sessions / decision_trace_events (EnsureIndexes)
+	{"decision_trace_sessions", bson.D{{Key: "userId", Value: 1}, {Key: "contentId", Value: 1}, {Key: "contentType", Value: 1}, {Key: "language", Value: 1}, {Key: "environment", Value: 1}}},
+	{"decision_trace_events", bson.D{{Key: "userId", Value: 1}, {Key: "contentId", Value: 1}, {Key: "createdAt", Value: -1}}},
+	{"decision_trace_events", bson.D{{Key: "sessionId", Value: 1}, {Key: "createdAt", Value: 1}}},
+	{"decision_trace_events", bson.D{{Key: "createdAt", Value: -1}}},
+
+	// activity_progress (EnsureActivityProgressIndexes)
+	{"activity_progress", bson.D{{Key: "email", Value: 1}, {Key: "moduleId", Value: 1}, {Key: "activityId", Value: 1}}},
+
+	// browser_submissions (CreateSubmissionIndexes, CreateBrowserSubmissionExtraIndexes)
+	{"browser_submissions", bson.D{{Key: "supabaseUserId", Value: 1}, {Key: "sourceType", Value: 1}, {Key: "createdAt", Value: -1}}},
+	{"browser_submissions", bson.D{{Key: "problemId", Value: 1}, {Key: "supabaseUserId", Value: 1}}},
+	{"browser_submissions", bson.D{{Key: "environment", Value: 1}, {Key: "supabaseUserId", Value: 1}, {Key: "createdAt", Value: -1}}},
+	{"browser_submissions", bson.D{{Key: "clientSubmissionId", Value: 1}}},
+
+	// runner_events (CreateTelemetryIndexes)
+	{"runner_events", bson.D{{Key: "supabaseUserId", Value: 1}, {Key: "createdAt", Value: -1}}},
+	{"runner_events", bson.D{{Key: "event", Value: 1}, {Key: "supabaseUserId", Value: 1}}},
+	{"runner_events", bson.D{{Key: "createdAt", Value: -1}}},
+	{"runner_events", bson.D{{Key: "environment", Value: 1}, {Key: "supabaseUserId", Value: 1}, {Key: "createdAt", Value: -1}}},
+
+	// report_cards (CreateReportCardIndexes)
+	{"report_cards", bson.D{{Key: "userId", Value: 1}}},
+}
+
+// MissingIndex names an expected index that is absent from the active app DB
+type MissingIndex struct {
+	Database   string `json:"database"`
+	Collection string `json:"collection"`
+	Keys       string `json:"keys"` // e.g. "supabaseUserId_1_createdAt_-1"
+}
+
+// VerifyCriticalIndexes lists the indexes of each critical collection in the active
+// app DB and returns the expected ones that are missing. A collection that does
+// not exist yet reports all of its expected indexes as missing.
+func VerifyCriticalIndexes(ctx context.Context) ([]MissingIndex, error) {
+	db := GetAppDb()
+
+	existingByCollection := make(map[string]map[string]bool)
+	missing := make([]MissingIndex, 0)
+	for _, expected := range criticalIndexes {
+		existing, ok := existingByCollection[expected.collection]
+		if !ok {
+			var err error
+			existing, err = listIndexKeySignatures(ctx, db.Collection(expected.collection).Indexes())
+			if err != nil {
+				return nil, fmt.Errorf("failed to list %s indexes: %w", expected.collection, err)
+			}
+			existingByCollection[expected.collection] = existing
+		}
+
+		signature := indexKeySignature(expected.keys)
+		if !existing[signature] {
+			missing = append(missing, MissingIndex{
+				Database:   db.Name(),
+				Collection: expected.collection,
+				Keys:       signature,
+			})
+		}
+	}
+	return missing, nil
+}
+
+// listIndexKeySignatures returns the key signatures of every index on a collection.
+// A missing collection yields an empty set rather than an error.
+func listIndexKeySignatures(ctx context.Context, view mongo.IndexView) (map[string]bool, error) {
+	specs, err := view.ListSpecifications(ctx)
+	if err != nil {
+		var cmdErr mongo.CommandError
+		// 26 = NamespaceNotFound
+		if errors.As(err, &cmdErr) && cmdErr.Code == 26 {
+			return map[string]bool{}, nil
+		}
+		return nil, err
+	}
+
+	signatures := make(map[string]bool, len(specs))
+	for _, spec := range specs {
+		var keys bson.D
+		if err := bson.Unmarshal(spec.KeysDocument, &keys); err != nil {
+			return nil, fmt.Errorf("failed to decode index %s keys: %w", spec.Name, err)
+		}
+		signatures[indexKeySignature(keys)] = true
+	}
+	return signatures, nil
+}
+
+// indexKeySignature renders a key pattern the way Mongo names default indexes
+// ("field_1_other_-1"); numeric directions are normalized across int32/int64/float64.
+func indexKeySignature(keys bson.D) string {
+	parts := make([]string, 0, len(keys)*2)
+	for _, k := range keys {
+		var direction string
+		switch v := k.Value.(type) {
+		case int:
+			direction = fmt.Sprint(v)
+		case int32:
+			direction = fmt.Sprint(v)
+		case int64:
+			direction = fmt.Sprint(v)
+		case float64:
+			direction = fmt.Sprint(int64(v))
+		default:
+			direction = fmt.Sprint(v)
+		}
+		parts = append(parts, k.Key, direction)
+	}
+	return strings.Join(parts, "_")
+}
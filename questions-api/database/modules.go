@@ -314,3 +314,41 @@ func (m *ModulesCollection) DeleteModule(ctx context.Context, moduleId string) (
 
 	return result.DeletedCount > 0, nil
 }
+
+// ModuleReference is a module that embeds a given content item (by refId)
+type ModuleReference struct {
+	ModuleID       string `json:"moduleId"`
+	Title          string `json:"title"`
+	ContentIndexes []int  `json:"contentIndexes"` // Positions in the module's content list
+}
+
+// FindModulesReferencing returns every module with a content item whose refId is refID
+func (m *ModulesCollection) FindModulesReferencing(ctx context.Context, refID primitive.ObjectID) ([]ModuleReference, error) {
+	cursor, err := m.collection.Find(ctx, bson.M{"content.refId": refID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query referencing modules: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	references := make([]ModuleReference, 0)
+	for cursor.Next(ctx) {
+		var module shared.ModuleDocument
+		if err := cursor.Decode(&module); err != nil {
+			continue
+		}
+		ref := ModuleReference{
+			ModuleID: module.ID.Hex(),
+			Title:    module.Title,
+		}
+		for i, content := range module.Content {
+			if content.RefID == refID {
+				ref.ContentIndexes = append(ref.ContentIndexes, i)
+			}
+		}
+		references = append(references, ref)
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("cursor error: %w", err)
+	}
+	return references, nil
+}
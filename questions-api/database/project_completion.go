@@ -0,0 +1,158 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DefaultRequiredPasses is how many passing submissions complete a project
+// when the project does not set requiredPasses
+const DefaultRequiredPasses = 1
+
+// GetRequiredPassesByProblemID returns problemId (projectNumber as string) -> requiredPasses
+// for every project that demands more than DefaultRequiredPasses passing submissions.
+// Projects not in the map use the default.
+func GetRequiredPassesByProblemID(ctx context.Context) (map[string]int, error) {
+	projectsCol := GetContentDb().Collection("projects")
+
+	cursor, err := projectsCol.Find(ctx, bson.M{"requiredPasses": bson.M{"$gt": DefaultRequiredPasses}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query required passes: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	required := make(map[string]int)
+	for cursor.Next(ctx) {
+		var doc struct {
+			ProjectNumber  int `bson:"projectNumber"`
+			RequiredPasses int `bson:"requiredPasses"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			continue
+		}
+		required[strconv.Itoa(doc.ProjectNumber)] = doc.RequiredPasses
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("cursor error: %w", err)
+	}
+	return required, nil
+}
+
+// requiredPassesMatch builds a $match clause for a stage that has grouped passing
+// submissions into a `passes` count keyed by problemField. Projects listed in
+// required need at least that many passes; every other project needs DefaultRequiredPasses.
+func requiredPassesMatch(problemField string, required map[string]int) bson.M {
+	if len(required) == 0 {
+		return bson.M{"passes": bson.M{"$gte": DefaultRequiredPasses}}
+	}
+
+	custom := make([]string, 0, len(required))
+	clauses := make([]bson.M, 0, len(required)+1)
+	for problemID, n := range required {
+		custom = append(custom, problemID)
+		clauses = append(clauses, bson.M{
+			problemField: problemID,
+			"passes":     bson.M{"$gte": n},
+		})
+	}
+	clauses = append(clauses, bson.M{
+		problemField: bson.M{"$nin": custom},
+		"passes":     bson.M{"$gte": DefaultRequiredPasses},
+	})
+	return bson.M{"$or": clauses}
+}
+
+// DefaultMinTestCount is the minimum number of tests a run must report before
+// it can count as passed when the project does not set minTestCount
+const DefaultMinTestCount = 1
+
+// GetProjectMinTestCount returns the effective minimum test count for a project
+// submission's problemId (projectNumber as string). Unknown projects and projects
+// without the field use DefaultMinTestCount.
+func GetProjectMinTestCount(ctx context.Context, problemID string) (int, error) {
+	projectNumber, err := strconv.Atoi(strings.TrimSpace(problemID))
+	if err != nil {
+		return DefaultMinTestCount, nil
+	}
+
+	var doc struct {
+		MinTestCount int `bson:"minTestCount"`
+	}
+	err = GetContentDb().Collection("projects").FindOne(ctx,
+		bson.M{"projectNumber": projectNumber},
+		options.FindOne().SetProjection(bson.M{"minTestCount": 1}),
+	).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return DefaultMinTestCount, nil
+	}
+	if err != nil {
+		return DefaultMinTestCount, fmt.Errorf("failed to get min test count: %w", err)
+	}
+	if doc.MinTestCount < DefaultMinTestCount {
+		return DefaultMinTestCount, nil
+	}
+	return doc.MinTestCount, nil
+}
+
+// projectIDFilter matches a project by projectNumber (as string) or ObjectID hex
+func projectIDFilter(projectID string) (bson.M, error) {
+	projectID = strings.TrimSpace(projectID)
+	if projectNumber, err := strconv.Atoi(projectID); err == nil {
+		return bson.M{"projectNumber": projectNumber}, nil
+	}
+	if oid, err := primitive.ObjectIDFromHex(projectID); err == nil {
+		return bson.M{"_id": oid}, nil
+	}
+	return nil, fmt.Errorf("invalid project id %q", projectID)
+}
+
+// SetProjectCompletionSettings stores requiredPasses and minTestCount on a project
+// identified by its projectNumber (as string) or, for legacy callers, its ObjectID hex,
+// in a single update. A nil setting is left untouched; values at or below the default
+// clear the field so the default applies.
+func SetProjectCompletionSettings(ctx context.Context, projectID string, requiredPasses, minTestCount *int) error {
+	filter, err := projectIDFilter(projectID)
+	if err != nil {
+		return err
+	}
+
+	set := bson.M{}
+	unset := bson.M{}
+	if requiredPasses != nil {
+		if *requiredPasses > DefaultRequiredPasses {
+			set["requiredPasses"] = *requiredPasses
+		} else {
+			unset["requiredPasses"] = ""
+		}
+	}
+	if minTestCount != nil {
+		if *minTestCount > DefaultMinTestCount {
+			set["minTestCount"] = *minTestCount
+		} else {
+			unset["minTestCount"] = ""
+		}
+	}
+
+	update := bson.M{}
+	if len(set) > 0 {
+		update["$set"] = set
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	if len(update) == 0 {
+		return nil
+	}
+
+	if _, err := GetContentDb().Collection("projects").UpdateOne(ctx, filter, update); err != nil {
+		return fmt.Errorf("failed to set project completion settings: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// GetMedianAttemptsBeforePassByProject returns problemId -> median number of failed
+// submissions a user made before their first passing one, over users who eventually
+// passed. Projects nobody has passed are absent from the map. Only submissions since
+// the given time are read (zero = all), and only the fields the grouping needs are
+// carried into the sort so file contents never reach it.
+func GetMedianAttemptsBeforePassByProject(ctx context.Context, since time.Time, excludedSupabaseUserIDs []string) (map[string]float64, error) {
+	collection := GetAnalyticsBrowserSubmissionsCollection()
+
+	match := ExcludeTestSubmissions(bson.M{
+		"$or": []bson.M{
+			{"sourceType": "project"},
+			{"sourceType": bson.M{"$exists": false}},
+			{"sourceType": ""},
+		},
+	})
+	if !since.IsZero() {
+		match["createdAt"] = bson.M{"$gte": since}
+	}
+	if len(excludedSupabaseUserIDs) > 0 {
+		match["supabaseUserId"] = bson.M{"$nin": excludedSupabaseUserIDs}
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$project", Value: bson.M{
+			"problemId":      1,
+			"supabaseUserId": 1,
+			"userId":         1,
+			"passed":         1,
+			"createdAt":      1,
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id": bson.M{
+				"problemId": "$problemId",
+				"user":      bson.M{"$ifNull": bson.A{"$supabaseUserId", "$userId"}},
+			},
+			"results": bson.M{"$push": "$passed"},
+		}}},
+		// Index of the first pass == failed attempts before it; -1 means never passed
+		{{Key: "$project", Value: bson.M{
+			"attempts": bson.M{"$indexOfArray": bson.A{"$results", true}},
+		}}},
+		{{Key: "$match", Value: bson.M{"attempts": bson.M{"$gte": 0}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":      "$_id.problemId",
+			"attempts": bson.M{"$push": "$attempts"},
+		}}},
+	}
+
+	cursor, err := collection.Aggregate(ctx, pipeline, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate attempts before pass: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	medians := make(map[string]float64)
+	for cursor.Next(ctx) {
+		var row struct {
+			ProblemID string `bson:"_id"`
+			Attempts  []int  `bson:"attempts"`
+		}
+		if err := cursor.Decode(&row); err != nil {
+			continue
+		}
+		if len(row.Attempts) == 0 {
+			continue
+		}
+		medians[row.ProblemID] = medianInts(row.Attempts)
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("cursor error: %w", err)
+	}
+	return medians, nil
+}
+
+// GetMedianRunsBeforeFirstPassByProject returns project contentId -> median number of
+// decision-trace RUN events a user made before their first fully passing SUBMIT, over
+// users who reached one. Users who never fully passed are left out of the median.
+// Only events since the given time are read (zero = all), which bounds the sort and
+// per-user $push on large collections.
+func (c *DecisionTraceEventsCollection) GetMedianRunsBeforeFirstPassByProject(ctx context.Context, since time.Time, excludedSupabaseUserIDs []string) (map[string]float64, error) {
+	match := bson.M{
+		"contentType": "project",
+		"eventType":   bson.M{"$in": bson.A{"RUN", "SUBMIT"}},
+	}
+	if !since.IsZero() {
+		match["createdAt"] = bson.M{"$gte": since}
+	}
+	if len(excludedSupabaseUserIDs) > 0 {
+		match["userId"] = bson.M{"$nin": excludedSupabaseUserIDs}
+	}
+
+	// Same rule as the handler's allTestsPassed: tests ran, none failed, no timeout
+	fullPass := bson.M{"$and": bson.A{
+		bson.M{"$eq": bson.A{"$eventType", "SUBMIT"}},
+		bson.M{"$gt": bson.A{bson.M{"$ifNull": bson.A{"$execution.tests.total", 0}}, 0}},
+		bson.M{"$eq": bson.A{"$execution.tests.failed", 0}},
+		bson.M{"$ne": bson.A{"$execution.timedOut", true}},
+		bson.M{"$ne": bson.A{"$execution.universalErrorCode", DTErrorCodeTimeout}},
+	}}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$sort", Value: bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   bson.M{"contentId": "$contentId", "userId": "$userId"},
+			"steps": bson.M{"$push": bson.M{"$cond": bson.A{fullPass, "PASS", "$eventType"}}},
+		}}},
+		// Count RUNs until the first PASS; passed stays false for users who never got there
+		{{Key: "$project", Value: bson.M{
+			"progress": bson.M{"$reduce": bson.M{
+				"input":        "$steps",
+				"initialValue": bson.M{"passed": false, "runs": 0},
+				"in": bson.M{
+					"passed": bson.M{"$or": bson.A{"$$value.passed", bson.M{"$eq": bson.A{"$$this", "PASS"}}}},
+					"runs": bson.M{"$cond": bson.A{
+						bson.M{"$and": bson.A{bson.M{"$not": bson.A{"$$value.passed"}}, bson.M{"$eq": bson.A{"$$this", "RUN"}}}},
+						bson.M{"$add": bson.A{"$$value.runs", 1}},
+						"$$value.runs",
+					}},
+				},
+			}},
+		}}},
+		{{Key: "$match", Value: bson.M{"progress.passed": true}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":  "$_id.contentId",
+			"runs": bson.M{"$push": "$progress.runs"},
+		}}},
+	}
+
+	cursor, err := c.collection.Aggregate(ctx, pipeline, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate runs before first pass: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	medians := make(map[string]float64)
+	for cursor.Next(ctx) {
+		var row struct {
+			ContentID string `bson:"_id"`
+			Runs      []int  `bson:"runs"`
+		}
+		if err := cursor.Decode(&row); err != nil {
+			continue
+		}
+		if len(row.Runs) == 0 {
+			continue
+		}
+		medians[row.ContentID] = medianInts(row.Runs)
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("cursor error: %w", err)
+	}
+	return medians, nil
+}
+
+// medianInts sorts values in place and returns their median; values must be non-empty
+func medianInts(values []int) float64 {
+	sort.Ints(values)
+	n := len(values)
+	if n%2 == 0 {
+		return float64(values[n/2-1]+values[n/2]) / 2
+	}
+	return float64(values[n/2])
+}
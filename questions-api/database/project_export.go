@@ -0,0 +1,27 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// OpenProjectExportCursor returns a cursor over full project documents (including
+// starter and test files) sorted by projectNumber, optionally limited to one category.
+// The caller must close the cursor.
+func OpenProjectExportCursor(ctx context.Context, category string) (*mongo.Cursor, error) {
+	filter := bson.M{}
+	if category != "" {
+		filter["category"] = category
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "projectNumber", Value: 1}})
+	cursor, err := GetContentDb().Collection("projects").Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query projects for export: %w", err)
+	}
+	return cursor, nil
+}
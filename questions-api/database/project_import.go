@@ -0,0 +1,112 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gerdinv/questions-api/shared"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Per-item outcomes of BulkUpsertProjects
+const (
+	ProjectImportCreated = "created"
+	ProjectImportUpdated = "updated"
+	ProjectImportFailed  = "failed"
+)
+
+// ProjectImport is one project to upsert, keyed by ProjectNumber
+type ProjectImport struct {
+	ProjectNumber int
+	Payload       shared.ProjectPayload
+}
+
+// ProjectImportResult is the outcome for the ProjectImport at the same index
+type ProjectImportResult struct {
+	Status string
+	Error  string
+}
+
+// BulkUpsertProjects upserts projects keyed on projectNumber in a single unordered
+// bulk write, so one failing item doesn't stop the rest. Returns one result per
+// input item. The error is only set when the whole write failed (e.g. connection loss).
+func BulkUpsertProjects(ctx context.Context, imports []ProjectImport) ([]ProjectImportResult, error) {
+	results := make([]ProjectImportResult, len(imports))
+	if len(imports) == 0 {
+		return results, nil
+	}
+
+	now := time.Now()
+	models := make([]mongo.WriteModel, 0, len(imports))
+	for _, item := range imports {
+		p := item.Payload
+		set := bson.M{
+			"projectNumber": item.ProjectNumber,
+			"title":         p.Title,
+			"description":   p.Description,
+			"difficulty":    p.Difficulty,
+			"instructions":  p.Instructions,
+			"starterFiles":  p.StarterFiles,
+			"testFile":      p.TestFile,
+			"category":      p.Category,
+			"tags":          p.Tags,
+			"updatedAt":     now,
+		}
+		unset := bson.M{}
+		// Defaults are stored as absent fields, matching SetProjectCompletionSettings
+		if p.RequiredPasses > DefaultRequiredPasses {
+			set["requiredPasses"] = p.RequiredPasses
+		} else {
+			unset["requiredPasses"] = ""
+		}
+		if p.MinTestCount > DefaultMinTestCount {
+			set["minTestCount"] = p.MinTestCount
+		} else {
+			unset["minTestCount"] = ""
+		}
+
+		update := bson.M{
+			"$set":         set,
+			"$setOnInsert": bson.M{"createdAt": now},
+		}
+		if len(unset) > 0 {
+			update["$unset"] = unset
+		}
+
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"projectNumber": item.ProjectNumber}).
+			SetUpdate(update).
+			SetUpsert(true))
+	}
+
+	res, err := GetContentDb().Collection("projects").BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+
+	failed := make(map[int]string)
+	if err != nil {
+		var bulkErr mongo.BulkWriteException
+		if !errors.As(err, &bulkErr) {
+			return nil, fmt.Errorf("failed to import projects: %w", err)
+		}
+		for _, we := range bulkErr.WriteErrors {
+			failed[we.Index] = we.Message
+		}
+	}
+
+	for i := range imports {
+		if msg, ok := failed[i]; ok {
+			results[i] = ProjectImportResult{Status: ProjectImportFailed, Error: msg}
+			continue
+		}
+		switch {
+		case res != nil && res.UpsertedIDs[int64(i)] != nil:
+			results[i] = ProjectImportResult{Status: ProjectImportCreated}
+		default:
+			results[i] = ProjectImportResult{Status: ProjectImportUpdated}
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,45 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gerdinv/questions-api/shared"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// GetProjectsByNumbers fetches the projects with the given projectNumbers in one query,
+// keyed by projectNumber. Only summary fields are loaded (no starter/test files).
+// Unknown numbers are absent from the map.
+func GetProjectsByNumbers(ctx context.Context, projectNumbers []int) (map[int]shared.ProjectDocument, error) {
+	result := make(map[int]shared.ProjectDocument, len(projectNumbers))
+	if len(projectNumbers) == 0 {
+		return result, nil
+	}
+
+	opts := options.Find().SetProjection(bson.M{
+		"projectNumber": 1,
+		"title":         1,
+		"difficulty":    1,
+		"category":      1,
+		"tags":          1,
+	})
+	cursor, err := GetContentDb().Collection("projects").Find(ctx, bson.M{"projectNumber": bson.M{"$in": projectNumbers}}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query projects by number: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var project shared.ProjectDocument
+		if err := cursor.Decode(&project); err != nil {
+			continue
+		}
+		result[project.ProjectNumber] = project
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("cursor error: %w", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,49 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ProjectTagCount is a normalized tag and how many projects carry it
+type ProjectTagCount struct {
+	Tag   string `bson:"_id" json:"tag"`
+	Count int    `bson:"count" json:"count"`
+}
+
+// GetProjectTagCounts returns every distinct tag across projects with its project count.
+// Tags are trimmed and lowercased in the pipeline so legacy unnormalized tags fold together.
+func GetProjectTagCounts(ctx context.Context) ([]ProjectTagCount, error) {
+	projectsCol := GetContentDb().Collection("projects")
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$unwind", Value: "$tags"}},
+		{{Key: "$project", Value: bson.M{
+			"tag": bson.M{"$toLower": bson.M{"$trim": bson.M{"input": "$tags"}}},
+		}}},
+		{{Key: "$match", Value: bson.M{"tag": bson.M{"$ne": ""}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":      "$tag",
+			"projects": bson.M{"$addToSet": "$_id"},
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"count": bson.M{"$size": "$projects"},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}}},
+	}
+
+	cursor, err := projectsCol.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate project tags: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	tags := []ProjectTagCount{}
+	if err := cursor.All(ctx, &tags); err != nil {
+		return nil, fmt.Errorf("failed to decode project tags: %w", err)
+	}
+	return tags, nil
+}
@@ -0,0 +1,82 @@
+package database
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/gerdinv/questions-api/config"
+)
+
+// DefaultProjectTitleCacheSize is used when PROJECT_TITLE_CACHE_SIZE is unset (0)
+const DefaultProjectTitleCacheSize = 256
+
+// projectTitleCache is a bounded LRU of project ID (number or legacy _id hex) -> title,
+// shared across requests. When full, the least recently used entry is evicted.
+type projectTitleCache struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List // front = most recently used
+	entries  map[string]*list.Element
+}
+
+type projectTitleEntry struct {
+	key   string
+	title string
+}
+
+var projectTitles = &projectTitleCache{
+	order:   list.New(),
+	entries: make(map[string]*list.Element),
+}
+
+// projectTitleCacheSize returns the configured capacity; negative disables the cache
+func projectTitleCacheSize() int {
+	size := config.GetConfig().ProjectTitleCacheSize
+	if size == 0 {
+		return DefaultProjectTitleCacheSize
+	}
+	return size
+}
+
+func (c *projectTitleCache) get(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return "", false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*projectTitleEntry).title, true
+}
+
+func (c *projectTitleCache) put(key, title string, capacity int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.capacity = capacity
+	if capacity <= 0 {
+		return
+	}
+	if el, ok := c.entries[key]; ok {
+		el.Value.(*projectTitleEntry).title = title
+		c.order.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&projectTitleEntry{key: key, title: title})
+	// Also shrinks the cache if the configured size was lowered
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*projectTitleEntry).key)
+	}
+}
+
+// InvalidateProjectTitleCache drops every cached project title; call after any
+// project create, update, delete or import
+func InvalidateProjectTitleCache() {
+	projectTitles.mu.Lock()
+	defer projectTitles.mu.Unlock()
+	projectTitles.order.Init()
+	projectTitles.entries = make(map[string]*list.Element)
+}
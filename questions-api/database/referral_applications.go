@@ -0,0 +1,414 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gerdinv/questions-api/shared"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Referral application lifecycle statuses. Webhook-created applications start as pending.
+const (
+	ReferralStatusPending   = "pending"
+	ReferralStatusInReview  = "in_review"
+	ReferralStatusReferred  = "referred"
+	ReferralStatusPlaced    = "placed"
+	ReferralStatusRejected  = "rejected"
+	ReferralStatusWithdrawn = "withdrawn"
+)
+
+// referralStatusTransitions lists the statuses each status may move to.
+// placed and withdrawn are terminal; a rejection can be reopened for review.
+var referralStatusTransitions = map[string][]string{
+	ReferralStatusPending:   {ReferralStatusInReview, ReferralStatusRejected, ReferralStatusWithdrawn},
+	ReferralStatusInReview:  {ReferralStatusPending, ReferralStatusReferred, ReferralStatusRejected, ReferralStatusWithdrawn},
+	ReferralStatusReferred:  {ReferralStatusPlaced, ReferralStatusRejected, ReferralStatusWithdrawn},
+	ReferralStatusPlaced:    {},
+	ReferralStatusRejected:  {ReferralStatusInReview},
+	ReferralStatusWithdrawn: {},
+}
+
+var (
+	// ErrReferralNotFound is returned when no application has the given ID
+	ErrReferralNotFound = errors.New("referral application not found")
+	// ErrInvalidReferralStatus is returned for a status outside the lifecycle
+	ErrInvalidReferralStatus = errors.New("invalid referral status")
+	// ErrReferralStatusTransition is returned when the current status cannot move to the requested one
+	ErrReferralStatusTransition = errors.New("referral status transition not allowed")
+	// ErrReferralStatusConflict is returned when the status changed between read and write
+	ErrReferralStatusConflict = errors.New("referral application was modified concurrently")
+)
+
+// IsValidReferralStatus reports whether status is part of the referral lifecycle
+func IsValidReferralStatus(status string) bool {
+	_, ok := referralStatusTransitions[status]
+	return ok
+}
+
+// CanTransitionReferralStatus reports whether an application may move from one status to another.
+// Staying on the same status is always allowed.
+func CanTransitionReferralStatus(from, to string) bool {
+	if from == to {
+		return true
+	}
+	for _, next := range referralStatusTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// ReferralApplicationUpdate holds the staff-editable fields; nil fields are left unchanged
+// and an empty AssignedReferrer or ReviewReason clears the field.
+type ReferralApplicationUpdate struct {
+	Status           *string
+	AssignedReferrer *string
+	ReviewReason     *string
+}
+
+// ReferralApplicationFilter narrows the admin referral queue. Zero values mean "any".
+type ReferralApplicationFilter struct {
+	Statuses          []string // match any of these statuses
+	NeedsManualReview *bool
+	TargetCompany     string // case-insensitive substring
+	OldestFirst       bool   // sort by submittedAt ascending instead of newest first
+	Skip              int64
+	Limit             int64
+}
+
+// ListReferralApplications returns one page of referral applications matching the filter,
+// sorted by submittedAt, plus the total number of matches.
+func (c *ReferralApplicationsCollection) ListReferralApplications(ctx context.Context, f ReferralApplicationFilter) ([]shared.ReferralApplicationDocument, int64, error) {
+	filter := bson.M{}
+	if len(f.Statuses) == 1 {
+		filter["status"] = f.Statuses[0]
+	} else if len(f.Statuses) > 1 {
+		filter["status"] = bson.M{"$in": f.Statuses}
+	}
+	if f.NeedsManualReview != nil {
+		filter["needsManualReview"] = *f.NeedsManualReview
+	}
+	if f.TargetCompany != "" {
+		filter["targetCompany"] = bson.M{"$regex": regexp.QuoteMeta(f.TargetCompany), "$options": "i"}
+	}
+
+	total, err := c.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count referral applications: %w", err)
+	}
+
+	direction := -1
+	if f.OldestFirst {
+		direction = 1
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "submittedAt", Value: direction}, {Key: "_id", Value: direction}}).
+		SetSkip(f.Skip)
+	if f.Limit > 0 {
+		opts.SetLimit(f.Limit)
+	}
+
+	cursor, err := c.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to find referral applications: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	apps := make([]shared.ReferralApplicationDocument, 0)
+	if err := cursor.All(ctx, &apps); err != nil {
+		return nil, 0, fmt.Errorf("failed to decode referral applications: %w", err)
+	}
+	return apps, total, nil
+}
+
+// EnsureReferralApplicationIndexes creates the indexes behind the admin referral queue filters
+func (c *ReferralApplicationsCollection) EnsureReferralApplicationIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "submittedAt", Value: -1}}},
+		{Keys: bson.D{{Key: "needsManualReview", Value: 1}, {Key: "submittedAt", Value: -1}}},
+		{Keys: bson.D{{Key: "submittedAt", Value: -1}}},
+	}
+	_, err := c.collection.Indexes().CreateMany(ctx, indexes)
+	return err
+}
+
+// UpdateReferralApplication applies a staff update and returns the updated document.
+// Status changes must follow referralStatusTransitions; the write is conditional on the
+// status read, so a concurrent change returns ErrReferralStatusConflict instead of skipping a check.
+func (c *ReferralApplicationsCollection) UpdateReferralApplication(ctx context.Context, id primitive.ObjectID, update ReferralApplicationUpdate) (*shared.ReferralApplicationDocument, error) {
+	var current shared.ReferralApplicationDocument
+	if err := c.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&current); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrReferralNotFound
+		}
+		return nil, fmt.Errorf("failed to load referral application: %w", err)
+	}
+
+	set := bson.M{"updatedAt": time.Now()}
+	unset := bson.M{}
+	if update.Status != nil {
+		if !IsValidReferralStatus(*update.Status) {
+			return nil, ErrInvalidReferralStatus
+		}
+		if !CanTransitionReferralStatus(current.Status, *update.Status) {
+			return nil, fmt.Errorf("%w: %s -> %s", ErrReferralStatusTransition, current.Status, *update.Status)
+		}
+		set["status"] = *update.Status
+	}
+	if update.AssignedReferrer != nil {
+		if *update.AssignedReferrer == "" {
+			unset["assignedReferrer"] = ""
+		} else {
+			set["assignedReferrer"] = *update.AssignedReferrer
+		}
+	}
+	if update.ReviewReason != nil {
+		if *update.ReviewReason == "" {
+			unset["reviewReason"] = ""
+		} else {
+			set["reviewReason"] = *update.ReviewReason
+		}
+	}
+
+	mods := bson.M{"$set": set}
+	if len(unset) > 0 {
+		mods["$unset"] = unset
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var updated shared.ReferralApplicationDocument
+	err := c.collection.FindOneAndUpdate(ctx, bson.M{"_id": id, "status": current.Status}, mods, opts).Decode(&updated)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrReferralStatusConflict
+		}
+		return nil, fmt.Errorf("failed to update referral application: %w", err)
+	}
+	return &updated, nil
+}
+
+// ReferralMatch is the outcome of matching an application to a user
+type ReferralMatch struct {
+	UserID            *primitive.ObjectID // app users._id, when the matched user has one
+	SupabaseUserID    string
+	MatchedBy         string // "email" | "name" | "none"
+	MatchConfidence   string // "high" | "low" | "none"
+	NeedsManualReview bool
+	ReviewReason      string
+}
+
+// GetReferralApplicationByID loads one application
+func (c *ReferralApplicationsCollection) GetReferralApplicationByID(ctx context.Context, id primitive.ObjectID) (*shared.ReferralApplicationDocument, error) {
+	var app shared.ReferralApplicationDocument
+	if err := c.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&app); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrReferralNotFound
+		}
+		return nil, fmt.Errorf("failed to load referral application: %w", err)
+	}
+	return &app, nil
+}
+
+// SetReferralMatch stores a match result, replacing any previous match, and returns the updated document.
+// matchedAt is set only when a user was matched.
+func (c *ReferralApplicationsCollection) SetReferralMatch(ctx context.Context, id primitive.ObjectID, match ReferralMatch) (*shared.ReferralApplicationDocument, error) {
+	now := time.Now()
+	set := bson.M{
+		"matchedBy":         match.MatchedBy,
+		"matchConfidence":   match.MatchConfidence,
+		"needsManualReview": match.NeedsManualReview,
+		"updatedAt":         now,
+	}
+	unset := bson.M{}
+	if match.UserID != nil {
+		set["userId"] = *match.UserID
+	} else {
+		unset["userId"] = ""
+	}
+	if match.SupabaseUserID != "" {
+		set["supabaseUserId"] = match.SupabaseUserID
+		set["matchedAt"] = now
+	} else {
+		unset["supabaseUserId"] = ""
+		unset["matchedAt"] = ""
+	}
+	if match.ReviewReason != "" {
+		set["reviewReason"] = match.ReviewReason
+	} else {
+		unset["reviewReason"] = ""
+	}
+
+	mods := bson.M{"$set": set}
+	if len(unset) > 0 {
+		mods["$unset"] = unset
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var updated shared.ReferralApplicationDocument
+	if err := c.collection.FindOneAndUpdate(ctx, bson.M{"_id": id}, mods, opts).Decode(&updated); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrReferralNotFound
+		}
+		return nil, fmt.Errorf("failed to save referral match: %w", err)
+	}
+	return &updated, nil
+}
+
+// maxReferralStatsCompanies caps the targetCompany breakdown in referral pipeline stats
+const maxReferralStatsCompanies = 50
+
+// ReferralCompanyStats is the application count for one target company
+type ReferralCompanyStats struct {
+	TargetCompany     string           `json:"targetCompany" bson:"targetCompany"`
+	Total             int64            `json:"total" bson:"total"`
+	NeedsManualReview int64            `json:"needsManualReview" bson:"needsManualReview"`
+	ByStatus          map[string]int64 `json:"byStatus" bson:"-"`
+}
+
+// ReferralPipelineStats summarizes the referral_applications collection
+type ReferralPipelineStats struct {
+	Total             int64                  `json:"total"`
+	ByStatus          map[string]int64       `json:"byStatus"`
+	NeedsManualReview int64                  `json:"needsManualReview"`
+	Matched           int64                  `json:"matched"`         // applications with a matchedAt
+	AvgHoursToMatch   *float64               `json:"avgHoursToMatch"` // nil when nothing is matched
+	ByTargetCompany   []ReferralCompanyStats `json:"byTargetCompany"` // largest first, capped
+}
+
+// GetReferralPipelineStats aggregates status counts, the manual-review backlog, average
+// submittedAt -> matchedAt time and a per-company breakdown in a single $facet query.
+// Company names are grouped case-insensitively; blank ones count as "(unspecified)".
+func (c *ReferralApplicationsCollection) GetReferralPipelineStats(ctx context.Context) (*ReferralPipelineStats, error) {
+	companyKey := bson.M{"$toLower": bson.M{"$trim": bson.M{"input": bson.M{"$ifNull": bson.A{"$targetCompany", ""}}}}}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$facet", Value: bson.M{
+			"byStatus": bson.A{
+				bson.M{"$group": bson.M{"_id": "$status", "count": bson.M{"$sum": 1}}},
+			},
+			"review": bson.A{
+				bson.M{"$match": bson.M{"needsManualReview": true}},
+				bson.M{"$count": "count"},
+			},
+			"matchTime": bson.A{
+				bson.M{"$match": bson.M{"matchedAt": bson.M{"$type": "date"}, "submittedAt": bson.M{"$type": "date"}}},
+				bson.M{"$group": bson.M{
+					"_id":   nil,
+					"count": bson.M{"$sum": 1},
+					"avgMs": bson.M{"$avg": bson.M{"$subtract": bson.A{"$matchedAt", "$submittedAt"}}},
+				}},
+			},
+			"byCompany": bson.A{
+				bson.M{"$group": bson.M{
+					"_id":               bson.M{"company": companyKey, "status": "$status"},
+					"name":              bson.M{"$first": "$targetCompany"},
+					"count":             bson.M{"$sum": 1},
+					"needsManualReview": bson.M{"$sum": bson.M{"$cond": bson.A{"$needsManualReview", 1, 0}}},
+				}},
+			},
+		}}},
+	}
+
+	cursor, err := c.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate referral stats: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var facets []struct {
+		ByStatus []struct {
+			Status *string `bson:"_id"`
+			Count  int64   `bson:"count"`
+		} `bson:"byStatus"`
+		Review []struct {
+			Count int64 `bson:"count"`
+		} `bson:"review"`
+		MatchTime []struct {
+			Count int64    `bson:"count"`
+			AvgMs *float64 `bson:"avgMs"`
+		} `bson:"matchTime"`
+		ByCompany []struct {
+			ID struct {
+				Company string  `bson:"company"`
+				Status  *string `bson:"status"`
+			} `bson:"_id"`
+			Name              string `bson:"name"`
+			Count             int64  `bson:"count"`
+			NeedsManualReview int64  `bson:"needsManualReview"`
+		} `bson:"byCompany"`
+	}
+	if err := cursor.All(ctx, &facets); err != nil {
+		return nil, fmt.Errorf("failed to decode referral stats: %w", err)
+	}
+
+	stats := &ReferralPipelineStats{
+		ByStatus:        make(map[string]int64),
+		ByTargetCompany: make([]ReferralCompanyStats, 0),
+	}
+	if len(facets) == 0 {
+		return stats, nil
+	}
+	f := facets[0]
+
+	for _, row := range f.ByStatus {
+		status := "unknown"
+		if row.Status != nil && *row.Status != "" {
+			status = *row.Status
+		}
+		stats.ByStatus[status] += row.Count
+		stats.Total += row.Count
+	}
+	if len(f.Review) > 0 {
+		stats.NeedsManualReview = f.Review[0].Count
+	}
+	if len(f.MatchTime) > 0 && f.MatchTime[0].AvgMs != nil {
+		stats.Matched = f.MatchTime[0].Count
+		hours := math.Round(*f.MatchTime[0].AvgMs/float64(time.Hour/time.Millisecond)*100) / 100
+		stats.AvgHoursToMatch = &hours
+	}
+
+	companies := make(map[string]*ReferralCompanyStats)
+	for _, row := range f.ByCompany {
+		company, ok := companies[row.ID.Company]
+		if !ok {
+			name := strings.TrimSpace(row.Name)
+			if row.ID.Company == "" {
+				name = "(unspecified)"
+			}
+			company = &ReferralCompanyStats{TargetCompany: name, ByStatus: make(map[string]int64)}
+			companies[row.ID.Company] = company
+		}
+		status := "unknown"
+		if row.ID.Status != nil && *row.ID.Status != "" {
+			status = *row.ID.Status
+		}
+		company.ByStatus[status] += row.Count
+		company.Total += row.Count
+		company.NeedsManualReview += row.NeedsManualReview
+	}
+	for _, company := range companies {
+		stats.ByTargetCompany = append(stats.ByTargetCompany, *company)
+	}
+	sort.Slice(stats.ByTargetCompany, func(i, j int) bool {
+		a, b := stats.ByTargetCompany[i], stats.ByTargetCompany[j]
+		if a.Total != b.Total {
+			return a.Total > b.Total
+		}
+		return a.TargetCompany < b.TargetCompany
+	})
+	if len(stats.ByTargetCompany) > maxReferralStatsCompanies {
+		stats.ByTargetCompany = stats.ByTargetCompany[:maxReferralStatsCompanies]
+	}
+	return stats, nil
+}
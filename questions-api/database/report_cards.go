@@ -3,7 +3,9 @@ package database
 import (
 	"context"
 	"errors"
+	"fmt"
 	"sort"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -30,6 +32,7 @@ type ReportCardEntry struct {
 	Source      map[string]interface{} `bson:"source,omitempty" json:"source,omitempty"`
 	Interpreted *InterpretedReportCard `bson:"interpreted,omitempty" json:"interpreted,omitempty"`
 	Revisions   []ReportCardRevision   `bson:"revisions,omitempty" json:"revisions,omitempty"`
+	SharedWith  []string               `bson:"sharedWith,omitempty" json:"sharedWith,omitempty"` // Recipient userIds / lowercase emails
 	CreatedAt   time.Time              `bson:"createdAt" json:"createdAt"`
 	UpdatedAt   time.Time              `bson:"updatedAt" json:"updatedAt"`
 }
@@ -64,8 +67,59 @@ type ReportCardEvidenceStats struct {
 	NarrativeFlagCount int     `bson:"narrativeFlagCount" json:"narrativeFlagCount"`
 }
 
+// NarrativeReliabilityUser is one user's most recent interpreted narrative reliability.
+type NarrativeReliabilityUser struct {
+	UserID               string    `bson:"_id" json:"userId"`
+	Email                string    `bson:"email" json:"email,omitempty"`
+	ReportID             string    `bson:"reportId" json:"reportId"`
+	NarrativeReliability string    `bson:"narrativeReliability" json:"narrativeReliability"`
+	NarrativeFlagCount   int       `bson:"narrativeFlagCount" json:"narrativeFlagCount"`
+	SessionCount         int       `bson:"sessionCount" json:"sessionCount"`
+	GeneratedAt          time.Time `bson:"generatedAt" json:"generatedAt"`
+}
+
+// NarrativeReliabilityOverview summarizes how often grader narratives diverge from evidence.
+type NarrativeReliabilityOverview struct {
+	TotalUsers int                        `json:"totalUsers"`
+	Counts     map[string]int             `json:"counts"` // high | medium | low
+	LowUsers   []NarrativeReliabilityUser `json:"lowUsers"`
+}
+
+// ReportCardUsageRow aggregates report-card generation volume for one model and creation path.
+type ReportCardUsageRow struct {
+	CreatedVia            string  `bson:"createdVia" json:"createdVia"` // llm | manual
+	Model                 string  `bson:"model" json:"model,omitempty"`
+	ReportCount           int     `bson:"reportCount" json:"reportCount"`
+	EstimatedPromptTokens int64   `bson:"estimatedPromptTokens" json:"estimatedPromptTokens"`
+	EstimatedOutputTokens int64   `bson:"estimatedOutputTokens" json:"estimatedOutputTokens"`
+	EstimatedCostUsd      float64 `bson:"estimatedCostUsd" json:"estimatedCostUsd"`
+}
+
+// OrphanedReportCard describes one inconsistent report entry found during cleanup.
+type OrphanedReportCard struct {
+	UserID   string `json:"userId"`
+	Email    string `json:"email,omitempty"`
+	ReportID string `json:"reportId"`
+	Reason   string `json:"reason"` // empty_paragraph | missing_created_via
+	Action   string `json:"action"` // archived | removed
+}
+
+// SharedReportCard is a report card another user explicitly shared with the viewer.
+// Revisions and the full recipient list are never included.
+type SharedReportCard struct {
+	OwnerUserID string          `bson:"ownerUserId" json:"ownerUserId"`
+	OwnerEmail  string          `bson:"ownerEmail" json:"ownerEmail,omitempty"`
+	Report      ReportCardEntry `bson:"report" json:"report"`
+}
+
+// MaxReportCardShares caps how many recipients one report can be shared with
+const MaxReportCardShares = 20
+
 var ErrReportNotFound = errors.New("report not found")
 
+// ErrTooManyShares is returned when sharing would exceed MaxReportCardShares
+var ErrTooManyShares = errors.New("report is shared with too many recipients")
+
 func GetReportCardsCollection() *mongo.Collection {
 	return GetAppDb().Collection("report_cards")
 }
@@ -81,6 +135,20 @@ func getReportCardsCollectionForUser(email string) *mongo.Collection {
 	return GetReportCardsCollection()
 }
 
+// GetUserReportCardsOrEmpty behaves like GetUserReportCards but returns an empty,
+// unsaved document for users who have never had a report appended.
+func GetUserReportCardsOrEmpty(ctx context.Context, userID, email string) (*UserReportCardsDocument, error) {
+	doc, err := GetUserReportCards(ctx, userID, email)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return &UserReportCardsDocument{
+			UserID:  userID,
+			Email:   email,
+			Reports: []ReportCardEntry{},
+		}, nil
+	}
+	return doc, err
+}
+
 func GetUserReportCards(ctx context.Context, userID, email string) (*UserReportCardsDocument, error) {
 	collection := getReportCardsCollectionForUser(email)
 	var doc UserReportCardsDocument
@@ -92,6 +160,8 @@ func GetUserReportCards(ctx context.Context, userID, email string) (*UserReportC
 	return &doc, nil
 }
 
+// AppendReportCard atomically upserts the user's report_cards document and pushes entry.
+// Concurrent first appends race on the unique userId index; the loser retries as an update.
 func AppendReportCard(ctx context.Context, userID, email string, entry ReportCardEntry) error {
 	collection := getReportCardsCollectionForUser(email)
 	now := time.Now()
@@ -105,12 +175,23 @@ func AppendReportCard(ctx context.Context, userID, email string, entry ReportCar
 	}
 
 	filter := bson.M{"userId": userID}
-	update := bson.M{
+	update := appendReportCardUpdate(userID, email, entry, now)
+
+	_, err := collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		_, err = collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	}
+	return err
+}
+
+// appendReportCardUpdate builds the upsert for AppendReportCard. MongoDB rejects an
+// update that touches the same path from two operators, so reports is left to $push
+// (which creates the array on insert) and email to $set.
+func appendReportCardUpdate(userID, email string, entry ReportCardEntry, now time.Time) bson.M {
+	return bson.M{
 		"$setOnInsert": bson.M{
 			"userId":    userID,
-			"email":     email,
 			"createdAt": now,
-			"reports":   []ReportCardEntry{},
 		},
 		"$set": bson.M{
 			"updatedAt": now,
@@ -120,9 +201,6 @@ func AppendReportCard(ctx context.Context, userID, email string, entry ReportCar
 			"reports": entry,
 		},
 	}
-
-	_, err := collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
-	return err
 }
 
 func ReviseReportCard(ctx context.Context, userID, email, reportID, newParagraph, reason string) (*ReportCardEntry, error) {
@@ -234,12 +312,317 @@ func SetReportStatus(ctx context.Context, userID, email, reportID, status string
 	return nil, ErrReportNotFound
 }
 
+// ArchiveReportCards archives every non-archived report of the user, or only those created
+// before `before` when it is set, in a single arrayFilters update. Returns how many were archived.
+func ArchiveReportCards(ctx context.Context, userID, email string, before *time.Time) (int, error) {
+	collection := getReportCardsCollectionForUser(email)
+	now := time.Now()
+
+	match := bson.M{"r.status": bson.M{"$ne": "archived"}}
+	if before != nil {
+		match["r.createdAt"] = bson.M{"$lt": *before}
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"reports.$[r].status":    "archived",
+			"reports.$[r].updatedAt": now,
+			"updatedAt":              now,
+		},
+	}
+	// The pre-image tells us which entries the filter matched, so the count is exact for this write
+	opts := options.FindOneAndUpdate().
+		SetArrayFilters(options.ArrayFilters{Filters: []interface{}{match}}).
+		SetReturnDocument(options.Before).
+		SetProjection(bson.M{"reports.status": 1, "reports.createdAt": 1})
+
+	var previous UserReportCardsDocument
+	err := collection.FindOneAndUpdate(ctx, bson.M{"userId": userID}, update, opts).Decode(&previous)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to archive report cards: %w", err)
+	}
+
+	archived := 0
+	for _, r := range previous.Reports {
+		if r.Status != "archived" && (before == nil || r.CreatedAt.Before(*before)) {
+			archived++
+		}
+	}
+	return archived, nil
+}
+
+// NormalizeShareRecipient trims a recipient identifier and lowercases emails
+func NormalizeShareRecipient(recipient string) string {
+	recipient = strings.TrimSpace(recipient)
+	if strings.Contains(recipient, "@") {
+		return strings.ToLower(recipient)
+	}
+	return recipient
+}
+
+// SetReportShared adds (share=true) or removes recipient from a report's sharedWith list
+func SetReportShared(ctx context.Context, userID, email, reportID, recipient string, share bool) (*ReportCardEntry, error) {
+	doc, err := GetUserReportCards(ctx, userID, email)
+	if err != nil {
+		return nil, err
+	}
+
+	recipient = NormalizeShareRecipient(recipient)
+	now := time.Now()
+	var updated *ReportCardEntry
+	for i := range doc.Reports {
+		if doc.Reports[i].ReportID != reportID {
+			continue
+		}
+		shared := make([]string, 0, len(doc.Reports[i].SharedWith)+1)
+		for _, existing := range doc.Reports[i].SharedWith {
+			if existing != recipient {
+				shared = append(shared, existing)
+			}
+		}
+		if share {
+			if len(shared) >= MaxReportCardShares {
+				return nil, ErrTooManyShares
+			}
+			shared = append(shared, recipient)
+		}
+		doc.Reports[i].SharedWith = shared
+		doc.Reports[i].UpdatedAt = now
+		updated = &doc.Reports[i]
+		break
+	}
+	if updated == nil {
+		return nil, ErrReportNotFound
+	}
+
+	doc.UpdatedAt = now
+	if err := replaceUserReportCards(ctx, email, doc); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// GetReportCardsSharedWith returns active report cards other users shared with any of
+// recipientIDs (the viewer's userId and email), newest first. Both the app and dev
+// collections are searched since internal users' cards live in the dev DB.
+func GetReportCardsSharedWith(ctx context.Context, recipientIDs []string) ([]SharedReportCard, error) {
+	if len(recipientIDs) == 0 {
+		return []SharedReportCard{}, nil
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"reports.sharedWith": bson.M{"$in": recipientIDs}}}},
+		{{Key: "$unwind", Value: "$reports"}},
+		// Only entries explicitly shared with the viewer, never archived ones
+		{{Key: "$match", Value: bson.M{
+			"reports.sharedWith": bson.M{"$in": recipientIDs},
+			"reports.status":     bson.M{"$ne": "archived"},
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"_id":         0,
+			"ownerUserId": "$userId",
+			"ownerEmail":  "$email",
+			"report":      "$reports",
+		}}},
+	}
+
+	collections := []*mongo.Collection{GetReportCardsCollection()}
+	if dev := GetDevReportCardsCollection(); dev.Database().Name() != collections[0].Database().Name() {
+		collections = append(collections, dev)
+	}
+
+	shared := make([]SharedReportCard, 0)
+	for _, coll := range collections {
+		cursor, err := coll.Aggregate(ctx, pipeline)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query shared report cards: %w", err)
+		}
+		var rows []SharedReportCard
+		err = cursor.All(ctx, &rows)
+		cursor.Close(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode shared report cards: %w", err)
+		}
+		shared = append(shared, rows...)
+	}
+
+	for i := range shared {
+		shared[i].Report.SharedWith = nil
+		shared[i].Report.Revisions = nil
+	}
+	sort.SliceStable(shared, func(i, j int) bool {
+		return shared[i].Report.CreatedAt.After(shared[j].Report.CreatedAt)
+	})
+	return shared, nil
+}
+
 func replaceUserReportCards(ctx context.Context, email string, doc *UserReportCardsDocument) error {
 	collection := getReportCardsCollectionForUser(email)
 	_, err := collection.ReplaceOne(ctx, bson.M{"userId": doc.UserID}, doc, options.Replace().SetUpsert(true))
 	return err
 }
 
+// GetNarrativeReliabilityOverview counts users by the narrative reliability of their
+// latest interpreted active report and lists the users rated "low".
+func GetNarrativeReliabilityOverview(ctx context.Context) (*NarrativeReliabilityOverview, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$unwind", Value: "$reports"}},
+		{{Key: "$match", Value: bson.M{
+			"reports.status": "active",
+			"reports.interpreted.narrativeReliability": bson.M{"$exists": true, "$ne": ""},
+		}}},
+		{{Key: "$sort", Value: bson.M{"reports.interpreted.generatedAt": -1}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":                  "$userId",
+			"email":                bson.M{"$first": "$email"},
+			"reportId":             bson.M{"$first": "$reports.reportId"},
+			"narrativeReliability": bson.M{"$first": "$reports.interpreted.narrativeReliability"},
+			"narrativeFlagCount":   bson.M{"$first": "$reports.interpreted.evidence.narrativeFlagCount"},
+			"sessionCount":         bson.M{"$first": "$reports.interpreted.evidence.sessionCount"},
+			"generatedAt":          bson.M{"$first": "$reports.interpreted.generatedAt"},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "narrativeFlagCount", Value: -1}, {Key: "generatedAt", Value: -1}}}},
+	}
+
+	cursor, err := GetReportCardsCollection().Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate narrative reliability: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var users []NarrativeReliabilityUser
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, fmt.Errorf("failed to decode narrative reliability: %w", err)
+	}
+
+	overview := &NarrativeReliabilityOverview{
+		TotalUsers: len(users),
+		Counts:     map[string]int{"high": 0, "medium": 0, "low": 0},
+		LowUsers:   []NarrativeReliabilityUser{},
+	}
+	for _, u := range users {
+		overview.Counts[u.NarrativeReliability]++
+		if u.NarrativeReliability == "low" {
+			overview.LowUsers = append(overview.LowUsers, u)
+		}
+	}
+	return overview, nil
+}
+
+// GetReportCardUsage groups reports created in [from, to) by createdVia and model,
+// summing the usage estimates recorded in each report's source at creation time.
+func GetReportCardUsage(ctx context.Context, from, to time.Time) ([]ReportCardUsageRow, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"reports.createdAt": bson.M{"$gte": from, "$lt": to}}}},
+		{{Key: "$unwind", Value: "$reports"}},
+		{{Key: "$match", Value: bson.M{"reports.createdAt": bson.M{"$gte": from, "$lt": to}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id": bson.M{
+				"createdVia": bson.M{"$ifNull": bson.A{"$reports.source.createdVia", "unknown"}},
+				"model":      bson.M{"$ifNull": bson.A{"$reports.source.model", ""}},
+			},
+			"reportCount":           bson.M{"$sum": 1},
+			"estimatedPromptTokens": bson.M{"$sum": bson.M{"$ifNull": bson.A{"$reports.source.estimatedPromptTokens", 0}}},
+			"estimatedOutputTokens": bson.M{"$sum": bson.M{"$ifNull": bson.A{"$reports.source.estimatedOutputTokens", 0}}},
+			"estimatedCostUsd":      bson.M{"$sum": bson.M{"$ifNull": bson.A{"$reports.source.estimatedCostUsd", 0}}},
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"_id":                   0,
+			"createdVia":            "$_id.createdVia",
+			"model":                 "$_id.model",
+			"reportCount":           1,
+			"estimatedPromptTokens": 1,
+			"estimatedOutputTokens": 1,
+			"estimatedCostUsd":      1,
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "createdVia", Value: 1}, {Key: "reportCount", Value: -1}}}},
+	}
+
+	cursor, err := GetReportCardsCollection().Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate report card usage: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	rows := []ReportCardUsageRow{}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, fmt.Errorf("failed to decode report card usage: %w", err)
+	}
+	return rows, nil
+}
+
+// CleanupOrphanedReportCards finds entries left behind by failed generation (empty
+// paragraph or no source.createdVia) across the app and dev report_cards collections
+// and archives or removes them. With dryRun, it only reports what would change.
+func CleanupOrphanedReportCards(ctx context.Context, remove, dryRun bool) ([]OrphanedReportCard, error) {
+	filter := bson.M{"reports": bson.M{"$elemMatch": bson.M{"$or": []bson.M{
+		{"paragraph": ""},
+		{"paragraph": bson.M{"$exists": false}},
+		{"source.createdVia": bson.M{"$exists": false}},
+	}}}}
+
+	action := "archived"
+	if remove {
+		action = "removed"
+	}
+
+	cleaned := []OrphanedReportCard{}
+	for _, coll := range []*mongo.Collection{GetReportCardsCollection(), GetDevReportCardsCollection()} {
+		cursor, err := coll.Find(ctx, filter)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan report cards: %w", err)
+		}
+		var docs []UserReportCardsDocument
+		if err := cursor.All(ctx, &docs); err != nil {
+			return nil, fmt.Errorf("failed to decode report cards: %w", err)
+		}
+
+		now := time.Now()
+		for _, doc := range docs {
+			kept := make([]ReportCardEntry, 0, len(doc.Reports))
+			changed := false
+			for _, r := range doc.Reports {
+				reason := ""
+				if r.Paragraph == "" {
+					reason = "empty_paragraph"
+				} else if _, ok := r.Source["createdVia"]; !ok {
+					reason = "missing_created_via"
+				}
+				if reason == "" || (!remove && r.Status == "archived") {
+					kept = append(kept, r)
+					continue
+				}
+
+				cleaned = append(cleaned, OrphanedReportCard{
+					UserID:   doc.UserID,
+					Email:    doc.Email,
+					ReportID: r.ReportID,
+					Reason:   reason,
+					Action:   action,
+				})
+				changed = true
+				if !remove {
+					r.Status = "archived"
+					r.UpdatedAt = now
+					kept = append(kept, r)
+				}
+			}
+
+			if !changed || dryRun {
+				continue
+			}
+			doc.Reports = kept
+			doc.UpdatedAt = now
+			if _, err := coll.ReplaceOne(ctx, bson.M{"_id": doc.ID}, doc); err != nil {
+				return cleaned, fmt.Errorf("failed to update report cards for %s: %w", doc.UserID, err)
+			}
+		}
+	}
+	return cleaned, nil
+}
+
 func sortReportsNewestFirst(reports []ReportCardEntry) {
 	sort.SliceStable(reports, func(i, j int) bool {
 		return reports[i].CreatedAt.After(reports[j].CreatedAt)
@@ -258,6 +641,9 @@ func CreateReportCardIndexes() {
 		{
 			Keys: bson.D{{Key: "reports.reportId", Value: 1}},
 		},
+		{
+			Keys: bson.D{{Key: "reports.sharedWith", Value: 1}},
+		},
 		{
 			Keys: bson.D{{Key: "updatedAt", Value: -1}},
 		},
@@ -0,0 +1,100 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestAppendReportCardUpdateHasNoConflictingPaths(t *testing.T) {
+	update := appendReportCardUpdate("user-1", "a@example.com", ReportCardEntry{ReportID: "r1"}, time.Now())
+
+	seen := map[string]string{}
+	for op, fields := range update {
+		for path := range fields.(bson.M) {
+			if prev, ok := seen[path]; ok {
+				t.Errorf("path %q is set by both %s and %s", path, prev, op)
+			}
+			seen[path] = op
+		}
+	}
+	if seen["reports"] != "$push" {
+		t.Errorf("reports should only be written by $push, got %q", seen["reports"])
+	}
+}
+
+// TestAppendReportCardConcurrentFirstAppend needs a real MongoDB; set MONGO_TEST_URI to run it.
+func TestAppendReportCardConcurrentFirstAppend(t *testing.T) {
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGO_TEST_URI not set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer client.Disconnect(context.Background())
+
+	prevClient, prevAppDB := MongoClient, activeAppDBName
+	MongoClient = client
+	activeAppDBName = fmt.Sprintf("report_cards_test_%d", time.Now().UnixNano())
+	defer func() {
+		_ = client.Database(activeAppDBName).Drop(context.Background())
+		MongoClient, activeAppDBName = prevClient, prevAppDB
+	}()
+
+	_, err = GetReportCardsCollection().Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "userId", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		t.Fatalf("create index: %v", err)
+	}
+
+	const writers = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- AppendReportCard(ctx, "user-1", "a@example.com", ReportCardEntry{ReportID: fmt.Sprintf("r%d", i)})
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("AppendReportCard: %v", err)
+		}
+	}
+
+	count, err := GetReportCardsCollection().CountDocuments(ctx, bson.M{"userId": "user-1"})
+	if err != nil {
+		t.Fatalf("count: %v", err)
+	}
+	if count != 1 {
+		t.Fatalf("expected 1 report_cards document, got %d", count)
+	}
+	doc, err := GetUserReportCards(ctx, "user-1", "a@example.com")
+	if err != nil {
+		t.Fatalf("GetUserReportCards: %v", err)
+	}
+	if len(doc.Reports) != writers {
+		t.Fatalf("expected %d reports, got %d", writers, len(doc.Reports))
+	}
+	if doc.CreatedAt.IsZero() {
+		t.Error("createdAt was not set on insert")
+	}
+}
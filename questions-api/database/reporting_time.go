@@ -0,0 +1,56 @@
+package database
+
+import (
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gerdinv/questions-api/config"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// DefaultReportingTimezone is used when REPORTING_TIMEZONE is unset or invalid
+const DefaultReportingTimezone = "UTC"
+
+var (
+	reportingLocationMutex sync.Mutex
+	reportingLocationName  string
+	reportingLocation      *time.Location
+)
+
+// ReportingLocation returns the timezone analytics use to decide which calendar day
+// an event falls on. Both Go-side bucketing and Mongo $dateToString go through it so
+// daily numbers line up. Re-reads config so a change applies without a restart.
+func ReportingLocation() *time.Location {
+	name := strings.TrimSpace(config.GetConfig().ReportingTimezone)
+	if name == "" {
+		name = DefaultReportingTimezone
+	}
+
+	reportingLocationMutex.Lock()
+	defer reportingLocationMutex.Unlock()
+
+	if reportingLocation != nil && reportingLocationName == name {
+		return reportingLocation
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		log.Printf("⚠️  Invalid REPORTING_TIMEZONE %q, using %s: %v", name, DefaultReportingTimezone, err)
+		loc = time.UTC
+	}
+	reportingLocationName = name
+	reportingLocation = loc
+	return loc
+}
+
+// reportingDayExpr builds a $dateToString expression that formats a date field
+// as YYYY-MM-DD in the reporting timezone.
+func reportingDayExpr(dateField string) bson.M {
+	return bson.M{"$dateToString": bson.M{
+		"format":   "%Y-%m-%d",
+		"date":     dateField,
+		"timezone": ReportingLocation().String(),
+	}}
+}
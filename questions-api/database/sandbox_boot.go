@@ -0,0 +1,113 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MaxSandboxBootSamples caps how many submissions a sandbox boot query loads
+const MaxSandboxBootSamples int64 = 50000
+
+// SandboxBootSample is the projection of a submission needed for boot-time analysis
+type SandboxBootSample struct {
+	SandboxBootMs  int64  `bson:"sandboxBootMs"`
+	PyodideVersion string `bson:"pyodideVersion"`
+	UserAgent      string `bson:"userAgent"`
+}
+
+// GetSandboxBootSamples returns submissions in [since, until) that recorded meta.sandboxBootMs.
+// Submissions lacking the field (or reporting 0) are skipped.
+func GetSandboxBootSamples(ctx context.Context, since, until time.Time, excludedSupabaseUserIDs []string) ([]SandboxBootSample, error) {
+	collection := GetAnalyticsBrowserSubmissionsCollection()
+
+	filter := ExcludeTestSubmissions(bson.M{
+		"meta.sandboxBootMs": bson.M{"$gt": 0},
+		"createdAt":          bson.M{"$gte": since, "$lt": until},
+	})
+	if len(excludedSupabaseUserIDs) > 0 {
+		filter["supabaseUserId"] = bson.M{"$nin": excludedSupabaseUserIDs}
+	}
+
+	opts := options.Find().
+		SetProjection(bson.M{
+			"_id":            0,
+			"sandboxBootMs":  "$meta.sandboxBootMs",
+			"pyodideVersion": "$meta.pyodideVersion",
+			"userAgent":      1,
+		}).
+		SetSort(bson.D{{Key: "createdAt", Value: -1}}).
+		SetLimit(MaxSandboxBootSamples)
+
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sandbox boot times: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	samples := []SandboxBootSample{}
+	if err := cursor.All(ctx, &samples); err != nil {
+		return nil, fmt.Errorf("failed to decode sandbox boot times: %w", err)
+	}
+	return samples, nil
+}
+
+// PyodideVersionUsage is one meta.pyodideVersion bucket of recent submissions.
+// Version is "" for submissions that did not report one.
+type PyodideVersionUsage struct {
+	Version     string    `bson:"_id" json:"version"`
+	Submissions int64     `bson:"submissions" json:"submissions"`
+	Users       int64     `bson:"users" json:"users"`
+	FirstSeenAt time.Time `bson:"firstSeenAt" json:"firstSeenAt"`
+	LastSeenAt  time.Time `bson:"lastSeenAt" json:"lastSeenAt"`
+}
+
+// GetPyodideVersionDistribution counts submissions and distinct users per
+// meta.pyodideVersion since the given time, most recently seen version first
+// (ties broken by submission count).
+func GetPyodideVersionDistribution(ctx context.Context, since time.Time, excludedSupabaseUserIDs []string) ([]PyodideVersionUsage, error) {
+	collection := GetAnalyticsBrowserSubmissionsCollection()
+
+	match := ExcludeTestSubmissions(bson.M{
+		"createdAt": bson.M{"$gte": since},
+	})
+	if len(excludedSupabaseUserIDs) > 0 {
+		match["supabaseUserId"] = bson.M{"$nin": excludedSupabaseUserIDs}
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: bson.M{
+			// Missing and null versions fall into the same "" bucket as empty strings
+			"_id":         bson.M{"$ifNull": bson.A{"$meta.pyodideVersion", ""}},
+			"submissions": bson.M{"$sum": 1},
+			// Legacy submissions without a Supabase UUID are keyed by userId
+			"userKeys":    bson.M{"$addToSet": bson.M{"$ifNull": bson.A{"$supabaseUserId", "$userId"}}},
+			"firstSeenAt": bson.M{"$min": "$createdAt"},
+			"lastSeenAt":  bson.M{"$max": "$createdAt"},
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"submissions": 1,
+			"users":       bson.M{"$size": "$userKeys"},
+			"firstSeenAt": 1,
+			"lastSeenAt":  1,
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "lastSeenAt", Value: -1}, {Key: "submissions", Value: -1}}}},
+	}
+
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate pyodide versions: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	versions := []PyodideVersionUsage{}
+	if err := cursor.All(ctx, &versions); err != nil {
+		return nil, fmt.Errorf("failed to decode pyodide versions: %w", err)
+	}
+	return versions, nil
+}
@@ -0,0 +1,46 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ProjectSubmissionStreamFilter narrows OpenProjectSubmissionsCursor
+type ProjectSubmissionStreamFilter struct {
+	Environment string // "" = all environments
+	Passed      *bool  // nil = passed and failed
+	IncludeCode bool   // include files and userTestsCode (large)
+
+	ExcludedSupabaseUserIDs []string
+}
+
+// OpenProjectSubmissionsCursor returns a cursor over every non-test submission for a
+// project (problemId), oldest first, on the analytics connection. The caller must
+// close the cursor; documents are meant to be decoded and written one at a time.
+func OpenProjectSubmissionsCursor(ctx context.Context, projectID string, f ProjectSubmissionStreamFilter) (*mongo.Cursor, error) {
+	filter := ExcludeTestSubmissions(bson.M{"problemId": projectID})
+	if f.Environment != "" {
+		filter["environment"] = f.Environment
+	}
+	if f.Passed != nil {
+		filter["passed"] = *f.Passed
+	}
+	if len(f.ExcludedSupabaseUserIDs) > 0 {
+		filter["supabaseUserId"] = bson.M{"$nin": f.ExcludedSupabaseUserIDs}
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}})
+	if !f.IncludeCode {
+		opts.SetProjection(bson.M{"files": 0, "userTestsCode": 0})
+	}
+
+	cursor, err := GetAnalyticsBrowserSubmissionsCollection().Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query project submissions: %w", err)
+	}
+	return cursor, nil
+}
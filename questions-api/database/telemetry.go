@@ -4,14 +4,42 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/gerdinv/questions-api/shared"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// Known runner_events event names. Queries must use these instead of string literals:
+// a misspelled event filter silently matches nothing.
+const (
+	EventProjectRunAttempt       = "project_run_attempt"
+	EventProjectSubmitAttempt    = "project_submit_attempt"
+	EventProjectSubmissionResult = "project_submission_result"
+	EventUserActivated           = "user_activated"
+	EventRunnerResult            = "runner_result"
+)
+
+// eventTypePattern is the shape of every runner_events name (project_run_attempt,
+// page_view, boss_fight_result, ...). Read filters check only the shape: the web app
+// emits more events than the constants above, and an unknown name simply matches nothing.
+var eventTypePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+
+// ValidateEventTypeFilter returns an error if eventType is set but is not a well-formed
+// event name. An empty eventType means "all events" and is always valid.
+func ValidateEventTypeFilter(eventType string) error {
+	if eventType == "" || eventTypePattern.MatchString(eventType) {
+		return nil
+	}
+	return fmt.Errorf("invalid telemetry event type %q", eventType)
+}
+
 // TelemetryCollection wraps MongoDB operations for telemetry/runner events
 type TelemetryCollection struct {
 	collection *mongo.Collection
@@ -29,19 +57,63 @@ func GetBrowserSubmissionsCollection() *mongo.Collection {
 	return GetAppDb().Collection("browser_submissions")
 }
 
-// GetEventsByUser retrieves telemetry events for a specific user
-func (tc *TelemetryCollection) GetEventsByUser(ctx context.Context, userID string, eventType string) ([]RunnerEventDocument, error) {
+// GetAnalyticsTelemetryCollection returns the runner_events collection on the analytics connection
+func GetAnalyticsTelemetryCollection() *TelemetryCollection {
+	return &TelemetryCollection{
+		collection: GetAnalyticsDb().Collection("runner_events"),
+	}
+}
+
+// GetDevTelemetryCollection returns runner_events from the dev DB, where internal users' events are routed
+func GetDevTelemetryCollection() *TelemetryCollection {
+	return &TelemetryCollection{
+		collection: GetDevDb().Collection("runner_events"),
+	}
+}
+
+// GetAnalyticsBrowserSubmissionsCollection returns browser_submissions on the analytics connection
+func GetAnalyticsBrowserSubmissionsCollection() *mongo.Collection {
+	return GetAnalyticsDb().Collection("browser_submissions")
+}
+
+// userEventsFilter matches a user's events by any of userIDs (Supabase UUID or the
+// legacy email key), optionally by event type
+func userEventsFilter(userIDs []string, eventType string) (bson.M, error) {
+	if err := ValidateEventTypeFilter(eventType); err != nil {
+		return nil, err
+	}
 	filter := bson.M{
 		"$or": []bson.M{
-			{"supabaseUserId": userID},
-			{"userId": userID},
+			{"supabaseUserId": bson.M{"$in": userIDs}},
+			{"userId": bson.M{"$in": userIDs}},
 		},
 	}
 	if eventType != "" {
 		filter["event"] = eventType
 	}
+	return filter, nil
+}
+
+// GetEventsByUser retrieves telemetry events for a specific user
+func (tc *TelemetryCollection) GetEventsByUser(ctx context.Context, userID string, eventType string) ([]RunnerEventDocument, error) {
+	return tc.GetEventsByUserPage(ctx, []string{userID}, eventType, 0, 0)
+}
+
+// GetEventsByUserPage retrieves one page of a user's telemetry events, newest first.
+// userIDs are the user's identity keys; limit <= 0 returns every event after skip.
+func (tc *TelemetryCollection) GetEventsByUserPage(ctx context.Context, userIDs []string, eventType string, skip int, limit int) ([]RunnerEventDocument, error) {
+	filter, err := userEventsFilter(userIDs, eventType)
+	if err != nil {
+		return nil, err
+	}
 
 	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}})
+	if skip > 0 {
+		opts.SetSkip(int64(skip))
+	}
+	if limit > 0 {
+		opts.SetLimit(int64(limit))
+	}
 	cursor, err := tc.collection.Find(ctx, filter, opts)
 	if err != nil {
 		return nil, err
@@ -55,18 +127,26 @@ func (tc *TelemetryCollection) GetEventsByUser(ctx context.Context, userID strin
 	return events, nil
 }
 
-// GetEventsByUserAndProject retrieves telemetry events for a user on a specific project
-func (tc *TelemetryCollection) GetEventsByUserAndProject(ctx context.Context, userID string, projectID string, eventType string) ([]RunnerEventDocument, error) {
-	filter := bson.M{
-		"$or": []bson.M{
-			{"supabaseUserId": userID},
-			{"userId": userID},
-		},
-		"properties.projectId": projectID,
+// CountEventsByUser counts a user's telemetry events across userIDs, optionally by event type
+func (tc *TelemetryCollection) CountEventsByUser(ctx context.Context, userIDs []string, eventType string) (int64, error) {
+	filter, err := userEventsFilter(userIDs, eventType)
+	if err != nil {
+		return 0, err
 	}
-	if eventType != "" {
-		filter["event"] = eventType
+	count, err := tc.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count user events: %w", err)
 	}
+	return count, nil
+}
+
+// GetEventsByUserAndProject retrieves telemetry events for a user (any of userIDs) on a specific project
+func (tc *TelemetryCollection) GetEventsByUserAndProject(ctx context.Context, userIDs []string, projectID string, eventType string) ([]RunnerEventDocument, error) {
+	filter, err := userEventsFilter(userIDs, eventType)
+	if err != nil {
+		return nil, err
+	}
+	filter["properties.projectId"] = projectID
 
 	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}}) // Ascending order for chronological processing
 	cursor, err := tc.collection.Find(ctx, filter, opts)
@@ -193,22 +273,28 @@ func GetSubmissionsByUser(ctx context.Context, userIdentifier string, sourceType
 	return submissions, nil
 }
 
-// GetUniqueProjectIDsByUser returns unique project IDs the user has submissions for
-// Matches on emailNormalized, email, or userId for backwards compatibility
-func GetUniqueProjectIDsByUser(ctx context.Context, userIdentifier string) ([]string, error) {
+// submissionIdentityFilter matches submissions owned by any of the user's identity keys
+// (Supabase UUID, email) on supabaseUserId, emailNormalized, email or userId
+func submissionIdentityFilter(userIdentifiers []string) bson.M {
+	or := make([]bson.M, 0, 4*len(userIdentifiers))
+	for _, id := range userIdentifiers {
+		or = append(or,
+			bson.M{"supabaseUserId": id},
+			bson.M{"emailNormalized": strings.ToLower(strings.TrimSpace(id))},
+			bson.M{"email": id},
+			bson.M{"userId": id},
+		)
+	}
+	return bson.M{"$or": or}
+}
+
+// GetUniqueProjectIDsByUser returns unique project IDs the user has submissions for,
+// matched on any of userIdentifiers (see submissionIdentityFilter)
+func GetUniqueProjectIDsByUser(ctx context.Context, userIdentifiers ...string) ([]string, error) {
 	collection := GetBrowserSubmissionsCollection()
 
-	normalizedIdentifier := strings.ToLower(strings.TrimSpace(userIdentifier))
-
-	filter := bson.M{
-		"$or": []bson.M{
-			{"supabaseUserId": userIdentifier},
-			{"emailNormalized": normalizedIdentifier},
-			{"email": userIdentifier},
-			{"userId": userIdentifier},
-		},
-		"sourceType": "project",
-	}
+	filter := submissionIdentityFilter(userIdentifiers)
+	filter["sourceType"] = "project"
 
 	projectIDs, err := collection.Distinct(ctx, "problemId", filter)
 	if err != nil {
@@ -226,34 +312,47 @@ func GetUniqueProjectIDsByUser(ctx context.Context, userIdentifier string) ([]st
 }
 
 // GetCompletedProjectIDsByUser returns project IDs where user has passed all tests
-// Matches on emailNormalized, email, or userId for backwards compatibility
-func GetCompletedProjectIDsByUser(ctx context.Context, userIdentifier string) ([]string, error) {
+// as many times as the project's requiredPasses (default 1), counting passes under
+// any of userIdentifiers (see submissionIdentityFilter)
+func GetCompletedProjectIDsByUser(ctx context.Context, userIdentifiers ...string) ([]string, error) {
 	collection := GetBrowserSubmissionsCollection()
 
-	normalizedIdentifier := strings.ToLower(strings.TrimSpace(userIdentifier))
+	required, err := GetRequiredPassesByProblemID(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-	filter := bson.M{
-		"$or": []bson.M{
-			{"supabaseUserId": userIdentifier},
-			{"emailNormalized": normalizedIdentifier},
-			{"email": userIdentifier},
-			{"userId": userIdentifier},
-		},
-		"sourceType": "project",
-		"passed":     true,
+	filter := submissionIdentityFilter(userIdentifiers)
+	filter["sourceType"] = "project"
+	filter["passed"] = true
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$group", Value: bson.M{
+			"_id":    "$problemId",
+			"passes": bson.M{"$sum": 1},
+		}}},
+		{{Key: "$match", Value: requiredPassesMatch("_id", required)}},
 	}
 
-	projectIDs, err := collection.Distinct(ctx, "problemId", filter)
+	cursor, err := collection.Aggregate(ctx, pipeline)
 	if err != nil {
 		return nil, err
 	}
+	defer cursor.Close(ctx)
 
-	// Convert []interface{} to []string
-	result := make([]string, 0, len(projectIDs))
-	for _, id := range projectIDs {
-		if strID, ok := id.(string); ok {
-			result = append(result, strID)
+	result := make([]string, 0)
+	for cursor.Next(ctx) {
+		var doc struct {
+			ProblemID string `bson:"_id"`
 		}
+		if err := cursor.Decode(&doc); err != nil {
+			continue
+		}
+		result = append(result, doc.ProblemID)
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
 	}
 	return result, nil
 }
@@ -306,15 +405,64 @@ func CountSubmissionsByUser(ctx context.Context, userID string, sourceType strin
 	return count, nil
 }
 
-// GetProjectTitle retrieves the title of a project by its projectNumber (as string)
+// GetProjectTitle retrieves the title of a project by its projectNumber (as string).
+// Legacy submissions stored the project's ObjectID hex as problemId, so non-numeric
+// IDs fall back to an _id lookup before giving up.
+// Titles are served from a bounded LRU shared across requests (see project_title_cache.go);
+// "Unknown Project" results are not cached.
 func GetProjectTitle(ctx context.Context, projectIDStr string) string {
-	// ProjectIDStr could be the projectNumber as string like "7"
-	// Try to parse it and get the project
-	project, err := ContentCollections.Projects.GetProjectByNumber(ctx, parseIntOrZero(projectIDStr))
-	if err != nil || project == nil {
+	projectIDStr = strings.TrimSpace(projectIDStr)
+	capacity := projectTitleCacheSize()
+	if capacity > 0 {
+		if title, ok := projectTitles.get(projectIDStr); ok {
+			return title
+		}
+	}
+
+	title, found := lookupProjectTitle(ctx, projectIDStr)
+	if !found {
 		return "Unknown Project"
 	}
-	return project.Title
+	projectTitles.put(projectIDStr, title, capacity)
+	return title
+}
+
+// Project lookups used by lookupProjectTitle; swapped out in tests
+var (
+	findProjectByNumber = func(ctx context.Context, projectNumber int) (*shared.ProjectDocument, error) {
+		return ContentCollections.Projects.GetProjectByNumber(ctx, projectNumber)
+	}
+	findProjectByObjectIDHex = getProjectByObjectIDHex
+)
+
+// lookupProjectTitle reads a project title from the content DB by number or legacy _id hex
+func lookupProjectTitle(ctx context.Context, projectIDStr string) (string, bool) {
+	if projectNumber, err := strconv.Atoi(projectIDStr); err == nil {
+		project, err := findProjectByNumber(ctx, projectNumber)
+		if err != nil || project == nil {
+			return "", false
+		}
+		return project.Title, true
+	}
+
+	project, err := findProjectByObjectIDHex(ctx, projectIDStr)
+	if err != nil || project == nil {
+		return "", false
+	}
+	return project.Title, true
+}
+
+// getProjectByObjectIDHex looks up a project in the content DB by its Mongo _id hex string
+func getProjectByObjectIDHex(ctx context.Context, hex string) (*shared.ProjectDocument, error) {
+	oid, err := primitive.ObjectIDFromHex(hex)
+	if err != nil {
+		return nil, err
+	}
+	var project shared.ProjectDocument
+	if err := GetContentDb().Collection("projects").FindOne(ctx, bson.M{"_id": oid}).Decode(&project); err != nil {
+		return nil, err
+	}
+	return &project, nil
 }
 
 // Helper function to parse string to int, returns 0 if invalid
@@ -350,9 +498,22 @@ func CreateTelemetryIndexes(ctx context.Context) error {
 		{
 			Keys: bson.D{{Key: "properties.projectId", Value: 1}, {Key: "supabaseUserId", Value: 1}},
 		},
+		{
+			// Numeric projectNumber derived from projectId (see DeriveProjectNumber)
+			Keys: bson.D{{Key: "event", Value: 1}, {Key: "properties.projectNumber", Value: 1}, {Key: "userId", Value: 1}},
+		},
 		{
 			Keys: bson.D{{Key: "environment", Value: 1}, {Key: "createdAt", Value: -1}},
 		},
+		{
+			Keys: bson.D{{Key: "tags." + EventTagFeature, Value: 1}, {Key: "userId", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "tags." + EventTagExperiment, Value: 1}, {Key: "tags." + EventTagVariant, Value: 1}, {Key: "userId", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "tags." + EventTagSource, Value: 1}, {Key: "userId", Value: 1}},
+		},
 		{
 			Keys: bson.D{{Key: "environment", Value: 1}, {Key: "supabaseUserId", Value: 1}, {Key: "createdAt", Value: -1}},
 		},
@@ -435,9 +596,113 @@ func (tc *TelemetryCollection) GetLatestTelemetryForUser(ctx context.Context, us
 	return &event, nil
 }
 
-// GetAllSubmissionsWithExecutionTime gets all submissions that have execution time data
-func GetAllSubmissionsWithExecutionTime(ctx context.Context) ([]BrowserSubmissionDocument, error) {
-	collection := GetBrowserSubmissionsCollection()
+// GetLastSeenByUserIDs returns userId -> most recent runner_events createdAt for the
+// given users, in a single aggregation. Legacy events stored createdAt as int64 Unix
+// milliseconds, so numbers are converted to a date before taking the max; any other
+// non-date value is ignored.
+// Users without any events are absent from the map.
+func GetLastSeenByUserIDs(ctx context.Context, userIDs []string) (map[string]time.Time, error) {
+	if len(userIDs) == 0 {
+		return make(map[string]time.Time), nil
+	}
+
+	collection := GetAnalyticsTelemetryCollection().collection
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"userId": bson.M{"$in": userIDs}}}},
+		// Normalize createdAt: Date stays as-is, legacy Unix milliseconds become a Date
+		{{Key: "$project", Value: bson.M{
+			"userId": 1,
+			"seenAt": bson.M{"$switch": bson.M{
+				"branches": bson.A{
+					bson.M{"case": bson.M{"$eq": bson.A{bson.M{"$type": "$createdAt"}, "date"}}, "then": "$createdAt"},
+					bson.M{"case": bson.M{"$isNumber": "$createdAt"}, "then": bson.M{"$toDate": "$createdAt"}},
+				},
+				"default": nil,
+			}},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":      "$userId",
+			"lastSeen": bson.M{"$max": "$seenAt"},
+		}}},
+	}
+
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("aggregation failed: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	result := make(map[string]time.Time)
+	for cursor.Next(ctx) {
+		var doc struct {
+			ID       string     `bson:"_id"`
+			LastSeen *time.Time `bson:"lastSeen"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("failed to decode aggregation result: %w", err)
+		}
+		if doc.LastSeen != nil {
+			result[doc.ID] = *doc.LastSeen
+		}
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("cursor error: %w", err)
+	}
+
+	return result, nil
+}
+
+// MaxExecutionTimeSubmissions caps how many submissions an execution-time query loads into memory
+const MaxExecutionTimeSubmissions int64 = 50000
+
+// ExecutionTimeFilter bounds execution-time queries by environment and createdAt range.
+// Zero values mean "no bound"; Limit <= 0 uses MaxExecutionTimeSubmissions.
+type ExecutionTimeFilter struct {
+	Environment        string
+	Since              *time.Time
+	Until              *time.Time
+	Limit              int64
+	ExcludedProblemIDs []string // e.g. tutorial/demo projects that skew timings
+}
+
+// apply adds the environment and date bounds to a browser_submissions filter.
+// Legacy documents without an environment field are kept; test submissions are dropped.
+func (f ExecutionTimeFilter) apply(filter bson.M) {
+	ExcludeTestSubmissions(filter)
+	ExcludeProblemIDs(filter, f.ExcludedProblemIDs)
+	if f.Environment != "" {
+		filter["environment"] = bson.M{"$in": bson.A{f.Environment, nil}}
+	}
+	if f.Since != nil || f.Until != nil {
+		createdAt := bson.M{}
+		if f.Since != nil {
+			createdAt["$gte"] = *f.Since
+		}
+		if f.Until != nil {
+			createdAt["$lt"] = *f.Until
+		}
+		filter["createdAt"] = createdAt
+	}
+}
+
+// findOptions projects only the fields needed for timing stats, newest first, capped.
+func (f ExecutionTimeFilter) findOptions() *options.FindOptions {
+	limit := f.Limit
+	if limit <= 0 || limit > MaxExecutionTimeSubmissions {
+		limit = MaxExecutionTimeSubmissions
+	}
+	return options.Find().
+		SetProjection(bson.M{"problemId": 1, "result.durationMs": 1, "result.ttfrMs": 1, "createdAt": 1}).
+		SetSort(bson.D{{Key: "createdAt", Value: -1}}).
+		SetLimit(limit)
+}
+
+// GetAllSubmissionsWithExecutionTime gets submissions that have execution time data,
+// bounded by the given environment/date filter and capped at MaxExecutionTimeSubmissions
+func GetAllSubmissionsWithExecutionTime(ctx context.Context, f ExecutionTimeFilter) ([]BrowserSubmissionDocument, error) {
+	collection := GetAnalyticsBrowserSubmissionsCollection()
 
 	filter := bson.M{
 		"$or": []bson.M{
@@ -445,8 +710,9 @@ func GetAllSubmissionsWithExecutionTime(ctx context.Context) ([]BrowserSubmissio
 			{"result.ttfrMs": bson.M{"$exists": true, "$gt": 0}},
 		},
 	}
+	f.apply(filter)
 
-	cursor, err := collection.Find(ctx, filter)
+	cursor, err := collection.Find(ctx, filter, f.findOptions())
 	if err != nil {
 		return nil, err
 	}
@@ -460,8 +726,8 @@ func GetAllSubmissionsWithExecutionTime(ctx context.Context) ([]BrowserSubmissio
 }
 
 // GetSubmissionsWithExecutionTimeByProject gets submissions with execution time for a specific project
-func GetSubmissionsWithExecutionTimeByProject(ctx context.Context, projectID string) ([]BrowserSubmissionDocument, error) {
-	collection := GetBrowserSubmissionsCollection()
+func GetSubmissionsWithExecutionTimeByProject(ctx context.Context, projectID string, f ExecutionTimeFilter) ([]BrowserSubmissionDocument, error) {
+	collection := GetAnalyticsBrowserSubmissionsCollection()
 
 	filter := bson.M{
 		"problemId": projectID,
@@ -470,8 +736,9 @@ func GetSubmissionsWithExecutionTimeByProject(ctx context.Context, projectID str
 			{"result.ttfrMs": bson.M{"$gt": 0}},
 		},
 	}
+	f.apply(filter)
 
-	cursor, err := collection.Find(ctx, filter)
+	cursor, err := collection.Find(ctx, filter, f.findOptions())
 	if err != nil {
 		return nil, err
 	}
@@ -517,11 +784,36 @@ func GetSubmissionsWithExecutionTimeByUserAndProject(ctx context.Context, userId
 
 // CountDistinctUsersWithSubmissions returns count of unique users who have submitted at least one project
 func CountDistinctUsersWithSubmissions(ctx context.Context, excludedSupabaseUserIDs []string) (int, error) {
-	collection := GetBrowserSubmissionsCollection()
+	return countDistinctSubmittersBySourceType(ctx, excludedSupabaseUserIDs, "project", false)
+}
 
-	filter := bson.M{
-		"sourceType": "project",
+// CountDistinctUsersWithCompletedProjects returns count of unique users who have passed at least one project
+func CountDistinctUsersWithCompletedProjects(ctx context.Context, excludedSupabaseUserIDs []string) (int, error) {
+	return countDistinctSubmittersBySourceType(ctx, excludedSupabaseUserIDs, "project", true)
+}
+
+// CountDistinctQuestionSubmitters returns count of unique users who submitted at least one
+// standalone question/problem (sourceType "code")
+func CountDistinctQuestionSubmitters(ctx context.Context, excludedSupabaseUserIDs []string) (int, error) {
+	return countDistinctSubmittersBySourceType(ctx, excludedSupabaseUserIDs, "code", false)
+}
+
+// CountDistinctQuestionSolvers returns count of unique users who passed at least one question
+func CountDistinctQuestionSolvers(ctx context.Context, excludedSupabaseUserIDs []string) (int, error) {
+	return countDistinctSubmittersBySourceType(ctx, excludedSupabaseUserIDs, "code", true)
+}
+
+// Helper: Count distinct users with submissions of a given sourceType ("project" or "code")
+// requirePassed: if true, only count passed submissions
+func countDistinctSubmittersBySourceType(ctx context.Context, excludedSupabaseUserIDs []string, sourceType string, requirePassed bool) (int, error) {
+	collection := GetAnalyticsBrowserSubmissionsCollection()
+
+	filter := ExcludeTestSubmissions(bson.M{
+		"sourceType": sourceType,
 		"userId":     bson.M{"$exists": true, "$ne": ""},
+	})
+	if requirePassed {
+		filter["passed"] = true
 	}
 
 	// Exclude if EITHER userId or supabaseUserId matches the excluded list
@@ -539,17 +831,15 @@ func CountDistinctUsersWithSubmissions(ctx context.Context, excludedSupabaseUser
 	return len(userIds), nil
 }
 
-// CountDistinctUsersWithCompletedProjects returns count of unique users who have passed at least one project
-func CountDistinctUsersWithCompletedProjects(ctx context.Context, excludedSupabaseUserIDs []string) (int, error) {
-	collection := GetBrowserSubmissionsCollection()
+// CountRetainedQuestionUsers returns count of question submitters who submitted
+// questions on more than 1 distinct day
+func CountRetainedQuestionUsers(ctx context.Context, excludedSupabaseUserIDs []string) (int, error) {
+	collection := GetAnalyticsBrowserSubmissionsCollection()
 
-	filter := bson.M{
-		"sourceType": "project",
-		"passed":     true,
+	filter := ExcludeTestSubmissions(bson.M{
+		"sourceType": "code",
 		"userId":     bson.M{"$exists": true, "$ne": ""},
-	}
-
-	// Exclude if EITHER userId or supabaseUserId matches the excluded list
+	})
 	if len(excludedSupabaseUserIDs) > 0 {
 		filter["$nor"] = []bson.M{
 			{"userId": bson.M{"$in": excludedSupabaseUserIDs}},
@@ -557,17 +847,40 @@ func CountDistinctUsersWithCompletedProjects(ctx context.Context, excludedSupaba
 		}
 	}
 
-	userIds, err := collection.Distinct(ctx, "userId", filter)
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$group", Value: bson.M{
+			"_id":          "$userId",
+			"distinctDays": bson.M{"$addToSet": reportingDayExpr("$createdAt")},
+		}}},
+		{{Key: "$match", Value: bson.M{
+			"distinctDays.1": bson.M{"$exists": true}, // More than 1 distinct day
+		}}},
+		{{Key: "$count", Value: "total"}},
+	}
+
+	cursor, err := collection.Aggregate(ctx, pipeline)
 	if err != nil {
 		return 0, err
 	}
-	return len(userIds), nil
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		Total int `bson:"total"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return 0, err
+	}
+	if len(results) == 0 {
+		return 0, nil
+	}
+	return results[0].Total, nil
 }
 
 // CountUsersWhoRanWarmup returns count of unique users who ran code on Project 0 (warmup)
 // Uses telemetry events: project_run_attempt where projectId equals "0" (projectNumber as string)
 func CountUsersWhoRanWarmup(ctx context.Context, excludedSupabaseUserIDs []string) (int, error) {
-	telemetryCol := GetTelemetryCollection()
+	telemetryCol := GetAnalyticsTelemetryCollection()
 
 	// IMPORTANT: projectId in telemetry is the projectNumber as a STRING (e.g., "0", "1", "7")
 	// Same pattern as problemId in submissions
@@ -575,7 +888,7 @@ func CountUsersWhoRanWarmup(ctx context.Context, excludedSupabaseUserIDs []strin
 
 	// Query telemetry by projectId string
 	filter := bson.M{
-		"event":                "project_run_attempt",
+		"event":                EventProjectRunAttempt,
 		"properties.projectId": "0", // Project 0 (warmup)
 		"userId":               bson.M{"$exists": true, "$ne": ""},
 	}
@@ -604,13 +917,22 @@ func CountUsersWhoSubmittedWarmup(ctx context.Context, excludedSupabaseUserIDs [
 // CountUsersWhoEnteredCurriculum returns count of unique users who ran code on any real project (projectNumber >= 1)
 // Uses telemetry events: project_run_attempt where projectId matches any real project
 func CountUsersWhoEnteredCurriculum(ctx context.Context, excludedSupabaseUserIDs []string) (int, error) {
-	telemetryCol := GetTelemetryCollection()
+	userIDs, err := getCurriculumRunnerUserIDs(ctx, excludedSupabaseUserIDs)
+	if err != nil {
+		return 0, err
+	}
+	return len(userIDs), nil
+}
+
+// Helper: Get list of user IDs who ran code on any real project (projectNumber >= 1)
+func getCurriculumRunnerUserIDs(ctx context.Context, excludedSupabaseUserIDs []string) ([]string, error) {
+	telemetryCol := GetAnalyticsTelemetryCollection()
 	projectsCol := GetContentDb().Collection("projects")
 
 	// First find all real project numbers (projectNumber >= 1)
 	cursor, err := projectsCol.Find(ctx, bson.M{"projectNumber": bson.M{"$gte": 1}})
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 	defer cursor.Close(ctx)
 
@@ -625,18 +947,18 @@ func CountUsersWhoEnteredCurriculum(ctx context.Context, excludedSupabaseUserIDs
 			continue
 		}
 		// Convert projectNumber to string to match projectId format in telemetry
-		projectIDs = append(projectIDs, fmt.Sprintf("%d", doc.ProjectNumber))
+		projectIDs = append(projectIDs, ProblemIDForProjectNumber(doc.ProjectNumber))
 	}
 
 	log.Printf("[DEBUG] CountUsersWhoEnteredCurriculum: Found %d project IDs: %v", len(projectIDs), projectIDs)
 
 	if len(projectIDs) == 0 {
-		return 0, nil
+		return nil, nil
 	}
 
 	// Query telemetry by projectId strings
 	filter := bson.M{
-		"event":                "project_run_attempt",
+		"event":                EventProjectRunAttempt,
 		"properties.projectId": bson.M{"$in": projectIDs},
 		"userId":               bson.M{"$exists": true, "$ne": ""},
 	}
@@ -647,9 +969,46 @@ func CountUsersWhoEnteredCurriculum(ctx context.Context, excludedSupabaseUserIDs
 
 	userIds, err := telemetryCol.collection.Distinct(ctx, "userId", filter)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
-	return len(userIds), nil
+
+	result := make([]string, 0, len(userIds))
+	for _, id := range userIds {
+		if str, ok := id.(string); ok && str != "" {
+			result = append(result, str)
+		}
+	}
+	return result, nil
+}
+
+// GetRanNeverSubmittedUserIDs returns users who ran code on a real project (projectNumber >= 1)
+// but never submitted one: the gap between EnteredCurriculum and Activated in the funnel.
+// Computed as curriculum runners minus real-project submitters, so it matches both counts.
+func GetRanNeverSubmittedUserIDs(ctx context.Context, excludedSupabaseUserIDs []string) ([]string, error) {
+	runnerIDs, err := getCurriculumRunnerUserIDs(ctx, excludedSupabaseUserIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get curriculum runners: %w", err)
+	}
+	if len(runnerIDs) == 0 {
+		return []string{}, nil
+	}
+
+	submitterIDs, err := getActivatedUserIDs(ctx, false, excludedSupabaseUserIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get project submitters: %w", err)
+	}
+	submitted := make(map[string]bool, len(submitterIDs))
+	for _, id := range submitterIDs {
+		submitted[id] = true
+	}
+
+	result := make([]string, 0, len(runnerIDs))
+	for _, id := range runnerIDs {
+		if !submitted[id] {
+			result = append(result, id)
+		}
+	}
+	return result, nil
 }
 
 // CountDistinctActivatedUsers returns count of unique users who submitted at least one REAL project (projectNumber >= 1)
@@ -664,13 +1023,19 @@ func CountDistinctCompletedRealProjects(ctx context.Context, excludedSupabaseUse
 }
 
 // CountRetainedActivatedUsers returns count of activated users who returned (>1 distinct session day)
-// An "activated" user is one who submitted a real project (projectNumber >= 1)
-// "Retained" means they have telemetry activity on more than 1 distinct calendar day
-func CountRetainedActivatedUsers(ctx context.Context, excludedSupabaseUserIDs []string) (int, error) {
-	collection := GetBrowserSubmissionsCollection()
-
-	// First, get all activated user IDs (users who submitted projectNumber >= 1)
-	activatedUserIDs, err := getActivatedUserIDs(ctx, excludedSupabaseUserIDs)
+// An "activated" user is one who meets the activation definition (see ActivationSubmitted etc.)
+// "Retained" means they have project submissions on more than 1 distinct calendar day
+func CountRetainedActivatedUsers(ctx context.Context, definition string, excludedSupabaseUserIDs []string) (int, error) {
+	collection := GetAnalyticsBrowserSubmissionsCollection()
+
+	// First, get all activated user IDs
+	var activatedUserIDs []string
+	var err error
+	if definition == ActivationRan {
+		activatedUserIDs, err = getCurriculumRunnerUserIDs(ctx, excludedSupabaseUserIDs)
+	} else {
+		activatedUserIDs, err = getActivatedUserIDs(ctx, definition == ActivationPassed, excludedSupabaseUserIDs)
+	}
 	if err != nil {
 		return 0, err
 	}
@@ -682,13 +1047,13 @@ func CountRetainedActivatedUsers(ctx context.Context, excludedSupabaseUserIDs []
 	// Now count how many of these users have submissions on >1 distinct day
 	// NOTE: Use userId (not supabaseUserId) since supabaseUserId is optional
 	pipeline := mongo.Pipeline{
-		{{Key: "$match", Value: bson.M{
+		{{Key: "$match", Value: ExcludeTestSubmissions(bson.M{
 			"sourceType": "project",
 			"userId":     bson.M{"$in": activatedUserIDs},
-		}}},
+		})}},
 		{{Key: "$project", Value: bson.M{
 			"userId": 1,
-			"dayStr": bson.M{"$dateToString": bson.M{"format": "%Y-%m-%d", "date": "$createdAt"}},
+			"dayStr": reportingDayExpr("$createdAt"),
 		}}},
 		{{Key: "$group", Value: bson.M{
 			"_id":          "$userId",
@@ -723,9 +1088,10 @@ func CountRetainedActivatedUsers(ctx context.Context, excludedSupabaseUserIDs []
 	return results[0].Total, nil
 }
 
-// Helper: Get list of activated user IDs (users who submitted projectNumber >= 1)
-func getActivatedUserIDs(ctx context.Context, excludedSupabaseUserIDs []string) ([]string, error) {
-	collection := GetBrowserSubmissionsCollection()
+// Helper: Get list of user IDs who submitted projectNumber >= 1
+// requirePassed: only users whose passing submissions meet a real project's requiredPasses
+func getActivatedUserIDs(ctx context.Context, requirePassed bool, excludedSupabaseUserIDs []string) ([]string, error) {
+	collection := GetAnalyticsBrowserSubmissionsCollection()
 	projectsCol := GetContentDb().Collection("projects")
 
 	// Get all projectNumbers where projectNumber >= 1
@@ -747,7 +1113,7 @@ func getActivatedUserIDs(ctx context.Context, excludedSupabaseUserIDs []string)
 			continue
 		}
 		// Convert projectNumber to string to match problemId format in submissions
-		problemIDs = append(problemIDs, fmt.Sprintf("%d", doc.ProjectNumber))
+		problemIDs = append(problemIDs, ProblemIDForProjectNumber(doc.ProjectNumber))
 	}
 
 	if len(problemIDs) == 0 {
@@ -756,10 +1122,13 @@ func getActivatedUserIDs(ctx context.Context, excludedSupabaseUserIDs []string)
 
 	// Now get distinct users from submissions matching these problem IDs
 	// NOTE: Use userId (not supabaseUserId) since supabaseUserId is optional
-	submissionFilter := bson.M{
+	submissionFilter := ExcludeTestSubmissions(bson.M{
 		"sourceType": "project",
 		"problemId":  bson.M{"$in": problemIDs},
 		"userId":     bson.M{"$exists": true, "$ne": ""},
+	})
+	if requirePassed {
+		submissionFilter["passed"] = true
 	}
 
 	// Exclude internal users - check both userId and supabaseUserId
@@ -771,6 +1140,12 @@ func getActivatedUserIDs(ctx context.Context, excludedSupabaseUserIDs []string)
 		}
 	}
 
+	// Same requiredPasses-aware set as CountDistinctCompletedRealProjects, so
+	// retention under ActivationPassed is measured over the activated users
+	if requirePassed {
+		return getUsersMeetingRequiredPasses(ctx, submissionFilter)
+	}
+
 	// Use userId for distinct count (always present)
 	userIds, err := collection.Distinct(ctx, "userId", submissionFilter)
 	if err != nil {
@@ -789,9 +1164,9 @@ func getActivatedUserIDs(ctx context.Context, excludedSupabaseUserIDs []string)
 
 // Helper: Count users with submissions by project number threshold
 // minProjectNumber: 0 for warmup, 1 for real projects
-// requirePassed: if true, only count passed submissions
+// requirePassed: if true, only count users whose passing submissions meet each project's requiredPasses
 func countUsersWithSubmissionsByProjectNumber(ctx context.Context, excludedSupabaseUserIDs []string, minProjectNumber int, requirePassed bool) (int, error) {
-	collection := GetBrowserSubmissionsCollection()
+	collection := GetAnalyticsBrowserSubmissionsCollection()
 	projectsCol := GetContentDb().Collection("projects")
 
 	log.Printf("[DEBUG] countUsersWithSubmissionsByProjectNumber: minProjectNumber=%d, requirePassed=%v", minProjectNumber, requirePassed)
@@ -823,7 +1198,7 @@ func countUsersWithSubmissionsByProjectNumber(ctx context.Context, excludedSupab
 			continue
 		}
 		// Convert projectNumber to string to match problemId format in submissions
-		problemIDs = append(problemIDs, fmt.Sprintf("%d", doc.ProjectNumber))
+		problemIDs = append(problemIDs, ProblemIDForProjectNumber(doc.ProjectNumber))
 	}
 
 	log.Printf("[DEBUG] countUsersWithSubmissionsByProjectNumber: Found %d problemIDs: %v", len(problemIDs), problemIDs)
@@ -835,11 +1210,11 @@ func countUsersWithSubmissionsByProjectNumber(ctx context.Context, excludedSupab
 
 	// Now count distinct users from submissions matching these problem IDs
 	// NOTE: Use userId (not supabaseUserId) since supabaseUserId is optional (omitempty)
-	submissionFilter := bson.M{
+	submissionFilter := ExcludeTestSubmissions(bson.M{
 		"sourceType": "project",
 		"problemId":  bson.M{"$in": problemIDs},
 		"userId":     bson.M{"$exists": true, "$ne": ""},
-	}
+	})
 
 	if requirePassed {
 		submissionFilter["passed"] = true
@@ -856,6 +1231,10 @@ func countUsersWithSubmissionsByProjectNumber(ctx context.Context, excludedSupab
 
 	log.Printf("[DEBUG] countUsersWithSubmissionsByProjectNumber: submissionFilter=%+v", submissionFilter)
 
+	if requirePassed {
+		return countUsersMeetingRequiredPasses(ctx, submissionFilter)
+	}
+
 	// Count distinct by userId (which is always present)
 	userIds, err := collection.Distinct(ctx, "userId", submissionFilter)
 	if err != nil {
@@ -868,6 +1247,96 @@ func countUsersWithSubmissionsByProjectNumber(ctx context.Context, excludedSupab
 	return len(userIds), nil
 }
 
+// countUsersMeetingRequiredPasses counts distinct users who, for at least one project,
+// have as many passing submissions (matching filter) as the project's requiredPasses
+func countUsersMeetingRequiredPasses(ctx context.Context, filter bson.M) (int, error) {
+	userIDs, err := getUsersMeetingRequiredPasses(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return len(userIDs), nil
+}
+
+// getUsersMeetingRequiredPasses returns the distinct userIds counted by countUsersMeetingRequiredPasses
+func getUsersMeetingRequiredPasses(ctx context.Context, filter bson.M) ([]string, error) {
+	collection := GetAnalyticsBrowserSubmissionsCollection()
+
+	required, err := GetRequiredPassesByProblemID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$group", Value: bson.M{
+			"_id":    bson.M{"userId": "$userId", "problemId": "$problemId"},
+			"passes": bson.M{"$sum": 1},
+		}}},
+		{{Key: "$match", Value: requiredPassesMatch("_id.problemId", required)}},
+		{{Key: "$group", Value: bson.M{"_id": "$_id.userId"}}},
+	}
+
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		UserID string `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+	userIDs := make([]string, 0, len(results))
+	for _, r := range results {
+		if r.UserID != "" {
+			userIDs = append(userIDs, r.UserID)
+		}
+	}
+	return userIDs, nil
+}
+
+// GetSubmissionCountsByLanguage returns language -> submission count across browser_submissions.
+// Submissions without a language are reported as "unknown".
+func GetSubmissionCountsByLanguage(ctx context.Context) (map[string]int, error) {
+	collection := GetAnalyticsBrowserSubmissionsCollection()
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: ExcludeTestSubmissions(bson.M{})}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   bson.M{"$toLower": bson.M{"$ifNull": bson.A{"$language", ""}}},
+			"count": bson.M{"$sum": 1},
+		}}},
+	}
+
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate submissions by language: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	counts := make(map[string]int)
+	for cursor.Next(ctx) {
+		var doc struct {
+			Language string `bson:"_id"`
+			Count    int    `bson:"count"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("failed to decode language count: %w", err)
+		}
+		language := doc.Language
+		if language == "" {
+			language = "unknown"
+		}
+		counts[language] += doc.Count
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("cursor error: %w", err)
+	}
+	return counts, nil
+}
+
 // GetAllTelemetryWithBrowserInfo gets all telemetry events that contain browser information
 func (tc *TelemetryCollection) GetAllTelemetryWithBrowserInfo(ctx context.Context) ([]RunnerEventDocument, error) {
 	filter := bson.M{
@@ -900,7 +1369,7 @@ func GetCompletedProjectCountsByUserIDs(ctx context.Context, userIDs []string) (
 		return make(map[string]int), nil
 	}
 
-	collection := GetBrowserSubmissionsCollection()
+	collection := GetAnalyticsBrowserSubmissionsCollection()
 
 	// MongoDB aggregation pipeline:
 	// Stage 1: Match submissions that are projects, passed, and belong to the given users
@@ -909,11 +1378,11 @@ func GetCompletedProjectCountsByUserIDs(ctx context.Context, userIDs []string) (
 	pipeline := mongo.Pipeline{
 		// Match: filter to relevant submissions
 		// Note: Supabase UUID is stored in "userId" field, not "supabaseUserId"
-		{{Key: "$match", Value: bson.M{
+		{{Key: "$match", Value: ExcludeTestSubmissions(bson.M{
 			"userId":     bson.M{"$in": userIDs},
 			"sourceType": "project",
 			"passed":     true,
-		}}},
+		})}},
 		// Group by user, collect unique project IDs
 		{{Key: "$group", Value: bson.M{
 			"_id":        "$userId",
@@ -957,22 +1426,22 @@ func GetCompletedProjectCountsByUserIDs(ctx context.Context, userIDs []string) (
 // This is used by /admin/roster to show user success rates.
 //
 // Aggregation logic:
-// 1. Match: filter to project submissions for the given user IDs
+// 1. Match: filter to project submissions for the given user IDs (minus excludedProblemIDs)
 // 2. Group by userId, count total submissions and passed submissions
 // 3. Calculate pass rate as (passed / total) * 100
-func GetPassRatesByUserIDs(ctx context.Context, userIDs []string) (map[string]int, error) {
+func GetPassRatesByUserIDs(ctx context.Context, userIDs []string, excludedProblemIDs []string) (map[string]int, error) {
 	if len(userIDs) == 0 {
 		return make(map[string]int), nil
 	}
 
-	collection := GetBrowserSubmissionsCollection()
+	collection := GetAnalyticsBrowserSubmissionsCollection()
 
 	pipeline := mongo.Pipeline{
 		// Match: filter to project submissions for these users
-		{{Key: "$match", Value: bson.M{
+		{{Key: "$match", Value: ExcludeProblemIDs(ExcludeTestSubmissions(bson.M{
 			"userId":     bson.M{"$in": userIDs},
 			"sourceType": "project",
-		}}},
+		}), excludedProblemIDs)}},
 		// Group by user, count total and passed
 		{{Key: "$group", Value: bson.M{
 			"_id":              "$userId",
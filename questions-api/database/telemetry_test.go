@@ -0,0 +1,128 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/gerdinv/questions-api/shared"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestLookupProjectTitle(t *testing.T) {
+	const oidHex = "64b7f0c2a1b2c3d4e5f60718"
+
+	prevByNumber, prevByHex := findProjectByNumber, findProjectByObjectIDHex
+	defer func() { findProjectByNumber, findProjectByObjectIDHex = prevByNumber, prevByHex }()
+
+	var lookedUp string
+	findProjectByNumber = func(ctx context.Context, projectNumber int) (*shared.ProjectDocument, error) {
+		lookedUp = "number"
+		if projectNumber == 7 {
+			return &shared.ProjectDocument{Title: "Todo API"}, nil
+		}
+		return nil, errors.New("not found")
+	}
+	findProjectByObjectIDHex = func(ctx context.Context, hex string) (*shared.ProjectDocument, error) {
+		lookedUp = "objectId"
+		if hex == oidHex {
+			return &shared.ProjectDocument{Title: "Legacy Project"}, nil
+		}
+		return nil, errors.New("not found")
+	}
+
+	tests := []struct {
+		name       string
+		id         string
+		wantTitle  string
+		wantFound  bool
+		wantLookup string
+	}{
+		{"numeric id", "7", "Todo API", true, "number"},
+		{"zero-padded numeric id", "007", "Todo API", true, "number"},
+		{"unknown numeric id", "99", "", false, "number"},
+		{"object id hex", oidHex, "Legacy Project", true, "objectId"},
+		{"unknown object id hex", "aaaaaaaaaaaaaaaaaaaaaaaa", "", false, "objectId"},
+		{"non-numeric non-hex id", "not-a-project", "", false, "objectId"},
+		{"empty id", "", "", false, "objectId"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lookedUp = ""
+			title, found := lookupProjectTitle(context.Background(), tt.id)
+			if title != tt.wantTitle || found != tt.wantFound {
+				t.Errorf("lookupProjectTitle(%q) = (%q, %v), want (%q, %v)", tt.id, title, found, tt.wantTitle, tt.wantFound)
+			}
+			if lookedUp != tt.wantLookup {
+				t.Errorf("lookupProjectTitle(%q) used %q lookup, want %q", tt.id, lookedUp, tt.wantLookup)
+			}
+		})
+	}
+}
+
+func TestValidateEventTypeFilter(t *testing.T) {
+	tests := []struct {
+		eventType string
+		wantErr   bool
+	}{
+		{"", false},
+		{EventProjectRunAttempt, false},
+		{"page_view", false},
+		{"boss_fight_added_to_tests", false},
+		{"onboarding_step_completed", false},
+		{"runner_total_latency", false},
+		{"Page_View", true},
+		{"page-view", true},
+		{"_page_view", true},
+		{"page_view\"", true},
+		{"$ne", true},
+		{strings.Repeat("a", 64), false},
+		{strings.Repeat("a", 65), true},
+	}
+	for _, tt := range tests {
+		if err := ValidateEventTypeFilter(tt.eventType); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateEventTypeFilter(%q) error = %v, wantErr %v", tt.eventType, err, tt.wantErr)
+		}
+	}
+}
+
+func TestSubmissionIdentityFilterMatchesEveryKey(t *testing.T) {
+	filter := submissionIdentityFilter([]string{"uuid-1", " Jane@Example.com "})
+	or, ok := filter["$or"].([]bson.M)
+	if !ok || len(or) != 8 {
+		t.Fatalf("submissionIdentityFilter $or = %#v, want 8 clauses", filter["$or"])
+	}
+	var sawUUID, sawEmail bool
+	for _, clause := range or {
+		if clause["supabaseUserId"] == "uuid-1" {
+			sawUUID = true
+		}
+		if clause["emailNormalized"] == "jane@example.com" {
+			sawEmail = true
+		}
+	}
+	if !sawUUID || !sawEmail {
+		t.Errorf("submissionIdentityFilter missing a key: uuid=%v email=%v", sawUUID, sawEmail)
+	}
+}
+
+func TestUserEventsFilterMatchesEveryKey(t *testing.T) {
+	keys := []string{"uuid-1", "jane@example.com"}
+	filter, err := userEventsFilter(keys, "project_run_attempt")
+	if err != nil {
+		t.Fatalf("userEventsFilter: %v", err)
+	}
+	if filter["event"] != "project_run_attempt" {
+		t.Errorf("event = %v, want project_run_attempt", filter["event"])
+	}
+	for _, clause := range filter["$or"].([]bson.M) {
+		for field, cond := range clause {
+			in, ok := cond.(bson.M)["$in"].([]string)
+			if !ok || len(in) != len(keys) {
+				t.Errorf("%s = %#v, want $in of every key", field, cond)
+			}
+		}
+	}
+}
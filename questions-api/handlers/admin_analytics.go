@@ -2,17 +2,22 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/gerdinv/questions-api/config"
 	"github.com/gerdinv/questions-api/database"
 	"github.com/gerdinv/questions-api/shared"
 	"github.com/labstack/echo/v4"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/sync/errgroup"
 )
 
 // GetUserDetailedMetrics handles GET /admin/users/:email/metrics (or :id)
@@ -25,6 +30,7 @@ func GetUserDetailedMetrics(c echo.Context) error {
 	// Check if it looks like an email or UUID
 	isEmail := strings.Contains(identifier, "@")
 	var user *shared.UserDocument
+	displayEmail := ""
 
 	// Basic validation
 	if isEmail {
@@ -41,11 +47,26 @@ func GetUserDetailedMetrics(c echo.Context) error {
 		if err == nil {
 			user = u
 		}
+
+		// Key all downstream queries on the Supabase UUID when the account exists
+		supabaseID, err := ResolveSupabaseUserID(ctx, identifier)
+		switch {
+		case err == nil:
+			displayEmail = identifier
+			identifier = supabaseID
+		case errors.Is(err, ErrSupabaseUserNotFound):
+			if user == nil {
+				return c.JSON(http.StatusNotFound, echo.Map{"error": "User not found"})
+			}
+			// Legacy Mongo-only account: keep matching by email
+		default:
+			c.Logger().Warnf("Failed to resolve Supabase ID for %s, falling back to email: %v", identifier, err)
+		}
 	}
 
-	// Build metrics using the identifier (Email or UUID)
-	// We pass 'user' if we found one (for legacy email/name).
-	metrics, err := buildUserMetrics(ctx, c, identifier, user)
+	// Build metrics using the identifier (UUID when resolved, otherwise Email or UUID as passed).
+	// We pass 'user' if we found one (for legacy email/name); the email keys legacy records.
+	metrics, err := buildUserMetrics(ctx, c, identifier, displayEmail, user)
 	if err != nil {
 		c.Logger().Errorf("Failed to build metrics for %s: %v", identifier, err)
 		return c.JSON(http.StatusInternalServerError, echo.Map{
@@ -57,18 +78,31 @@ func GetUserDetailedMetrics(c echo.Context) error {
 	return c.JSON(http.StatusOK, metrics)
 }
 
-// buildUserMetrics aggregates all user metrics
-func buildUserMetrics(ctx context.Context, c echo.Context, identifier string, user *shared.UserDocument) (*shared.UserDetailedMetrics, error) {
+// buildUserMetrics aggregates all user metrics.
+// displayEmail (optional) is shown instead of the identifier when no Mongo user exists.
+// Records are read under every identity key (UUID and email), since legacy ones are email-keyed.
+func buildUserMetrics(ctx context.Context, c echo.Context, identifier, displayEmail string, user *shared.UserDocument) (*shared.UserDetailedMetrics, error) {
+	userKeys := []string{identifier, displayEmail}
+	if user != nil {
+		userKeys = append(userKeys, user.SupabaseUserID, user.Email)
+	}
+	userKeys = uniqueUserKeys(userKeys)
+
 	// Fetch all projects and submissions
 	allProjects, err := database.ContentCollections.Projects.GetAllProjects(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch projects: %w", err)
 	}
 
-	submissions, err := database.GetSubmissionsByUser(ctx, identifier, "project", 0)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch submissions: %w", err)
+	submissionLists := make([][]database.BrowserSubmissionDocument, 0, len(userKeys))
+	for _, key := range userKeys {
+		list, err := database.GetSubmissionsByUser(ctx, key, "project", 0)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch submissions: %w", err)
+		}
+		submissionLists = append(submissionLists, list)
 	}
+	submissions := mergeSubmissionsByID(submissionLists...)
 
 	// Calculate project stats
 	projectStats := calculateProjectStats(ctx, identifier, allProjects, submissions)
@@ -77,7 +111,7 @@ func buildUserMetrics(ctx context.Context, c echo.Context, identifier string, us
 	recentSubmissions := buildRecentSubmissions(ctx, submissions, MaxRecentSubmissions)
 
 	// Calculate project attempts
-	projectAttempts, err := calculateProjectAttempts(ctx, c, identifier)
+	projectAttempts, err := calculateProjectAttempts(ctx, c, userKeys)
 	if err != nil {
 		c.Logger().Warnf("Failed to calculate project attempts for %s: %v", identifier, err)
 		projectAttempts = []shared.ProjectAttemptMetrics{}
@@ -89,11 +123,35 @@ func buildUserMetrics(ctx context.Context, c echo.Context, identifier string, us
 
 	email := identifier
 	name := identifier
+	if displayEmail != "" {
+		email = displayEmail
+		name = displayEmail
+	}
 	if user != nil {
 		email = user.Email
 		name = user.Name
 	}
 
+	// runner_events are keyed by Supabase UUID, legacy ones by email
+	var lastSeen *time.Time
+	if lastSeenByUser, err := database.GetLastSeenByUserIDs(ctx, userKeys); err != nil {
+		c.Logger().Warnf("Failed to get last seen for %s: %v", identifier, err)
+	} else {
+		for _, t := range lastSeenByUser {
+			if lastSeen == nil || t.After(*lastSeen) {
+				t := t
+				lastSeen = &t
+			}
+		}
+	}
+
+	// Overall ratio pools events across projects rather than averaging per-project ratios
+	totalRuns, totalSubmits := 0, 0
+	for _, attempt := range projectAttempts {
+		totalRuns += attempt.RunAttempts
+		totalSubmits += attempt.SubmitAttempts
+	}
+
 	// Build response
 	return &shared.UserDetailedMetrics{
 		Email:             email,
@@ -102,20 +160,42 @@ func buildUserMetrics(ctx context.Context, c echo.Context, identifier string, us
 		ProjectStats:      projectStats,
 		RecentSubmissions: recentSubmissions,
 		ProjectAttempts:   projectAttempts,
+		RunToSubmitRatio:  runToSubmitRatio(totalRuns, totalSubmits),
 		LastSeenBrowser:   browserInfo.Browser,
 		LastSeenOS:        browserInfo.OS,
 		LastSeenDevice:    browserInfo.Device,
+		LastSeen:          lastSeen,
 	}, nil
 }
 
-// calculateProjectAttempts builds attempt metrics for each project (extracted for clarity)
-func calculateProjectAttempts(ctx context.Context, c echo.Context, email string) ([]shared.ProjectAttemptMetrics, error) {
-	uniqueProjectIDs, err := database.GetUniqueProjectIDsByUser(ctx, email)
+// uniqueUserKeys drops empty and repeated identity keys, keeping their order
+func uniqueUserKeys(keys []string) []string {
+	seen := make(map[string]bool, len(keys))
+	unique := make([]string, 0, len(keys))
+	for _, key := range keys {
+		key = strings.TrimSpace(key)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		unique = append(unique, key)
+	}
+	return unique
+}
+
+// calculateProjectAttempts builds attempt metrics for each project (extracted for clarity).
+// userKeys are the user's identity keys, primary (Supabase UUID when known) first.
+func calculateProjectAttempts(ctx context.Context, c echo.Context, userKeys []string) ([]shared.ProjectAttemptMetrics, error) {
+	if len(userKeys) == 0 {
+		return []shared.ProjectAttemptMetrics{}, nil
+	}
+
+	uniqueProjectIDs, err := database.GetUniqueProjectIDsByUser(ctx, userKeys...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get unique project IDs: %w", err)
 	}
 
-	completedProjectIDs, err := database.GetCompletedProjectIDsByUser(ctx, email)
+	completedProjectIDs, err := database.GetCompletedProjectIDsByUser(ctx, userKeys...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get completed project IDs: %w", err)
 	}
@@ -130,19 +210,19 @@ func calculateProjectAttempts(ctx context.Context, c echo.Context, email string)
 
 	for _, projectID := range uniqueProjectIDs {
 		// Fetch telemetry events
-		runEvents, err := telemetryCol.GetEventsByUserAndProject(ctx, email, projectID, "project_run_attempt")
+		runEvents, err := telemetryCol.GetEventsByUserAndProject(ctx, userKeys, projectID, database.EventProjectRunAttempt)
 		if err != nil {
 			c.Logger().Warnf("Failed to get run events for project %s: %v", projectID, err)
 			runEvents = []database.RunnerEventDocument{}
 		}
 
-		submitEvents, err := telemetryCol.GetEventsByUserAndProject(ctx, email, projectID, "project_submit_attempt")
+		submitEvents, err := telemetryCol.GetEventsByUserAndProject(ctx, userKeys, projectID, database.EventProjectSubmitAttempt)
 		if err != nil {
 			c.Logger().Warnf("Failed to get submit events for project %s: %v", projectID, err)
 			submitEvents = []database.RunnerEventDocument{}
 		}
 
-		resultEvents, err := telemetryCol.GetEventsByUserAndProject(ctx, email, projectID, "project_submission_result")
+		resultEvents, err := telemetryCol.GetEventsByUserAndProject(ctx, userKeys, projectID, database.EventProjectSubmissionResult)
 		if err != nil {
 			c.Logger().Warnf("Failed to get result events for project %s: %v", projectID, err)
 			resultEvents = []database.RunnerEventDocument{}
@@ -151,7 +231,7 @@ func calculateProjectAttempts(ctx context.Context, c echo.Context, email string)
 		// Calculate metrics
 		attemptsBeforePass := countAttemptsBeforeSuccess(runEvents, submitEvents, resultEvents)
 		failedTests := aggregateFailedTests(resultEvents)
-		avgExecTime := calculateAvgExecutionTime(ctx, email, projectID)
+		avgExecTime := calculateAvgExecutionTime(ctx, userKeys[0], projectID)
 		projectTitle := database.GetProjectTitle(ctx, projectID)
 
 		projectAttempts = append(projectAttempts, shared.ProjectAttemptMetrics{
@@ -160,6 +240,7 @@ func calculateProjectAttempts(ctx context.Context, c echo.Context, email string)
 			AttemptsBeforePass: attemptsBeforePass,
 			RunAttempts:        len(runEvents),
 			SubmitAttempts:     len(submitEvents),
+			RunToSubmitRatio:   runToSubmitRatio(len(runEvents), len(submitEvents)),
 			Completed:          completedMap[projectID],
 			AvgExecutionTimeMs: avgExecTime,
 			FailedTests:        failedTests,
@@ -169,38 +250,156 @@ func calculateProjectAttempts(ctx context.Context, c echo.Context, email string)
 	return projectAttempts, nil
 }
 
+// runToSubmitRatio is runs per submit rounded to 2 decimals. High values mean the student
+// tests before submitting; nil when there are no submits, since the ratio is undefined.
+func runToSubmitRatio(runs, submits int) *float64 {
+	if submits == 0 {
+		return nil
+	}
+	ratio := math.Round(float64(runs)/float64(submits)*100) / 100
+	return &ratio
+}
+
+// Platform analytics sections that can be requested individually via ?sections=
+const (
+	AnalyticsSectionDAU       = "dau"       // DAU/WAU/MAU counts
+	AnalyticsSectionTrends    = "trends"    // 30-day DAU and 12-week WAU trends
+	AnalyticsSectionExecution = "execution" // Execution time metrics (expensive)
+	AnalyticsSectionBrowser   = "browser"   // Browser/OS/device/language breakdown (expensive)
+)
+
+// analyticsSections is the set of platform analytics sections to compute
+type analyticsSections map[string]bool
+
+// allAnalyticsSections computes everything (the behaviour without ?sections=)
+var allAnalyticsSections = analyticsSections{
+	AnalyticsSectionDAU:       true,
+	AnalyticsSectionTrends:    true,
+	AnalyticsSectionExecution: true,
+	AnalyticsSectionBrowser:   true,
+}
+
+// parseAnalyticsSections parses a comma-separated ?sections= value.
+// Empty means all sections. Unknown names (e.g. "funnel", which has its own
+// endpoint) are ignored, so a request naming only unknown sections computes none.
+func parseAnalyticsSections(raw string) analyticsSections {
+	if strings.TrimSpace(raw) == "" {
+		return allAnalyticsSections
+	}
+	sections := make(analyticsSections)
+	named := false
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		named = true
+		if allAnalyticsSections[name] {
+			sections[name] = true
+		}
+	}
+	if !named {
+		return allAnalyticsSections
+	}
+	return sections
+}
+
 // Helper function to calculate platform analytics
 func calculatePlatformAnalytics(ctx context.Context, excludedSupabaseUserIDs []string) (*shared.PlatformAnalytics, error) {
-	telemetryCol := database.GetTelemetryCollection()
+	return calculatePlatformAnalyticsSections(ctx, excludedSupabaseUserIDs, defaultExcludedProblemIDs(), allAnalyticsSections)
+}
+
+// defaultExcludedProblemIDs returns ANALYTICS_EXCLUDED_PROBLEM_IDS (empty = exclude nothing)
+func defaultExcludedProblemIDs() []string {
+	return database.ParseProblemIDList(config.GetConfig().AnalyticsExcludedProblemIds)
+}
+
+// resolveExcludedProblemIDs returns the problemIds to leave out of execution-time and
+// pass-rate aggregations: ?exclude_problem_ids= (comma-separated) when present, even
+// if empty, otherwise the configured default.
+func resolveExcludedProblemIDs(c echo.Context) []string {
+	if raw, ok := c.QueryParams()["exclude_problem_ids"]; ok {
+		return database.ParseProblemIDList(strings.Join(raw, ","))
+	}
+	return defaultExcludedProblemIDs()
+}
+
+// calculatePlatformAnalyticsSections computes only the requested sections; skipped
+// sections are left at their zero value (omitted from JSON where possible)
+func calculatePlatformAnalyticsSections(ctx context.Context, excludedSupabaseUserIDs, excludedProblemIDs []string, sections analyticsSections) (*shared.PlatformAnalytics, error) {
+	telemetryCol := database.GetAnalyticsTelemetryCollection()
 	now := time.Now()
+	thirtyDaysAgo := now.Add(-30 * 24 * time.Hour)
+	analytics := &shared.PlatformAnalytics{}
 
-	// DAU: Users active in last 24 hours
-	oneDayAgo := now.Add(-24 * time.Hour)
-	dau, err := telemetryCol.GetDistinctUsersSince(ctx, oneDayAgo, excludedSupabaseUserIDs)
-	if err != nil {
-		return nil, err
+	if sections[AnalyticsSectionDAU] {
+		var err error
+
+		// DAU: Users active in last 24 hours
+		oneDayAgo := now.Add(-24 * time.Hour)
+		analytics.DAU, err = telemetryCol.GetDistinctUsersSince(ctx, oneDayAgo, excludedSupabaseUserIDs)
+		if err != nil {
+			return nil, err
+		}
+
+		// WAU: Users active in last 7 days
+		sevenDaysAgo := now.Add(-7 * 24 * time.Hour)
+		analytics.WAU, err = telemetryCol.GetDistinctUsersSince(ctx, sevenDaysAgo, excludedSupabaseUserIDs)
+		if err != nil {
+			return nil, err
+		}
+
+		// MAU: Users active in last 30 days
+		analytics.MAU, err = telemetryCol.GetDistinctUsersSince(ctx, thirtyDaysAgo, excludedSupabaseUserIDs)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	// WAU: Users active in last 7 days
-	sevenDaysAgo := now.Add(-7 * 24 * time.Hour)
-	wau, err := telemetryCol.GetDistinctUsersSince(ctx, sevenDaysAgo, excludedSupabaseUserIDs)
-	if err != nil {
-		return nil, err
+	if sections[AnalyticsSectionTrends] {
+		analytics.DAUTrend, analytics.WAUTrend = calculateActivityTrends(ctx, telemetryCol, now, excludedSupabaseUserIDs)
 	}
 
-	// MAU: Users active in last 30 days
-	thirtyDaysAgo := now.Add(-30 * 24 * time.Hour)
-	mau, err := telemetryCol.GetDistinctUsersSince(ctx, thirtyDaysAgo, excludedSupabaseUserIDs)
-	if err != nil {
-		return nil, err
+	if sections[AnalyticsSectionExecution] {
+		// Calculate execution metrics (current environment, same 30-day window as MAU)
+		executionMetrics, err := calculateExecutionMetrics(ctx, database.ExecutionTimeFilter{
+			Environment:        resolveAppEnvironment(),
+			Since:              &thirtyDaysAgo,
+			ExcludedProblemIDs: excludedProblemIDs,
+		})
+		if err != nil {
+			// Use empty metrics on error
+			executionMetrics = newEmptyExecutionMetrics()
+		}
+		analytics.ExecutionMetrics = executionMetrics
 	}
 
+	if sections[AnalyticsSectionBrowser] {
+		// Calculate browser analytics
+		browserAnalytics, err := calculateBrowserAnalytics(ctx)
+		if err != nil {
+			// Use empty analytics on error
+			browserAnalytics = newEmptyBrowserAnalytics()
+		}
+		analytics.BrowserAnalytics = browserAnalytics
+	}
+
+	return analytics, nil
+}
+
+// calculateActivityTrends returns the 30-day DAU trend and 12-week WAU trend
+func calculateActivityTrends(ctx context.Context, telemetryCol *database.TelemetryCollection, now time.Time, excludedSupabaseUserIDs []string) ([]shared.TrendDataPoint, []shared.TrendDataPoint) {
+
+	// Trend buckets use the reporting timezone so days match the Mongo-side
+	// $dateToString buckets (e.g. retention)
+	localNow := now.In(database.ReportingLocation())
+
 	// DAU Trend: Daily counts for last 30 days
 	dauTrend := make([]shared.TrendDataPoint, 0, 30)
 	for i := 29; i >= 0; i-- {
-		date := now.Add(time.Duration(-i) * 24 * time.Hour)
+		date := localNow.AddDate(0, 0, -i)
 		startOfDay := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
-		endOfDay := startOfDay.Add(24 * time.Hour)
+		endOfDay := startOfDay.AddDate(0, 0, 1)
 
 		count, err := telemetryCol.GetDistinctUsersInRange(ctx, startOfDay, endOfDay, excludedSupabaseUserIDs)
 		if err != nil {
@@ -217,10 +416,10 @@ func calculatePlatformAnalytics(ctx context.Context, excludedSupabaseUserIDs []s
 	// WAU Trend: Weekly counts for last 12 weeks
 	wauTrend := make([]shared.TrendDataPoint, 0, 12)
 	for i := 11; i >= 0; i-- {
-		weekStart := now.Add(time.Duration(-i) * 7 * 24 * time.Hour)
+		weekStart := localNow.AddDate(0, 0, -7*i)
 		// Align to Monday
 		weekStart = getMonday(weekStart)
-		weekEnd := weekStart.Add(7 * 24 * time.Hour)
+		weekEnd := weekStart.AddDate(0, 0, 7)
 
 		count, err := telemetryCol.GetDistinctUsersInRange(ctx, weekStart, weekEnd, excludedSupabaseUserIDs)
 		if err != nil {
@@ -234,29 +433,61 @@ func calculatePlatformAnalytics(ctx context.Context, excludedSupabaseUserIDs []s
 		})
 	}
 
-	// Calculate execution metrics
-	executionMetrics, err := calculateExecutionMetrics(ctx)
-	if err != nil {
-		// Use empty metrics on error
-		executionMetrics = newEmptyExecutionMetrics()
+	return dauTrend, wauTrend
+}
+
+// GetPlatformAnalytics handles GET /admin/metrics/platform
+// Returns platform analytics limited to ?sections= (comma-separated: dau, trends,
+// execution, browser) so partial dashboards skip the expensive aggregations.
+// No sections param computes everything, like /admin/metrics.
+func GetPlatformAnalytics(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Request().Context(), DefaultQueryTimeout)
+	defer cancel()
+
+	sections := parseAnalyticsSections(c.QueryParam("sections"))
+
+	var err error
+	var excludedSupabaseUserIDs []string
+	if c.QueryParam("include_internal") != "true" {
+		excludedSupabaseUserIDs, err = GetInternalSupabaseIDs(ctx, []string{"linkedinorleftout.com"}, nil)
+		if err != nil {
+			c.Logger().Errorf("Failed to get internal user IDs: %v", err)
+		}
 	}
 
-	// Calculate browser analytics
-	browserAnalytics, err := calculateBrowserAnalytics(ctx)
+	excludedProblemIDs := resolveExcludedProblemIDs(c)
+	analytics, err := calculatePlatformAnalyticsSections(ctx, excludedSupabaseUserIDs, excludedProblemIDs, sections)
 	if err != nil {
-		// Use empty analytics on error
-		browserAnalytics = newEmptyBrowserAnalytics()
-	}
-
-	return &shared.PlatformAnalytics{
-		DAU:              dau,
-		WAU:              wau,
-		MAU:              mau,
-		DAUTrend:         dauTrend,
-		WAUTrend:         wauTrend,
-		ExecutionMetrics: executionMetrics,
-		BrowserAnalytics: browserAnalytics,
-	}, nil
+		c.Logger().Errorf("Failed to calculate platform analytics: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to calculate platform analytics",
+		})
+	}
+
+	names := make([]string, 0, len(sections))
+	for name := range sections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"platformAnalytics":  analytics,
+		"sections":           names,
+		"excludedProblemIds": excludedProblemIDs,
+	})
+}
+
+// resolveAppEnvironment returns the environment label stored on submissions and events
+// (APP_ENV, falling back to NODE_ENV production/development)
+func resolveAppEnvironment() string {
+	cfg := config.GetConfig()
+	if cfg.AppEnv != "" {
+		return cfg.AppEnv
+	}
+	if cfg.NodeEnv == "production" {
+		return "production"
+	}
+	return "development"
 }
 
 // getMonday returns the Monday of the week for the given date
@@ -264,9 +495,9 @@ func getMonday(t time.Time) time.Time {
 	// Get to the start of the day
 	t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
 
-	// Calculate days to subtract to get to Monday
+	// Calculate days to subtract to get to Monday (AddDate keeps midnight across DST changes)
 	daysToMonday := (int(t.Weekday()) - int(time.Monday) + 7) % 7
-	return t.Add(time.Duration(-daysToMonday) * 24 * time.Hour)
+	return t.AddDate(0, 0, -daysToMonday)
 }
 
 // CreateAnalyticsIndexes handles POST /admin/indexes/create
@@ -308,10 +539,13 @@ func CreateAnalyticsIndexes(c echo.Context) error {
 	})
 }
 
+// executionMetricsConcurrency bounds concurrent per-project execution time queries
+const executionMetricsConcurrency = 8
+
 // calculateExecutionMetrics aggregates execution time data
-func calculateExecutionMetrics(ctx context.Context) (*shared.ExecutionMetrics, error) {
-	// Get all submissions with execution time
-	submissions, err := database.GetAllSubmissionsWithExecutionTime(ctx)
+func calculateExecutionMetrics(ctx context.Context, filter database.ExecutionTimeFilter) (*shared.ExecutionMetrics, error) {
+	// Get submissions with execution time within the filter bounds
+	submissions, err := database.GetAllSubmissionsWithExecutionTime(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
@@ -344,15 +578,28 @@ func calculateExecutionMetrics(ctx context.Context) (*shared.ExecutionMetrics, e
 		return nil, err
 	}
 
-	executionsByProject := make([]shared.ProjectExecution, 0)
-	for _, project := range allProjects {
+	excluded := make(map[string]bool, len(filter.ExcludedProblemIDs))
+	for _, id := range filter.ExcludedProblemIDs {
+		excluded[id] = true
+	}
+
+	// One query per project, run concurrently. Each worker writes only its own slot;
+	// projects that error, are excluded or have no timed submissions leave it nil and are skipped.
+	projectResults := make([]*shared.ProjectExecution, len(allProjects))
+	var g errgroup.Group
+	g.SetLimit(executionMetricsConcurrency)
+	for i, project := range allProjects {
+		i, project := i, project
 		projectID := fmt.Sprintf("%d", project.ProjectNumber)
-		projectSubs, err := database.GetSubmissionsWithExecutionTimeByProject(ctx, projectID)
-		if err != nil {
+		if excluded[projectID] {
 			continue
 		}
+		g.Go(func() error {
+			projectSubs, err := database.GetSubmissionsWithExecutionTimeByProject(ctx, projectID, filter)
+			if err != nil || len(projectSubs) == 0 {
+				return nil
+			}
 
-		if len(projectSubs) > 0 {
 			projectTimes := make([]int64, 0, len(projectSubs))
 			projectTTFRTimes := make([]int64, 0, len(projectSubs))
 			for _, sub := range projectSubs {
@@ -365,14 +612,24 @@ func calculateExecutionMetrics(ctx context.Context) (*shared.ExecutionMetrics, e
 			}
 
 			if len(projectTimes) > 0 {
-				executionsByProject = append(executionsByProject, shared.ProjectExecution{
+				projectResults[i] = &shared.ProjectExecution{
 					ProjectID:      projectID,
 					ProjectTitle:   project.Title,
 					AvgTimeMs:      calculateAverage(projectTimes),
 					AvgTTFRMs:      calculateAverage(projectTTFRTimes),
 					ExecutionCount: len(projectSubs),
-				})
+				}
 			}
+			return nil
+		})
+	}
+	_ = g.Wait() // workers never return errors
+
+	// Assemble in catalog order so the sort below sees the same input as before
+	executionsByProject := make([]shared.ProjectExecution, 0, len(allProjects))
+	for _, result := range projectResults {
+		if result != nil {
+			executionsByProject = append(executionsByProject, *result)
 		}
 	}
 
@@ -392,9 +649,17 @@ func calculateExecutionMetrics(ctx context.Context) (*shared.ExecutionMetrics, e
 	}, nil
 }
 
-// calculateBrowserAnalytics aggregates browser/device usage data
+// calculateBrowserAnalytics aggregates browser/device usage data from telemetry
+// and the language breakdown from browser_submissions
 func calculateBrowserAnalytics(ctx context.Context) (*shared.BrowserAnalytics, error) {
-	telemetryCol := database.GetTelemetryCollection()
+	telemetryCol := database.GetAnalyticsTelemetryCollection()
+
+	// Languages come from submissions, independent of whether telemetry has browser info
+	languageCounts, err := database.GetSubmissionCountsByLanguage(ctx)
+	if err != nil {
+		return nil, err
+	}
+	languageBreakdown := calculateLanguageBreakdown(languageCounts)
 
 	// Get all telemetry events with browser info
 	telemetry, err := telemetryCol.GetAllTelemetryWithBrowserInfo(ctx)
@@ -403,7 +668,9 @@ func calculateBrowserAnalytics(ctx context.Context) (*shared.BrowserAnalytics, e
 	}
 
 	if len(telemetry) == 0 {
-		return newEmptyBrowserAnalytics(), nil
+		empty := newEmptyBrowserAnalytics()
+		empty.LanguageBreakdown = languageBreakdown
+		return empty, nil
 	}
 
 	// Count by browser, OS, and device
@@ -465,12 +732,37 @@ func calculateBrowserAnalytics(ctx context.Context) (*shared.BrowserAnalytics, e
 	})
 
 	return &shared.BrowserAnalytics{
-		BrowserBreakdown: browserBreakdown,
-		OSBreakdown:      osBreakdown,
-		DeviceBreakdown:  deviceBreakdown,
+		BrowserBreakdown:  browserBreakdown,
+		OSBreakdown:       osBreakdown,
+		DeviceBreakdown:   deviceBreakdown,
+		LanguageBreakdown: languageBreakdown,
 	}, nil
 }
 
+// calculateLanguageBreakdown converts language counts into a sorted breakdown with percentages
+func calculateLanguageBreakdown(counts map[string]int) []shared.LanguageStat {
+	total := 0
+	for _, count := range counts {
+		total += count
+	}
+
+	breakdown := make([]shared.LanguageStat, 0, len(counts))
+	if total == 0 {
+		return breakdown
+	}
+	for language, count := range counts {
+		breakdown = append(breakdown, shared.LanguageStat{
+			Language:   language,
+			Count:      count,
+			Percentage: (float64(count) / float64(total)) * 100,
+		})
+	}
+	sort.Slice(breakdown, func(i, j int) bool {
+		return breakdown[i].Count > breakdown[j].Count
+	})
+	return breakdown
+}
+
 // Helper functions for statistics
 
 func calculateAverage(times []int64) int64 {
@@ -526,6 +818,455 @@ func calculateMax(times []int64) int64 {
 	return max
 }
 
+// calculatePercentile returns the nearest-rank percentile (0-100) of times
+func calculatePercentile(times []int64, percentile float64) int64 {
+	if len(times) == 0 {
+		return 0
+	}
+	sorted := make([]int64, len(times))
+	copy(sorted, times)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i] < sorted[j]
+	})
+	rank := int(math.Ceil(percentile/100*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+	return sorted[rank]
+}
+
+// SandboxBootStats summarizes sandbox boot times for one group of submissions
+type SandboxBootStats struct {
+	Key      string `json:"key,omitempty"`
+	Count    int    `json:"count"`
+	AvgMs    int64  `json:"avgMs"`
+	MedianMs int64  `json:"medianMs"`
+	P95Ms    int64  `json:"p95Ms"`
+}
+
+func newSandboxBootStats(key string, times []int64) SandboxBootStats {
+	return SandboxBootStats{
+		Key:      key,
+		Count:    len(times),
+		AvgMs:    calculateAverage(times),
+		MedianMs: calculateMedian(times),
+		P95Ms:    calculatePercentile(times, 95),
+	}
+}
+
+// parseBrowser parses the User-Agent string to return a readable browser name
+func parseBrowser(ua string) string {
+	if ua == "" {
+		return "Unknown"
+	}
+	uaLower := strings.ToLower(ua)
+	// Order matters: Edge and Opera UAs also contain "chrome", Chrome UAs contain "safari"
+	if strings.Contains(uaLower, "edg/") || strings.Contains(uaLower, "edge/") {
+		return "Edge"
+	} else if strings.Contains(uaLower, "opr/") || strings.Contains(uaLower, "opera") {
+		return "Opera"
+	} else if strings.Contains(uaLower, "firefox") || strings.Contains(uaLower, "fxios") {
+		return "Firefox"
+	} else if strings.Contains(uaLower, "chrome") || strings.Contains(uaLower, "crios") {
+		return "Chrome"
+	} else if strings.Contains(uaLower, "safari") {
+		return "Safari"
+	}
+	return "Other"
+}
+
+// GetSandboxBootMetrics handles GET /admin/metrics/sandbox-boot
+// Returns avg/median/p95 of meta.sandboxBootMs over a date range
+// Query params:
+//   - from, to: RFC3339 or YYYY-MM-DD (default: last 30 days)
+//   - groupBy: browser | pyodideVersion (optional breakdown)
+//   - include_internal: include internal users (default false)
+func GetSandboxBootMetrics(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Request().Context(), DefaultQueryTimeout)
+	defer cancel()
+
+	to := time.Now()
+	if t, err := parseTimeQueryParam(c, "to"); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": err.Error()})
+	} else if t != nil {
+		to = *t
+	}
+	from := to.AddDate(0, 0, -30)
+	if t, err := parseTimeQueryParam(c, "from"); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": err.Error()})
+	} else if t != nil {
+		from = *t
+	}
+	if !from.Before(to) {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "from must be before to"})
+	}
+
+	groupBy := c.QueryParam("groupBy")
+	if groupBy != "" && groupBy != "browser" && groupBy != "pyodideVersion" {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "groupBy must be browser or pyodideVersion"})
+	}
+
+	// Exclude internal users unless requested
+	var excludedSupabaseUserIDs []string
+	if c.QueryParam("include_internal") != "true" {
+		var err error
+		excludedSupabaseUserIDs, err = GetInternalSupabaseIDs(ctx, []string{"linkedinorleftout.com"}, nil)
+		if err != nil {
+			c.Logger().Errorf("Failed to get internal user IDs: %v", err)
+		}
+	}
+
+	samples, err := database.GetSandboxBootSamples(ctx, from, to, excludedSupabaseUserIDs)
+	if err != nil {
+		c.Logger().Errorf("Failed to fetch sandbox boot samples: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Failed to fetch sandbox boot metrics"})
+	}
+
+	all := make([]int64, 0, len(samples))
+	groups := make(map[string][]int64)
+	for _, sample := range samples {
+		all = append(all, sample.SandboxBootMs)
+		switch groupBy {
+		case "browser":
+			key := parseBrowser(sample.UserAgent)
+			groups[key] = append(groups[key], sample.SandboxBootMs)
+		case "pyodideVersion":
+			key := sample.PyodideVersion
+			if key == "" {
+				key = "unknown"
+			}
+			groups[key] = append(groups[key], sample.SandboxBootMs)
+		}
+	}
+
+	breakdown := make([]SandboxBootStats, 0, len(groups))
+	for key, times := range groups {
+		breakdown = append(breakdown, newSandboxBootStats(key, times))
+	}
+	sort.Slice(breakdown, func(i, j int) bool {
+		return breakdown[i].Count > breakdown[j].Count
+	})
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"from":      from,
+		"to":        to,
+		"overall":   newSandboxBootStats("", all),
+		"groupBy":   groupBy,
+		"breakdown": breakdown,
+	})
+}
+
+const (
+	defaultPyodideVersionDays = 30
+	maxPyodideVersionDays     = 365
+)
+
+// GetPyodideVersionMetrics handles GET /admin/metrics/pyodide-versions
+// Returns submissions and distinct users per meta.pyodideVersion, to confirm a
+// runner upgrade is reaching users. Submissions without a version are reported as "unknown".
+// Query params:
+//   - days: window size (default 30, max 365)
+//   - include_internal: include internal users (default false)
+func GetPyodideVersionMetrics(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Request().Context(), DefaultQueryTimeout)
+	defer cancel()
+
+	days, err := parseDaysParam(c, defaultPyodideVersionDays, maxPyodideVersionDays)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": err.Error()})
+	}
+	since := time.Now().AddDate(0, 0, -days)
+
+	// Exclude internal users unless requested
+	var excludedSupabaseUserIDs []string
+	if c.QueryParam("include_internal") != "true" {
+		excludedSupabaseUserIDs, err = GetInternalSupabaseIDs(ctx, []string{"linkedinorleftout.com"}, nil)
+		if err != nil {
+			c.Logger().Errorf("Failed to get internal user IDs: %v", err)
+		}
+	}
+
+	versions, err := database.GetPyodideVersionDistribution(ctx, since, excludedSupabaseUserIDs)
+	if err != nil {
+		c.Logger().Errorf("Failed to fetch pyodide version distribution: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Failed to fetch pyodide version metrics"})
+	}
+
+	var totalSubmissions int64
+	for i := range versions {
+		if versions[i].Version == "" {
+			versions[i].Version = "unknown"
+		}
+		totalSubmissions += versions[i].Submissions
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"days":             days,
+		"since":            since,
+		"totalSubmissions": totalSubmissions,
+		"versions":         versions,
+	})
+}
+
+// parseDaysParam reads ?days= as a positive window size, defaulting to def and capped at max
+func parseDaysParam(c echo.Context, def, max int) (int, error) {
+	raw := c.QueryParam("days")
+	if raw == "" {
+		return def, nil
+	}
+	days, err := strconv.Atoi(raw)
+	if err != nil || days < 1 {
+		return 0, fmt.Errorf("days must be a positive integer")
+	}
+	if days > max {
+		days = max
+	}
+	return days, nil
+}
+
+const (
+	defaultActivityHeatmapDays = 28
+	maxActivityHeatmapDays     = 365
+)
+
+// GetActivityHeatmap handles GET /admin/metrics/activity-heatmap
+// Returns a 7x24 grid (Mon-Sun x hour 0-23) of submission counts for scheduling
+// office hours and TA coverage.
+// Query params:
+//   - days: window size (default 28 = four full weeks, max 365)
+//   - tz: IANA timezone for the buckets (default REPORTING_TIMEZONE)
+//   - include_internal: include internal users (default false)
+func GetActivityHeatmap(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Request().Context(), DefaultQueryTimeout)
+	defer cancel()
+
+	days, err := parseDaysParam(c, defaultActivityHeatmapDays, maxActivityHeatmapDays)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": err.Error()})
+	}
+
+	loc := database.ReportingLocation()
+	if tz := strings.TrimSpace(c.QueryParam("tz")); tz != "" {
+		loc, err = time.LoadLocation(tz)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": fmt.Sprintf("unknown timezone %q", tz)})
+		}
+	}
+	since := time.Now().AddDate(0, 0, -days)
+
+	// Exclude internal users unless requested
+	var excludedSupabaseUserIDs []string
+	if c.QueryParam("include_internal") != "true" {
+		excludedSupabaseUserIDs, err = GetInternalSupabaseIDs(ctx, []string{"linkedinorleftout.com"}, nil)
+		if err != nil {
+			c.Logger().Errorf("Failed to get internal user IDs: %v", err)
+		}
+	}
+
+	heatmap, err := database.GetSubmissionActivityHeatmap(ctx, since, loc, excludedSupabaseUserIDs)
+	if err != nil {
+		c.Logger().Errorf("Failed to fetch activity heatmap: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Failed to fetch activity heatmap"})
+	}
+
+	var total int64
+	for _, row := range heatmap {
+		for _, count := range row {
+			total += count
+		}
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"days":      days,
+		"since":     since,
+		"timezone":  loc.String(),
+		"dayLabels": database.ActivityHeatmapDays,
+		"matrix":    heatmap,
+		"total":     total,
+	})
+}
+
+// RanNeverSubmittedUser is a user who ran a real project but has no project submission
+type RanNeverSubmittedUser struct {
+	UserID   string     `json:"userId"`
+	LastSeen *time.Time `json:"lastSeen,omitempty"`
+}
+
+// GetRanNeverSubmittedUsers handles GET /admin/metrics/ran-never-submitted
+// Lists users with real-project run attempts but zero real-project submissions, most recently seen first.
+func GetRanNeverSubmittedUsers(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Request().Context(), DefaultQueryTimeout)
+	defer cancel()
+
+	page := parsePagination(c, defaultPaginationLimits())
+
+	// Exclude internal users unless requested
+	var excludedSupabaseUserIDs []string
+	if c.QueryParam("include_internal") != "true" {
+		var err error
+		excludedSupabaseUserIDs, err = GetInternalSupabaseIDs(ctx, []string{"linkedinorleftout.com"}, nil)
+		if err != nil {
+			c.Logger().Errorf("Failed to get internal user IDs: %v", err)
+		}
+	}
+
+	userIDs, err := database.GetRanNeverSubmittedUserIDs(ctx, excludedSupabaseUserIDs)
+	if err != nil {
+		c.Logger().Errorf("Failed to fetch ran-never-submitted users: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Failed to fetch ran-never-submitted users"})
+	}
+
+	// Last seen is optional enrichment; the list is still useful without it
+	lastSeen, err := database.GetLastSeenByUserIDs(ctx, userIDs)
+	if err != nil {
+		c.Logger().Warnf("Failed to fetch last seen for ran-never-submitted users: %v", err)
+		lastSeen = map[string]time.Time{}
+	}
+
+	// Most recently active first (they are the likeliest to respond to outreach), then by id for stable pages
+	sort.Slice(userIDs, func(i, j int) bool {
+		ti, tj := lastSeen[userIDs[i]], lastSeen[userIDs[j]]
+		if !ti.Equal(tj) {
+			return ti.After(tj)
+		}
+		return userIDs[i] < userIDs[j]
+	})
+
+	total := len(userIDs)
+	start := page.Offset
+	if start > total {
+		start = total
+	}
+	end := start + page.Limit
+	if end > total {
+		end = total
+	}
+
+	users := make([]RanNeverSubmittedUser, 0, end-start)
+	for _, id := range userIDs[start:end] {
+		user := RanNeverSubmittedUser{UserID: id}
+		if seen, ok := lastSeen[id]; ok {
+			seen := seen
+			user.LastSeen = &seen
+		}
+		users = append(users, user)
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"users":   users,
+		"total":   total,
+		"limit":   page.Limit,
+		"offset":  page.Offset,
+		"hasMore": page.HasMore(int64(total)),
+	})
+}
+
+// GetWarmupBySource handles GET /admin/metrics/warmup-by-source
+// Warmup (Project 0) run and submit counts per acquisition source tag, so growth can see
+// which channels send users who actually engage with the warmup.
+func GetWarmupBySource(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Request().Context(), DefaultQueryTimeout)
+	defer cancel()
+
+	// Exclude internal users unless requested
+	var excludedSupabaseUserIDs []string
+	if c.QueryParam("include_internal") != "true" {
+		var err error
+		excludedSupabaseUserIDs, err = GetInternalSupabaseIDs(ctx, []string{"linkedinorleftout.com"}, nil)
+		if err != nil {
+			c.Logger().Errorf("Failed to get internal user IDs: %v", err)
+		}
+	}
+
+	sources, err := database.GetAnalyticsTelemetryCollection().GetWarmupStatsBySource(ctx, excludedSupabaseUserIDs)
+	if err != nil {
+		c.Logger().Errorf("Failed to fetch warmup stats by source: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Failed to fetch warmup stats by source"})
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"tagKey":  database.EventTagSource,
+		"sources": sources,
+	})
+}
+
+// WarmupToCurriculumResponse summarizes how long warmup submitters take to start a real project
+type WarmupToCurriculumResponse struct {
+	// Users who submitted Project 0 (warmup)
+	WarmupSubmitters int `json:"warmupSubmitters"`
+	// Warmup submitters who later ran code on a real project
+	EnteredCurriculum int `json:"enteredCurriculum"`
+	// Warmup submitters with no real-project run yet
+	NeverEntered int `json:"neverEntered"`
+	// Users whose first real-project run predates their warmup submission (excluded from timing stats)
+	EnteredBeforeWarmup int `json:"enteredBeforeWarmup"`
+	// Days from warmup submit to first real-project run
+	MedianDaysToCurriculum  float64 `json:"medianDaysToCurriculum"`
+	AverageDaysToCurriculum float64 `json:"averageDaysToCurriculum"`
+	// Distribution of the gap
+	SameDay     int `json:"sameDay"`
+	Within7Days int `json:"within7Days"`
+	Over7Days   int `json:"over7Days"`
+}
+
+// GetWarmupToCurriculumMetrics handles GET /admin/metrics/warmup-to-curriculum
+// Among warmup submitters, measures the time until their first real-project run
+func GetWarmupToCurriculumMetrics(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Request().Context(), DefaultQueryTimeout)
+	defer cancel()
+
+	// Exclude internal users unless requested
+	var excludedSupabaseUserIDs []string
+	if c.QueryParam("include_internal") != "true" {
+		var err error
+		excludedSupabaseUserIDs, err = GetInternalSupabaseIDs(ctx, []string{"linkedinorleftout.com"}, nil)
+		if err != nil {
+			c.Logger().Errorf("Failed to get internal user IDs: %v", err)
+		}
+	}
+
+	timings, err := database.GetWarmupToCurriculumTimings(ctx, excludedSupabaseUserIDs)
+	if err != nil {
+		c.Logger().Errorf("Failed to compute warmup-to-curriculum timings: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Failed to compute warmup-to-curriculum metrics"})
+	}
+
+	response := WarmupToCurriculumResponse{WarmupSubmitters: len(timings)}
+	gapMinutes := make([]int64, 0, len(timings))
+	for _, t := range timings {
+		if t.FirstCurriculumRunAt == nil {
+			response.NeverEntered++
+			continue
+		}
+		response.EnteredCurriculum++
+
+		gap := t.FirstCurriculumRunAt.Sub(t.WarmupSubmittedAt)
+		if gap < 0 {
+			response.EnteredBeforeWarmup++
+			continue
+		}
+		gapMinutes = append(gapMinutes, int64(gap/time.Minute))
+
+		switch {
+		case gap < 24*time.Hour:
+			response.SameDay++
+		case gap <= 7*24*time.Hour:
+			response.Within7Days++
+		default:
+			response.Over7Days++
+		}
+	}
+
+	const minutesPerDay = 24 * 60
+	response.MedianDaysToCurriculum = float64(calculateMedian(gapMinutes)) / minutesPerDay
+	response.AverageDaysToCurriculum = float64(calculateAverage(gapMinutes)) / minutesPerDay
+
+	return c.JSON(http.StatusOK, response)
+}
+
 // LatestSubmissionResponse represents a submission for the admin submissions feed
 type LatestSubmissionResponse struct {
 	ID           string                `json:"_id"`
@@ -566,24 +1307,42 @@ func parseOS(ua string) string {
 	return "Other"
 }
 
+// parseTimeQueryParam reads an optional RFC3339 or YYYY-MM-DD query parameter.
+// Returns nil when the parameter is absent.
+func parseTimeQueryParam(c echo.Context, name string) (*time.Time, error) {
+	return parseTimeValue(name, c.QueryParam(name))
+}
+
+// parseTimeValue parses an optional RFC3339 or YYYY-MM-DD value (dates are UTC midnight).
+// Returns nil when raw is blank; name is used in the error.
+func parseTimeValue(name, raw string) (*time.Time, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return &t, nil
+	}
+	t, err := time.Parse("2006-01-02", raw)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be RFC3339 or YYYY-MM-DD", name)
+	}
+	return &t, nil
+}
+
 // GetLatestSubmissions handles GET /admin/submissions/latest
 // Returns the most recent project submissions for the admin dashboard
 // Query params:
-//   - limit: number of submissions (default 20, max 100)
+//   - limit, offset/page: pagination (PAGINATION_DEFAULT_LIMIT / PAGINATION_MAX_LIMIT, default 20 / 100);
+//     an invalid limit uses the default rather than a 400
 //   - timeRange: filter by time period (1h, 12h, 24h, 7d, 30d, all)
+//   - includeTest: include staff-flagged test submissions (default false)
+//   - anonymize: replace user ids/emails with stable pseudonyms and drop avatars for screen-sharing (default false)
 func GetLatestSubmissions(c echo.Context) error {
 	ctx, cancel := context.WithTimeout(c.Request().Context(), DefaultQueryTimeout)
 	defer cancel()
 
-	// Get limit from query param, default to 20
-	limit := 20
-	if limitParam := c.QueryParam("limit"); limitParam != "" {
-		if l, err := fmt.Sscanf(limitParam, "%d", &limit); err == nil && l > 0 {
-			if limit > 100 {
-				limit = 100 // Cap at 100
-			}
-		}
-	}
+	pagination := parsePagination(c, defaultPaginationLimits())
 
 	// Get time range filter
 	timeRange := c.QueryParam("timeRange")
@@ -640,6 +1399,10 @@ func GetLatestSubmissions(c echo.Context) error {
 		filter["supabaseUserId"] = bson.M{"$nin": excludedSupabaseUserIDs}
 	}
 
+	if c.QueryParam("includeTest") != "true" {
+		database.ExcludeTestSubmissions(filter)
+	}
+
 	// Add time filter if specified
 	if sinceTime != nil {
 		filter["createdAt"] = bson.M{"$gte": *sinceTime}
@@ -647,7 +1410,8 @@ func GetLatestSubmissions(c echo.Context) error {
 
 	findOptions := options.Find().
 		SetSort(bson.D{{Key: "createdAt", Value: -1}}).
-		SetLimit(int64(limit))
+		SetSkip(int64(pagination.Offset)).
+		SetLimit(int64(pagination.Limit))
 
 	cursor, err := collection.Find(ctx, filter, findOptions)
 	if err != nil {
@@ -666,6 +1430,14 @@ func GetLatestSubmissions(c echo.Context) error {
 		})
 	}
 
+	// Masking only touches the response; pseudonyms are scoped to the viewing admin
+	anonymize := c.QueryParam("anonymize") == "true"
+	var pseudonyms pseudonymizer
+	if anonymize {
+		viewer, _ := GetUserClaims(c)
+		pseudonyms = newPseudonymizer(viewer.UserID)
+	}
+
 	// Build response with project titles and user names
 	response := make([]LatestSubmissionResponse, 0, len(submissions))
 
@@ -711,6 +1483,16 @@ func GetLatestSubmissions(c echo.Context) error {
 			userDisplayEmail = "Unknown Email"
 		}
 
+		if anonymize {
+			identity := sub.SupabaseUserID
+			if identity == "" {
+				identity = sub.UserID
+			}
+			userDisplayName = pseudonyms.Name(identity)
+			userDisplayEmail = pseudonyms.Email(identity)
+			userDisplayImage = "" // An avatar identifies the user as well as a name does
+		}
+
 		// Build test summary
 		testSummary := LatestSubmissionTests{
 			Passed: 0,
@@ -738,14 +1520,17 @@ func GetLatestSubmissions(c echo.Context) error {
 
 	return c.JSON(http.StatusOK, echo.Map{
 		"submissions": response,
+		"limit":       pagination.Limit,
+		"offset":      pagination.Offset,
+		"anonymized":  anonymize,
 	})
 }
 
 // FunnelMetricsResponse represents the pre-activation onboarding funnel metrics
 // Stages are CAUSALLY ORDERED: each stage is a subset of the previous stage
 type FunnelMetricsResponse struct {
-	// Stage 0: Total distinct users in Supabase (invited or signed up)
-	TotalUsers int `json:"totalUsers"`
+	// Stage 0: Total distinct users in Supabase (invited or signed up); null when Supabase is unavailable
+	TotalUsers *int `json:"totalUsers"`
 	// Stage 1: Users who created an account (have record in MongoDB)
 	SignedIn int `json:"signedIn"`
 	// Stage 2: Users who ran code on Project 0 (warmup)
@@ -754,14 +1539,36 @@ type FunnelMetricsResponse struct {
 	WarmupSubmit int `json:"warmupSubmit"`
 	// Stage 4: Users who ran code on any real project (projectNumber >= 1)
 	EnteredCurriculum int `json:"enteredCurriculum"`
-	// Stage 5: Users who submitted at least 1 real project (projectNumber >= 1)
+	// Stage 5: Users who meet the activation definition on a real project (projectNumber >= 1)
 	Activated int `json:"activated"`
+	// What "activated" means for this response: "ran", "submitted" (default) or "passed"
+	ActivationDefinition string `json:"activationDefinition"`
 	// Stage 6: Activated users who completed at least 1 real project (passed=true, projectNumber >= 1)
 	Completed int `json:"completed"`
 	// Stage 7: Activated users who returned and performed meaningful action (>1 session day)
 	Retained int `json:"retained"`
+
+	// Separate track for standalone question/problem submissions (sourceType "code").
+	// Only present with ?include_questions=true; the project stages above are unaffected.
+	QuestionTrack *QuestionFunnelMetrics `json:"questionTrack,omitempty"`
+
+	// Fields that could not be computed because a dependency was down (e.g. "totalUsers")
+	Unavailable []string `json:"unavailable,omitempty"`
 }
 
+// QuestionFunnelMetrics is the question-based activation track of the funnel
+type QuestionFunnelMetrics struct {
+	// Users who submitted at least 1 question (question-based activation)
+	Activated int `json:"activated"`
+	// Users who passed at least 1 question
+	Completed int `json:"completed"`
+	// Question submitters active on more than 1 distinct day
+	Retained int `json:"retained"`
+}
+
+// funnelStageConcurrency bounds how many funnel stage queries run at once
+const funnelStageConcurrency = 4
+
 // GetFunnelMetrics handles GET /admin/metrics/funnel
 // Returns pre-activation onboarding funnel metrics for the admin dashboard
 // All stages are CAUSALLY ORDERED (each is a subset of the previous)
@@ -780,76 +1587,153 @@ func GetFunnelMetrics(c echo.Context) error {
 		var err error
 		excludedSupabaseUserIDs, err = GetInternalSupabaseIDs(ctx, []string{"linkedinorleftout.com"}, nil)
 		if err != nil {
-			c.Logger().Errorf("Failed to get internal user IDs: %v", err)
+			// Without the exclusion list internal users would inflate every stage,
+			// so refuse rather than serve polluted numbers
+			c.Logger().Errorf("Funnel: Supabase unavailable, cannot exclude internal users: %v", err)
+			return c.JSON(http.StatusServiceUnavailable, echo.Map{
+				"error": "Supabase is unavailable; internal users cannot be excluded. Retry later or pass include_internal=true.",
+			})
 		}
 	}
 
+	// Stages are independent counts, so run them concurrently. Each stage logs and
+	// leaves its field at zero on failure rather than failing the whole request.
+	var g errgroup.Group
+	g.SetLimit(funnelStageConcurrency)
+
 	// Stage 0: Total Users - Count all distinct users in Supabase auth.users
-	totalUserCount, err := database.CountTotalSupabaseUsers(ctx, excludedSupabaseUserIDs)
-	if err != nil {
-		c.Logger().Warnf("Failed to count total Supabase users: %v", err)
-	} else {
-		response.TotalUsers = totalUserCount
-	}
+	g.Go(func() error {
+		totalUserCount, err := database.CountTotalSupabaseUsers(ctx, excludedSupabaseUserIDs)
+		if err != nil {
+			// Leave totalUsers null (not 0) so the dashboard can tell "unknown" from "none"
+			c.Logger().Errorf("Funnel: Supabase unavailable, totalUsers omitted: %v", err)
+			response.Unavailable = append(response.Unavailable, "totalUsers")
+		} else {
+			response.TotalUsers = &totalUserCount
+		}
+		return nil
+	})
 
 	// Stage 1: Signed In - Count from MongoDB users collection
-	signedInCount, err := database.AppCollections.Users.CountUsers(ctx)
-	if err != nil {
-		c.Logger().Warnf("Failed to count users: %v", err)
-	} else {
-		response.SignedIn = int(signedInCount)
-	}
+	g.Go(func() error {
+		signedInCount, err := database.AppCollections.Users.CountUsers(ctx)
+		if err != nil {
+			c.Logger().Warnf("Failed to count users: %v", err)
+		} else {
+			response.SignedIn = int(signedInCount)
+		}
+		return nil
+	})
 
 	// Stage 2: Warmup Run - Users who ran code on Project 0
 	// Uses telemetry events (project_run_attempt with projectNumber=0)
-	warmupRunCount, err := database.CountUsersWhoRanWarmup(ctx, excludedSupabaseUserIDs)
-	if err != nil {
-		c.Logger().Warnf("Failed to count warmup run users: %v", err)
-	} else {
-		response.WarmupRun = warmupRunCount
-	}
+	g.Go(func() error {
+		warmupRunCount, err := database.CountUsersWhoRanWarmup(ctx, excludedSupabaseUserIDs)
+		if err != nil {
+			c.Logger().Warnf("Failed to count warmup run users: %v", err)
+		} else {
+			response.WarmupRun = warmupRunCount
+		}
+		return nil
+	})
 
 	// Stage 3: Warmup Submit - Users who submitted Project 0
 	// Uses browser_submissions with projectNumber=0
-	warmupSubmitCount, err := database.CountUsersWhoSubmittedWarmup(ctx, excludedSupabaseUserIDs)
-	if err != nil {
-		c.Logger().Warnf("Failed to count warmup submit users: %v", err)
-	} else {
-		response.WarmupSubmit = warmupSubmitCount
-	}
+	g.Go(func() error {
+		warmupSubmitCount, err := database.CountUsersWhoSubmittedWarmup(ctx, excludedSupabaseUserIDs)
+		if err != nil {
+			c.Logger().Warnf("Failed to count warmup submit users: %v", err)
+		} else {
+			response.WarmupSubmit = warmupSubmitCount
+		}
+		return nil
+	})
 
 	// Stage 4: Entered Curriculum - Users who ran code on any real project (projectNumber >= 1)
 	// Uses telemetry events (project_run_attempt with projectNumber >= 1)
-	enteredCount, err := database.CountUsersWhoEnteredCurriculum(ctx, excludedSupabaseUserIDs)
-	if err != nil {
-		c.Logger().Warnf("Failed to count users who entered curriculum: %v", err)
-	} else {
-		response.EnteredCurriculum = enteredCount
-	}
+	g.Go(func() error {
+		enteredCount, err := database.CountUsersWhoEnteredCurriculum(ctx, excludedSupabaseUserIDs)
+		if err != nil {
+			c.Logger().Warnf("Failed to count users who entered curriculum: %v", err)
+		} else {
+			response.EnteredCurriculum = enteredCount
+		}
+		return nil
+	})
 
-	// Stage 5: Activated - Users who submitted at least 1 real project (projectNumber >= 1)
-	activatedCount, err := database.CountDistinctActivatedUsers(ctx, excludedSupabaseUserIDs)
-	if err != nil {
-		c.Logger().Warnf("Failed to count activated users: %v", err)
-	} else {
-		response.Activated = activatedCount
-	}
+	// Stage 5: Activated - Users meeting ACTIVATION_DEFINITION on a real project (projectNumber >= 1);
+	// by default, submitted at least 1 real project
+	activation := database.ResolveActivationDefinition(config.GetConfig().ActivationDefinition)
+	response.ActivationDefinition = activation
+	g.Go(func() error {
+		activatedCount, err := database.CountActivatedUsers(ctx, activation, excludedSupabaseUserIDs)
+		if err != nil {
+			c.Logger().Warnf("Failed to count activated users: %v", err)
+		} else {
+			response.Activated = activatedCount
+		}
+		return nil
+	})
 
 	// Stage 6: Completed - Activated users who passed at least 1 real project
-	completedCount, err := database.CountDistinctCompletedRealProjects(ctx, excludedSupabaseUserIDs)
-	if err != nil {
-		c.Logger().Warnf("Failed to count completed users: %v", err)
-	} else {
-		response.Completed = completedCount
-	}
+	g.Go(func() error {
+		completedCount, err := database.CountDistinctCompletedRealProjects(ctx, excludedSupabaseUserIDs)
+		if err != nil {
+			c.Logger().Warnf("Failed to count completed users: %v", err)
+		} else {
+			response.Completed = completedCount
+		}
+		return nil
+	})
 
 	// Stage 7: Retained - Activated users who returned (>1 distinct session day)
-	retainedCount, err := database.CountRetainedActivatedUsers(ctx, excludedSupabaseUserIDs)
-	if err != nil {
-		c.Logger().Warnf("Failed to count retained users: %v", err)
-	} else {
-		response.Retained = retainedCount
+	g.Go(func() error {
+		retainedCount, err := database.CountRetainedActivatedUsers(ctx, activation, excludedSupabaseUserIDs)
+		if err != nil {
+			c.Logger().Warnf("Failed to count retained users: %v", err)
+		} else {
+			response.Retained = retainedCount
+		}
+		return nil
+	})
+
+	// Question track: independent of the project stages, run in the same group
+	if c.QueryParam("include_questions") == "true" {
+		response.QuestionTrack = &QuestionFunnelMetrics{}
+		track := response.QuestionTrack
+
+		g.Go(func() error {
+			count, err := database.CountDistinctQuestionSubmitters(ctx, excludedSupabaseUserIDs)
+			if err != nil {
+				c.Logger().Warnf("Failed to count question submitters: %v", err)
+			} else {
+				track.Activated = count
+			}
+			return nil
+		})
+
+		g.Go(func() error {
+			count, err := database.CountDistinctQuestionSolvers(ctx, excludedSupabaseUserIDs)
+			if err != nil {
+				c.Logger().Warnf("Failed to count question solvers: %v", err)
+			} else {
+				track.Completed = count
+			}
+			return nil
+		})
+
+		g.Go(func() error {
+			count, err := database.CountRetainedQuestionUsers(ctx, excludedSupabaseUserIDs)
+			if err != nil {
+				c.Logger().Warnf("Failed to count retained question users: %v", err)
+			} else {
+				track.Retained = count
+			}
+			return nil
+		})
 	}
 
+	_ = g.Wait()
+
 	return c.JSON(http.StatusOK, response)
 }
@@ -0,0 +1,11 @@
+package handlers
+
+import "testing"
+
+func TestUniqueUserKeys(t *testing.T) {
+	got := uniqueUserKeys([]string{"uuid-1", "", "jane@example.com", " uuid-1 ", "jane@example.com"})
+	want := []string{"uuid-1", "jane@example.com"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("uniqueUserKeys = %q, want %q", got, want)
+	}
+}
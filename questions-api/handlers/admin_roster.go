@@ -3,7 +3,6 @@ package handlers
 import (
 	"context"
 	"net/http"
-	"strconv"
 	"time"
 
 	"github.com/gerdinv/questions-api/config"
@@ -12,27 +11,23 @@ import (
 	"github.com/labstack/echo/v4"
 )
 
+// rosterLimits bounds the roster page size
+var rosterLimits = paginationLimits{Default: 50, Max: 100}
+
 // GetRoster handles GET /admin/roster
 // Fetches users from Supabase and enriches with project completion data from MongoDB.
 // Returns:
 //   - users: Supabase user list
 //   - projectsTotal: total curriculum projects (from content DB)
 //   - projectsCompletedByUser: map of supabaseUserId -> completed project count
+//   - lastSeenByUser: map of supabaseUserId -> most recent runner event time
 func GetRoster(c echo.Context) error {
 	ctx, cancel := context.WithTimeout(c.Request().Context(), 15*time.Second)
 	defer cancel()
 
-	page, _ := strconv.Atoi(c.QueryParam("page"))
-	if page < 1 {
-		page = 1
-	}
-	limit, _ := strconv.Atoi(c.QueryParam("limit"))
-	if limit < 1 {
-		limit = 50
-	}
-	if limit > 100 {
-		limit = 100
-	}
+	// Supabase pages by page number, so offset= is rounded down to its page
+	pagination := parsePagination(c, rosterLimits)
+	page, limit := pagination.Page, pagination.Limit
 
 	// 1. Fetch users from Supabase
 	cfg := config.GetConfig()
@@ -71,13 +66,21 @@ func GetRoster(c echo.Context) error {
 	}
 
 	// 5. Get pass rates per user
-	passRatesByUser, err := database.GetPassRatesByUserIDs(ctx, userIDs)
+	passRatesByUser, err := database.GetPassRatesByUserIDs(ctx, userIDs, resolveExcludedProblemIDs(c))
 	if err != nil {
 		c.Logger().Errorf("Failed to get pass rates: %v", err)
 		// Don't fail the request, just return empty map
 		passRatesByUser = make(map[string]int)
 	}
 
+	// 6. Get last activity per user
+	lastSeenByUser, err := database.GetLastSeenByUserIDs(ctx, userIDs)
+	if err != nil {
+		c.Logger().Errorf("Failed to get last seen: %v", err)
+		// Don't fail the request, just return empty map
+		lastSeenByUser = make(map[string]time.Time)
+	}
+
 	// Return enriched response
 	return c.JSON(http.StatusOK, echo.Map{
 		"users":                   users,
@@ -86,5 +89,6 @@ func GetRoster(c echo.Context) error {
 		"projectsTotal":           projectsTotal,
 		"projectsCompletedByUser": projectsCompletedByUser,
 		"passRatesByUser":         passRatesByUser,
+		"lastSeenByUser":          lastSeenByUser,
 	})
 }
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gerdinv/questions-api/database"
+	"github.com/labstack/echo/v4"
+)
+
+// userTelemetryLimits are larger than the API default since events are small
+var userTelemetryLimits = paginationLimits{Default: 50, Max: 200}
+
+// GetUserTelemetry handles GET /admin/users/:id/telemetry
+// Returns a user's raw runner_events, newest first, for debugging metrics.
+// Query params:
+//   - event: optional event type filter (must be a well-formed event name)
+//   - page or offset, limit: pagination (default 1 / 50, limit capped at 200)
+//   - db: "dev" reads the dev DB instead; only allowed for internal users,
+//     whose events are routed there
+func GetUserTelemetry(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Request().Context(), DefaultQueryTimeout)
+	defer cancel()
+
+	userID := strings.TrimSpace(c.Param("id"))
+	if userID == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "User ID is required",
+		})
+	}
+	if decoded, err := DecodeEmailParam(userID); err == nil {
+		userID = decoded
+	}
+	// runner_events are keyed by Supabase UUID, legacy ones by email; accept either
+	// and query both keys
+	userKeys := []string{userID}
+	resolvedID, err := ResolveSupabaseUserID(ctx, userID)
+	switch {
+	case err == nil:
+		userID = resolvedID
+		userKeys = uniqueUserKeys(append(userKeys, userID))
+	case errors.Is(err, ErrSupabaseUserNotFound):
+		// Legacy Mongo-only account: keep matching by email
+		if _, lookupErr := database.AppCollections.Users.GetUserByEmail(ctx, userID); lookupErr != nil {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": "User not found",
+			})
+		}
+	default:
+		c.Logger().Warnf("Failed to resolve Supabase ID for %s, falling back to email: %v", userID, err)
+	}
+
+	eventType := c.QueryParam("event")
+	if err := database.ValidateEventTypeFilter(eventType); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	pagination := parsePagination(c, userTelemetryLimits)
+
+	telemetryCol := database.GetTelemetryCollection()
+	source := "app"
+	switch c.QueryParam("db") {
+	case "", "app":
+	case "dev":
+		internal, err := isInternalUserID(ctx, userID)
+		if err != nil {
+			c.Logger().Errorf("Failed to resolve internal users: %v", err)
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to verify internal user",
+			})
+		}
+		if !internal {
+			return c.JSON(http.StatusForbidden, map[string]string{
+				"error": "Dev DB telemetry is only available for internal users",
+			})
+		}
+		telemetryCol = database.GetDevTelemetryCollection()
+		source = "dev"
+	default:
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "db must be 'app' or 'dev'",
+		})
+	}
+
+	total, err := telemetryCol.CountEventsByUser(ctx, userKeys, eventType)
+	if err != nil {
+		c.Logger().Errorf("Failed to count telemetry for user %s: %v", userID, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to fetch telemetry",
+		})
+	}
+
+	events, err := telemetryCol.GetEventsByUserPage(ctx, userKeys, eventType, pagination.Offset, pagination.Limit)
+	if err != nil {
+		c.Logger().Errorf("Failed to fetch telemetry for user %s: %v", userID, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to fetch telemetry",
+		})
+	}
+	if events == nil {
+		events = []database.RunnerEventDocument{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"userId":  userID,
+		"event":   eventType,
+		"db":      source,
+		"page":    pagination.Page,
+		"limit":   pagination.Limit,
+		"offset":  pagination.Offset,
+		"total":   total,
+		"hasMore": pagination.HasMore(total),
+		"events":  events,
+	})
+}
+
+// isInternalUserID reports whether an email or Supabase user ID belongs to an internal user
+func isInternalUserID(ctx context.Context, userID string) (bool, error) {
+	if strings.Contains(userID, "@") {
+		return database.IsInternalUser(userID), nil
+	}
+	internalIDs, err := GetInternalSupabaseIDs(ctx, []string{"linkedinorleftout.com"}, nil)
+	if err != nil {
+		return false, err
+	}
+	for _, id := range internalIDs {
+		if id == userID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
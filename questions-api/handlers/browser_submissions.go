@@ -1,7 +1,9 @@
 package handlers
 
 import (
+	"context"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -29,6 +31,7 @@ type BrowserSubmissionPayload struct {
 	UserTestsResults []UserTestResult       `json:"userTestsResults,omitempty"`
 	Result           BrowserExecutionResult `json:"result"`
 	Meta             BrowserExecutionMeta   `json:"meta"`
+	IsTest           bool                   `json:"isTest,omitempty"` // Staff QA flag, only honored for admin/internal users
 }
 
 // BrowserExecutionResult contains the execution results
@@ -151,6 +154,77 @@ func convertEditorSignals(signals *EditorSignals) *database.EditorSignals {
 	}
 }
 
+// submissionMatchesStarter reports whether the submitted files differ from the project's
+// starter files by at most maxDiffChars changed characters in total. Files that are not
+// starter files (e.g. tests) are ignored. Uses the cached project catalog; unknown
+// projects or projects without starter files never match.
+func submissionMatchesStarter(ctx context.Context, problemID string, files map[string]string, maxDiffChars int) bool {
+	projectNumber, err := strconv.Atoi(strings.TrimSpace(problemID))
+	if err != nil {
+		return false
+	}
+	catalog, err := getProjectCatalog(ctx)
+	if err != nil {
+		return false
+	}
+
+	for _, project := range catalog {
+		if project.ProjectNumber != projectNumber {
+			continue
+		}
+		if len(project.StarterFiles) == 0 {
+			return false
+		}
+		changed := 0
+		for name, starter := range project.StarterFiles {
+			changed += changedChars(normalizeCode(starter), normalizeCode(files[name]))
+			if changed > maxDiffChars {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// normalizeCode ignores line-ending and surrounding whitespace differences
+func normalizeCode(s string) string {
+	return strings.TrimSpace(strings.ReplaceAll(s, "\r\n", "\n"))
+}
+
+// changedChars approximates the edit size between a and b as the length of the
+// longer string minus their common prefix and suffix.
+func changedChars(a, b string) int {
+	if a == b {
+		return 0
+	}
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	longer := len(a)
+	if len(b) > longer {
+		longer = len(b)
+	}
+	return longer - prefix - suffix
+}
+
+// submissionPassed reports whether a run passed: clean exit, no failures and at
+// least minTestCount tests. A run that reports zero tests never passes.
+func submissionPassed(exitCode int, summary *BrowserTestSummary, minTestCount int) bool {
+	if exitCode != 0 || summary == nil {
+		return false
+	}
+	if minTestCount < 1 {
+		minTestCount = 1
+	}
+	return summary.Failed == 0 && summary.Total >= minTestCount
+}
+
 // convertTestSummary converts handler test summary to database format
 func convertTestSummary(summary *BrowserTestSummary) *database.BrowserTestSummary {
 	if summary == nil {
@@ -197,6 +271,9 @@ func CreateBrowserSubmission(c echo.Context) error {
 		})
 	}
 
+	// Legacy clients sent " 7" / "07" for projects; normalize before project lookups and the insert
+	payload.ProblemID = database.NormalizeSubmissionProblemID(payload.SourceType, payload.ProblemID)
+
 	// Get user claims from JWT - STRICT MODE: Source of Truth
 	claims, ok := GetUserClaims(c)
 	if !ok {
@@ -226,13 +303,34 @@ func CreateBrowserSubmission(c echo.Context) error {
 		}
 	}
 
+	// Only staff may flag submissions as test data; clients always send the flag,
+	// so a non-admin's isTest is ignored rather than rejected
+	isTest := payload.IsTest && isAdminClaims(claims)
+	if payload.IsTest && !isTest {
+		c.Logger().Warnf("CreateBrowserSubmission: Ignoring isTest flag from non-admin user %s", userID)
+	}
+
 	// Normalize email for consistent querying
 	emailNormalized := strings.ToLower(strings.TrimSpace(email))
 
+	// Projects may require a minimum number of tests so a mis-scoped harness
+	// running one trivial test can't count as a pass
+	minTestCount := database.DefaultMinTestCount
+	if payload.SourceType == "project" {
+		var err error
+		minTestCount, err = database.GetProjectMinTestCount(c.Request().Context(), payload.ProblemID)
+		if err != nil {
+			c.Logger().Warnf("CreateBrowserSubmission: %v; using default min test count", err)
+		}
+	}
+
 	// Determine if all tests passed
-	passed := false
-	if payload.Result.ExitCode == 0 && payload.Result.TestSummary != nil {
-		passed = payload.Result.TestSummary.Failed == 0 && payload.Result.TestSummary.Total > 0
+	passed := submissionPassed(payload.Result.ExitCode, payload.Result.TestSummary, minTestCount)
+
+	// Flag passes that barely touched the starter files (trivial tests / curriculum gap)
+	passedWithStarter := false
+	if passed && payload.SourceType == "project" {
+		passedWithStarter = submissionMatchesStarter(c.Request().Context(), payload.ProblemID, payload.Files, cfg.StarterCodeMaxDiffChars)
 	}
 
 	// Convert user test results to database format
@@ -275,14 +373,17 @@ func CreateBrowserSubmission(c echo.Context) error {
 			EditorSignals:  convertEditorSignals(payload.Meta.EditorSignals),
 			VizPayload:     payload.Meta.VizPayload, // Pass through VizPayload
 		},
-		Passed:      passed,
-		UserAgent:   c.Request().Header.Get("User-Agent"),
-		Environment: env,
-		CreatedAt:   time.Now(),
+		Passed:            passed,
+		MinTestCount:      minTestCount,
+		PassedWithStarter: passedWithStarter,
+		UserAgent:         c.Request().Header.Get("User-Agent"),
+		Environment:       env,
+		IsTest:            isTest,
+		CreatedAt:         time.Now(),
 	}
 
 	// Insert into MongoDB
-	insertedID, err := database.CreateBrowserSubmission(&submission)
+	insertedID, err := database.CreateBrowserSubmission(c.Request().Context(), &submission)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{
 			"error": "Failed to save submission",
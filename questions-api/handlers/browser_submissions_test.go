@@ -0,0 +1,31 @@
+package handlers
+
+import "testing"
+
+func TestSubmissionPassedMinTestCount(t *testing.T) {
+	tests := []struct {
+		name         string
+		exitCode     int
+		summary      *BrowserTestSummary
+		minTestCount int
+		want         bool
+	}{
+		{"nil summary", 0, nil, 1, false},
+		{"zero tests never pass", 0, &BrowserTestSummary{Total: 0}, 1, false},
+		{"zero tests with unset minimum", 0, &BrowserTestSummary{Total: 0}, 0, false},
+		{"one test meets default", 0, &BrowserTestSummary{Total: 1, Passed: 1}, 1, true},
+		{"unset minimum behaves as default", 0, &BrowserTestSummary{Total: 1, Passed: 1}, 0, true},
+		{"one below minimum", 0, &BrowserTestSummary{Total: 4, Passed: 4}, 5, false},
+		{"exactly at minimum", 0, &BrowserTestSummary{Total: 5, Passed: 5}, 5, true},
+		{"above minimum", 0, &BrowserTestSummary{Total: 6, Passed: 6}, 5, true},
+		{"failure at minimum", 0, &BrowserTestSummary{Total: 5, Passed: 4, Failed: 1}, 5, false},
+		{"non-zero exit", 1, &BrowserTestSummary{Total: 5, Passed: 5}, 5, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := submissionPassed(tt.exitCode, tt.summary, tt.minTestCount); got != tt.want {
+				t.Errorf("submissionPassed(%d, %+v, %d) = %v, want %v", tt.exitCode, tt.summary, tt.minTestCount, got, tt.want)
+			}
+		})
+	}
+}
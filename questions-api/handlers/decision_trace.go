@@ -3,10 +3,15 @@ package handlers
 import (
 	"context"
 	"crypto/sha256"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/gerdinv/questions-api/config"
 	"github.com/gerdinv/questions-api/database"
 	"github.com/gerdinv/questions-api/shared"
 	"github.com/labstack/echo/v4"
@@ -40,6 +45,7 @@ type DTExecutionPayload struct {
 	MemoryKb           *int                  `json:"memoryKb"`
 	Tests              *DTTestSummaryPayload `json:"tests"`
 	TestResults        []DTTestResultPayload `json:"testResults"`
+	TimedOut           bool                  `json:"timedOut,omitempty"`
 }
 
 // DTTestSummaryPayload holds pass/fail counts from the frontend.
@@ -113,14 +119,42 @@ var validEventTypes = map[string]bool{
 // maxTestResults caps how many individual test results we store per event (V1).
 const maxTestResults = 10
 
+// defaultMaxMermaidBytes caps stored Mermaid text when DECISION_TRACE_MAX_MERMAID_BYTES is unset.
+const defaultMaxMermaidBytes = 20000
+
+// mermaidKind is the only accepted visualization kind (null means no visualization type).
+const mermaidKind = "MERMAID"
+
+// mermaidDiagramHeaders are the diagram declarations a Mermaid document may start with.
+var mermaidDiagramHeaders = []string{
+	"graph", "flowchart", "sequenceDiagram", "classDiagram", "stateDiagram", "stateDiagram-v2",
+	"erDiagram", "gantt", "pie", "journey", "mindmap", "timeline", "gitGraph", "block-beta",
+}
+
 // isAdminClaims checks if the user has admin-level access (internal email or admin role).
 func isAdminClaims(claims shared.UserClaims) bool {
 	return shared.IsInternalUser(claims.Email) || claims.Role == "admin"
 }
 
+// canAccessUserData reports whether the caller may read data owned by ownerUserID:
+// their own, or anyone's as an admin. A false result is a 403, never a 401.
+func canAccessUserData(claims shared.UserClaims, ownerUserID string) bool {
+	return ownerUserID == claims.UserID || isAdminClaims(claims)
+}
+
+// targetUserIDParam resolves the optional ?userId= query param. Empty or the caller's
+// own id selects the caller; another user's id requires admin (ok is false otherwise).
+func targetUserIDParam(c echo.Context, claims shared.UserClaims) (string, bool) {
+	requested := strings.TrimSpace(c.QueryParam("userId"))
+	if requested == "" {
+		return claims.UserID, true
+	}
+	return requested, canAccessUserData(claims, requested)
+}
+
 // allTestsPassed returns true if the execution indicates all tests passed.
 func allTestsPassed(exec *DTExecutionPayload) bool {
-	if exec == nil || exec.Tests == nil {
+	if exec == nil || exec.Tests == nil || executionTimedOut(exec) {
 		return false
 	}
 	if exec.Tests.Total == nil || exec.Tests.Failed == nil {
@@ -129,6 +163,12 @@ func allTestsPassed(exec *DTExecutionPayload) bool {
 	return *exec.Tests.Total > 0 && *exec.Tests.Failed == 0
 }
 
+// executionTimedOut reports whether the run hit the time limit, either flagged
+// directly or via the runner's TIMEOUT error code.
+func executionTimedOut(exec *DTExecutionPayload) bool {
+	return exec.TimedOut || (exec.UniversalErrorCode != nil && *exec.UniversalErrorCode == database.DTErrorCodeTimeout)
+}
+
 // ============================================================
 // Payload → Database Conversion
 // ============================================================
@@ -146,6 +186,9 @@ func convertDTExecution(p *DTExecutionPayload) database.DTEventExecution {
 		MemoryKb:           p.MemoryKb,
 	}
 
+	timedOut := executionTimedOut(p)
+	exec.TimedOut = timedOut
+
 	if p.Tests != nil {
 		exec.Tests = database.DTEventTestSummary{
 			Total:  p.Tests.Total,
@@ -159,30 +202,203 @@ func convertDTExecution(p *DTExecutionPayload) database.DTEventExecution {
 		if i >= maxTestResults {
 			break
 		}
+		status := tr.Status
+		// On a timeout, anything that didn't report a verdict never ran
+		if timedOut && status != database.DTTestStatusPassed && status != database.DTTestStatusFailed {
+			status = database.DTTestStatusNotRun
+		}
 		exec.TestResults = append(exec.TestResults, database.DTEventTestResult{
 			TestName:     tr.TestName,
-			Status:       tr.Status,
+			Status:       status,
 			Message:      tr.Message,
 			ErrorCode:    tr.ErrorCode,
 			ErrorTooltip: tr.ErrorTooltip,
 		})
 	}
 
+	if timedOut {
+		fillPartialTestSummary(&exec.Tests, p.TestResults)
+	}
+
 	return exec
 }
 
-func convertDTVisualization(p *DTVisualizationPayload) database.DTEventVisualization {
+// fillPartialTestSummary completes the summary of a timed-out run from whatever
+// test results arrived: missing counts are derived from the individual results
+// and notRun covers tests that never reported a verdict.
+func fillPartialTestSummary(tests *database.DTEventTestSummary, results []DTTestResultPayload) {
+	passed, failed := 0, 0
+	for _, tr := range results {
+		switch tr.Status {
+		case database.DTTestStatusPassed:
+			passed++
+		case database.DTTestStatusFailed:
+			failed++
+		}
+	}
+
+	if tests.Passed == nil {
+		tests.Passed = &passed
+	}
+	if tests.Failed == nil {
+		tests.Failed = &failed
+	}
+	if tests.Total == nil {
+		total := len(results)
+		tests.Total = &total
+	}
+
+	notRun := *tests.Total - *tests.Passed - *tests.Failed
+	if notRun < 0 {
+		notRun = 0
+	}
+	tests.NotRun = &notRun
+}
+
+// convertDTVisualization expects a payload that passed validateDTVisualization.
+// Mermaid text over maxBytes is dropped (a truncated diagram would not render)
+// and flagged with MermaidOmitted.
+func convertDTVisualization(p *DTVisualizationPayload, maxBytes int) database.DTEventVisualization {
 	if p == nil {
 		return database.DTEventVisualization{}
 	}
-	return database.DTEventVisualization{
+	vis := database.DTEventVisualization{
 		Kind:          p.Kind,
 		MermaidText:   p.MermaidText,
 		StateSnapshot: p.StateSnapshot,
 	}
+	if p.MermaidText != nil && len(*p.MermaidText) > maxBytes {
+		vis.MermaidText = nil
+		vis.MermaidOmitted = true
+	}
+	return vis
+}
+
+// maxMermaidBytes returns the configured Mermaid size cap.
+func maxMermaidBytes() int {
+	if n := config.GetConfig().DecisionTraceMaxMermaidBytes; n > 0 {
+		return n
+	}
+	return defaultMaxMermaidBytes
+}
+
+// isConsecutiveDuplicateDTEvent reports whether an event with eventType and codeSHA,
+// arriving at now, repeats the session's previous dedup claim within window. A claim
+// stamped slightly after now is a concurrent copy and counts too. A zero window disables dedup.
+func isConsecutiveDuplicateDTEvent(prev *database.DTDedupClaim, eventType, codeSHA string, now time.Time, window time.Duration) bool {
+	if prev == nil || window <= 0 {
+		return false
+	}
+	if prev.EventType != eventType || prev.CodeSHA256 != codeSHA {
+		return false
+	}
+	return now.Sub(prev.At) < window
+}
+
+// missingDTEventFields returns the absent required fields of an event payload, in
+// request order, plus a field-keyed message for each so the frontend can show inline errors.
+// A codeText of only whitespace counts as missing.
+func missingDTEventFields(p DTEventPayload) ([]string, map[string]string) {
+	required := []struct {
+		name  string
+		value string
+	}{
+		{"contentId", p.ContentID},
+		{"contentType", p.ContentType},
+		{"language", p.Language},
+		{"eventType", p.EventType},
+	}
+
+	missing := make([]string, 0)
+	fields := make(map[string]string)
+	for _, f := range required {
+		if f.value == "" {
+			missing = append(missing, f.name)
+			fields[f.name] = "required"
+		}
+	}
+	if p.CodeText == "" {
+		missing = append(missing, "codeText")
+		fields["codeText"] = "required"
+	} else if strings.TrimSpace(p.CodeText) == "" {
+		missing = append(missing, "codeText")
+		fields["codeText"] = "must not be blank"
+	}
+	return missing, fields
+}
+
+// validateDTVisualization rejects unknown kinds and Mermaid text that is empty or
+// does not start with a recognizable diagram header. Size is handled in convertDTVisualization.
+func validateDTVisualization(p *DTVisualizationPayload) error {
+	if p == nil {
+		return nil
+	}
+	if p.Kind != nil && *p.Kind != mermaidKind {
+		return fmt.Errorf("invalid visualization.kind %q. Must be MERMAID or null", *p.Kind)
+	}
+	if p.MermaidText == nil {
+		return nil
+	}
+	if !hasMermaidHeader(*p.MermaidText) {
+		return fmt.Errorf("visualization.mermaidText must start with a Mermaid diagram declaration (e.g. \"graph TD\")")
+	}
+	return nil
+}
+
+// hasMermaidHeader reports whether the first meaningful line (skipping blank lines,
+// %% comments and a --- front-matter block) declares a known diagram type.
+func hasMermaidHeader(text string) bool {
+	inFrontMatter := false
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "---" {
+			inFrontMatter = !inFrontMatter
+			continue
+		}
+		if inFrontMatter || line == "" || strings.HasPrefix(line, "%%") {
+			continue
+		}
+		keyword := strings.Fields(line)[0]
+		for _, header := range mermaidDiagramHeaders {
+			if keyword == header {
+				return true
+			}
+		}
+		return false
+	}
+	return false
+}
+
+// codeLineCount counts lines the way an editor numbers them; a trailing newline
+// does not start a new line and empty code has none.
+func codeLineCount(code string) int {
+	if code == "" {
+		return 0
+	}
+	return strings.Count(strings.TrimSuffix(code, "\n"), "\n") + 1
+}
+
+// clampCitedLineRange fits a cited range to 1..lineCount, swapping inverted bounds.
+// ok is false when the range lies entirely outside the code.
+func clampCitedLineRange(start, end, lineCount int) (int, int, bool) {
+	if start > end {
+		start, end = end, start
+	}
+	if end < 1 || start > lineCount {
+		return 0, 0, false
+	}
+	if start < 1 {
+		start = 1
+	}
+	if end > lineCount {
+		end = lineCount
+	}
+	return start, end, true
 }
 
-func convertDTAI(p *DTAIPayload) database.DTEventAI {
+// convertDTAI maps the AI payload, validating cited line ranges against codeText:
+// out-of-bounds ranges are clamped or, if nothing is left, dropped and counted.
+func convertDTAI(p *DTAIPayload, codeText string) database.DTEventAI {
 	if p == nil {
 		return database.DTEventAI{}
 	}
@@ -205,11 +421,17 @@ func convertDTAI(p *DTAIPayload) database.DTEventAI {
 			NudgeType:     p.Gemini.NudgeType,
 			ResponseText:  p.Gemini.ResponseText,
 		}
+		lineCount := codeLineCount(codeText)
 		for _, lr := range p.Gemini.CitedLineRanges {
+			start, end, ok := clampCitedLineRange(lr.StartLine, lr.EndLine, lineCount)
+			if !ok {
+				ai.Gemini.CitedLineRangesDropped++
+				continue
+			}
 			ai.Gemini.CitedLineRanges = append(ai.Gemini.CitedLineRanges, database.DTEventCitedLineRange{
 				File:      lr.File,
-				StartLine: lr.StartLine,
-				EndLine:   lr.EndLine,
+				StartLine: start,
+				EndLine:   end,
 			})
 		}
 	}
@@ -227,10 +449,12 @@ func convertDTAI(p *DTAIPayload) database.DTEventAI {
 //  1. Authenticate via JWT
 //  2. Validate payload
 //  3. Get-or-create active session for (user, content, language)
-//  4. Check idempotency via browserSubmissionId
+//  4. Check idempotency via browserSubmissionId (and the optional dedup window)
 //  5. Insert event document
 //  6. Update session rolling fields
 //  7. If SUBMIT and all tests passed → end session
+//
+// Steps 3-7 live in recordDecisionTraceEvent.
 func CreateDecisionTraceEvent(c echo.Context) error {
 	// 1. Auth
 	claims, ok := GetUserClaims(c)
@@ -248,47 +472,99 @@ func CreateDecisionTraceEvent(c echo.Context) error {
 		})
 	}
 
-	if payload.ContentID == "" || payload.ContentType == "" || payload.Language == "" || payload.EventType == "" || payload.CodeText == "" {
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Missing required fields: contentId, contentType, language, eventType, codeText",
+	if missing, fields := missingDTEventFields(payload); len(fields) > 0 {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error":  "Missing required fields: " + strings.Join(missing, ", "),
+			"fields": fields,
 		})
 	}
 	if !validContentTypes[payload.ContentType] {
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Invalid contentType. Must be one of: project, problem, module_problem",
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error":  "Invalid contentType. Must be one of: project, problem, module_problem",
+			"fields": map[string]string{"contentType": "must be one of: project, problem, module_problem"},
 		})
 	}
 	if !validEventTypes[payload.EventType] {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error":  "Invalid eventType. Must be one of: RUN, SUBMIT",
+			"fields": map[string]string{"eventType": "must be one of: RUN, SUBMIT"},
+		})
+	}
+	if err := validateDTVisualization(payload.Visualization); err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Invalid eventType. Must be one of: RUN, SUBMIT",
+			"error": err.Error(),
 		})
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
 
-	userID := claims.UserID
+	outcome, err := recordDecisionTraceEvent(ctx, claims.UserID, payload, dtEventSettings{
+		Environment:     resolveAppEnvironment(),
+		DedupWindow:     time.Duration(config.GetConfig().DecisionTraceDedupWindowMs) * time.Millisecond,
+		MaxMermaidBytes: maxMermaidBytes(),
+	})
+	if err != nil {
+		c.Logger().Errorf("DecisionTrace: %v", err)
+		message := "Failed to save event"
+		if errors.Is(err, errDTSession) {
+			message = "Failed to get or create session"
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": message,
+		})
+	}
+
+	response := map[string]interface{}{
+		"eventId":   outcome.EventID.Hex(),
+		"sessionId": outcome.SessionID.Hex(),
+	}
+	switch {
+	case outcome.Duplicate:
+		response["duplicate"] = true
+		return c.JSON(http.StatusOK, response)
+	case outcome.Deduped:
+		response["deduped"] = true
+		return c.JSON(http.StatusOK, response)
+	}
+	return c.JSON(http.StatusCreated, response)
+}
 
+// dtEventSettings are the config-derived inputs to recordDecisionTraceEvent
+type dtEventSettings struct {
+	Environment     string
+	DedupWindow     time.Duration // 0 disables consecutive-duplicate dedup
+	MaxMermaidBytes int
+}
+
+// dtEventOutcome is the stored (or matched) event for a POST /decision-trace/event
+type dtEventOutcome struct {
+	EventID   primitive.ObjectID
+	SessionID primitive.ObjectID
+	Duplicate bool // browserSubmissionId already recorded
+	Deduped   bool // consecutive duplicate within the dedup window
+}
+
+// errDTSession marks failures to get or create the active session (vs. saving the event)
+var errDTSession = errors.New("failed to get/create session")
+
+// recordDecisionTraceEvent stores a validated event payload for userID: everything
+// CreateDecisionTraceEvent does after auth and validation.
+func recordDecisionTraceEvent(ctx context.Context, userID string, payload DTEventPayload, settings dtEventSettings) (*dtEventOutcome, error) {
 	// 3. Get or create active session
 	session, _, err := database.AppCollections.DecisionTraceSessions.GetOrCreateActiveSession(
-		ctx, userID, payload.ContentID, payload.ContentType, payload.Language,
+		ctx, userID, payload.ContentID, payload.ContentType, payload.Language, settings.Environment,
 	)
 	if err != nil {
-		c.Logger().Errorf("DecisionTrace: failed to get/create session: %v", err)
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to get or create session",
-		})
+		return nil, fmt.Errorf("%w: %v", errDTSession, err)
 	}
 
 	// 4. Idempotency: check if browserSubmissionId already exists
-	if payload.BrowserSubmissionID != nil && *payload.BrowserSubmissionID != "" {
+	hasSubmissionID := payload.BrowserSubmissionID != nil && *payload.BrowserSubmissionID != ""
+	if hasSubmissionID {
 		existing, err := database.AppCollections.DecisionTraceEvents.FindEventByBrowserSubmissionID(ctx, *payload.BrowserSubmissionID)
 		if err == nil && existing != nil {
-			return c.JSON(http.StatusOK, map[string]interface{}{
-				"eventId":   existing.ID.Hex(),
-				"sessionId": existing.SessionID.Hex(),
-				"duplicate": true,
-			})
+			return &dtEventOutcome{EventID: existing.ID, SessionID: existing.SessionID, Duplicate: true}, nil
 		}
 		// If mongo.ErrNoDocuments, proceed with insertion
 	}
@@ -297,8 +573,28 @@ func CreateDecisionTraceEvent(c echo.Context) error {
 	now := time.Now()
 	hash := sha256.Sum256([]byte(payload.CodeText))
 	codeSHA := fmt.Sprintf("%x", hash)
+	eventID := primitive.NewObjectID()
+
+	// Optional dedup of flaky double-fires that carry no browserSubmissionId: same type
+	// and code as the session's previous event within the window. Every event claims the
+	// session's dedup slot atomically, so two concurrent copies can't both get through.
+	claimed := false
+	if settings.DedupWindow > 0 {
+		claim := database.DTDedupClaim{EventID: eventID, EventType: payload.EventType, CodeSHA256: codeSHA, At: now}
+		prev, err := database.AppCollections.DecisionTraceSessions.ClaimDedupSlot(ctx, session, claim, func(prev *database.DTDedupClaim) bool {
+			return !hasSubmissionID && isConsecutiveDuplicateDTEvent(prev, payload.EventType, codeSHA, now, settings.DedupWindow)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if prev != nil {
+			return &dtEventOutcome{EventID: prev.EventID, SessionID: session.ID, Deduped: true}, nil
+		}
+		claimed = true
+	}
 
 	event := database.DecisionTraceEventDocument{
+		ID:                  eventID,
 		SchemaVersion:       1,
 		SessionID:           session.ID,
 		UserID:              userID,
@@ -313,48 +609,42 @@ func CreateDecisionTraceEvent(c echo.Context) error {
 			SHA256: codeSHA,
 		},
 		Execution:     convertDTExecution(payload.Execution),
-		Visualization: convertDTVisualization(payload.Visualization),
-		AI:            convertDTAI(payload.AI),
+		Visualization: convertDTVisualization(payload.Visualization, settings.MaxMermaidBytes),
+		AI:            convertDTAI(payload.AI, payload.CodeText),
 	}
 
 	// 6. Insert event
-	eventID, err := database.AppCollections.DecisionTraceEvents.InsertEvent(ctx, &event)
-	if err != nil {
+	if _, err := database.AppCollections.DecisionTraceEvents.InsertEvent(ctx, &event); err != nil {
+		if claimed {
+			if releaseErr := database.AppCollections.DecisionTraceSessions.ReleaseDedupClaim(ctx, session.ID, eventID); releaseErr != nil {
+				log.Printf("DecisionTrace: failed to release dedup claim: %v", releaseErr)
+			}
+		}
 		// Handle duplicate key on browserSubmissionId (race condition)
 		if mongo.IsDuplicateKeyError(err) && payload.BrowserSubmissionID != nil {
 			existing, findErr := database.AppCollections.DecisionTraceEvents.FindEventByBrowserSubmissionID(ctx, *payload.BrowserSubmissionID)
 			if findErr == nil && existing != nil {
-				return c.JSON(http.StatusOK, map[string]interface{}{
-					"eventId":   existing.ID.Hex(),
-					"sessionId": existing.SessionID.Hex(),
-					"duplicate": true,
-				})
+				return &dtEventOutcome{EventID: existing.ID, SessionID: existing.SessionID, Duplicate: true}, nil
 			}
 		}
-		c.Logger().Errorf("DecisionTrace: failed to insert event: %v", err)
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to save event",
-		})
+		return nil, fmt.Errorf("failed to insert event: %w", err)
 	}
 
 	// 7. Update session rolling fields (best-effort; don't fail the request)
 	if updateErr := database.AppCollections.DecisionTraceSessions.UpdateSessionRollingFields(
 		ctx, session.ID, eventID, now, payload.BrowserSubmissionID,
 	); updateErr != nil {
-		c.Logger().Errorf("DecisionTrace: failed to update session rolling fields: %v", updateErr)
+		log.Printf("DecisionTrace: failed to update session rolling fields: %v", updateErr)
 	}
 
 	// 8. If SUBMIT and all tests passed → end session
 	if payload.EventType == "SUBMIT" && allTestsPassed(payload.Execution) {
 		if endErr := database.AppCollections.DecisionTraceSessions.EndSession(ctx, session.ID); endErr != nil {
-			c.Logger().Errorf("DecisionTrace: failed to end session: %v", endErr)
+			log.Printf("DecisionTrace: failed to end session: %v", endErr)
 		}
 	}
 
-	return c.JSON(http.StatusCreated, map[string]interface{}{
-		"eventId":   eventID.Hex(),
-		"sessionId": session.ID.Hex(),
-	})
+	return &dtEventOutcome{EventID: eventID, SessionID: session.ID}, nil
 }
 
 // ============================================================
@@ -380,20 +670,17 @@ func GetDecisionTraceSession(c echo.Context) error {
 	}
 
 	// Determine which user's session to look up
-	targetUserID := claims.UserID
-	if qUserID := c.QueryParam("userId"); qUserID != "" {
-		if !isAdminClaims(claims) {
-			return c.JSON(http.StatusForbidden, map[string]string{
-				"error": "Only admins can view other users' sessions",
-			})
-		}
-		targetUserID = qUserID
+	targetUserID, ok := targetUserIDParam(c, claims)
+	if !ok {
+		return c.JSON(http.StatusForbidden, map[string]string{
+			"error": "Only admins can view other users' sessions",
+		})
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	session, err := database.AppCollections.DecisionTraceSessions.FindActiveSession(ctx, targetUserID, contentID, contentType)
+	session, err := database.AppCollections.DecisionTraceSessions.FindActiveSession(ctx, targetUserID, contentID, contentType, "", resolveAppEnvironment())
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return c.JSON(http.StatusOK, map[string]interface{}{
@@ -411,6 +698,73 @@ func GetDecisionTraceSession(c echo.Context) error {
 	})
 }
 
+// ============================================================
+// Handler: GET /decision-trace/latest-event
+// ============================================================
+
+// GetDecisionTraceLatestEvent returns the full most recent event of the caller's active
+// session for a content item, so the editor can be restored in one round trip.
+// Query params: contentId, contentType, language (optional)
+func GetDecisionTraceLatestEvent(c echo.Context) error {
+	claims, ok := GetUserClaims(c)
+	if !ok || claims.UserID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized: Valid JWT required",
+		})
+	}
+
+	contentID := c.QueryParam("contentId")
+	contentType := c.QueryParam("contentType")
+	if contentID == "" || contentType == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Missing required query params: contentId, contentType",
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	session, err := database.AppCollections.DecisionTraceSessions.FindActiveSession(ctx, claims.UserID, contentID, contentType, c.QueryParam("language"), resolveAppEnvironment())
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return c.JSON(http.StatusOK, map[string]interface{}{
+				"sessionId": nil,
+				"event":     nil,
+			})
+		}
+		c.Logger().Errorf("DecisionTrace: failed to find session: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to find session",
+		})
+	}
+
+	if session.LastEventID == nil {
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"sessionId": session.ID.Hex(),
+			"event":     nil,
+		})
+	}
+
+	event, err := database.AppCollections.DecisionTraceEvents.FindEventByID(ctx, *session.LastEventID)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return c.JSON(http.StatusOK, map[string]interface{}{
+				"sessionId": session.ID.Hex(),
+				"event":     nil,
+			})
+		}
+		c.Logger().Errorf("DecisionTrace: failed to load latest event: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to load event",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"sessionId": session.ID.Hex(),
+		"event":     event,
+	})
+}
+
 // ============================================================
 // Handler: GET /decision-trace/timeline
 // ============================================================
@@ -456,7 +810,7 @@ func GetDecisionTraceTimeline(c echo.Context) error {
 		})
 	}
 
-	if session.UserID != claims.UserID && !isAdminClaims(claims) {
+	if !canAccessUserData(claims, session.UserID) {
 		return c.JSON(http.StatusForbidden, map[string]string{
 			"error": "Access denied",
 		})
@@ -477,6 +831,133 @@ func GetDecisionTraceTimeline(c echo.Context) error {
 	})
 }
 
+// ============================================================
+// Handler: GET /decision-trace/session/:id/churn
+// ============================================================
+
+// GetDecisionTraceSessionChurn returns session-level code churn: lines added/removed
+// between consecutive Run/Submit snapshots and a thrash score (churn / net change).
+func GetDecisionTraceSessionChurn(c echo.Context) error {
+	claims, ok := GetUserClaims(c)
+	if !ok || claims.UserID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized: Valid JWT required",
+		})
+	}
+
+	sessionID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid session id format",
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Verify ownership (unless admin)
+	session, err := database.AppCollections.DecisionTraceSessions.FindSessionByID(ctx, sessionID)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": "Session not found",
+			})
+		}
+		c.Logger().Errorf("DecisionTrace: failed to find session for churn: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to load session",
+		})
+	}
+
+	if !canAccessUserData(claims, session.UserID) {
+		return c.JSON(http.StatusForbidden, map[string]string{
+			"error": "Access denied",
+		})
+	}
+
+	churn, err := database.AppCollections.DecisionTraceEvents.GetSessionChurn(ctx, sessionID)
+	if err != nil {
+		c.Logger().Errorf("DecisionTrace: failed to compute churn: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to compute session churn",
+		})
+	}
+
+	return c.JSON(http.StatusOK, churn)
+}
+
+// ============================================================
+// Handler: GET /decision-trace/code-evolution
+// ============================================================
+
+// Code evolution limits: newest snapshots kept per request
+const (
+	defaultCodeEvolutionLimit = 200
+	maxCodeEvolutionLimit     = 1000
+)
+
+// GetDecisionTraceCodeEvolution returns a user's code snapshots for one content item
+// across all sessions, oldest first, for before/after progressions.
+// Query params: contentId, contentType, includeText (optional), limit (optional), userId (admin only)
+func GetDecisionTraceCodeEvolution(c echo.Context) error {
+	claims, ok := GetUserClaims(c)
+	if !ok || claims.UserID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized: Valid JWT required",
+		})
+	}
+
+	contentID := c.QueryParam("contentId")
+	contentType := c.QueryParam("contentType")
+	if contentID == "" || contentType == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Missing required query params: contentId, contentType",
+		})
+	}
+	if !validContentTypes[contentType] {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid contentType. Must be one of: project, problem, module_problem",
+		})
+	}
+
+	limit := defaultCodeEvolutionLimit
+	if raw := c.QueryParam("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "limit must be a positive integer",
+			})
+		}
+		limit = parsed
+	}
+	if limit > maxCodeEvolutionLimit {
+		limit = maxCodeEvolutionLimit
+	}
+
+	// Determine whose history to load
+	targetUserID, ok := targetUserIDParam(c, claims)
+	if !ok {
+		return c.JSON(http.StatusForbidden, map[string]string{
+			"error": "Only admins can view other users' code history",
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	evolution, err := database.AppCollections.DecisionTraceEvents.GetCodeEvolution(
+		ctx, targetUserID, contentID, contentType, int64(limit), c.QueryParam("includeText") == "true",
+	)
+	if err != nil {
+		c.Logger().Errorf("DecisionTrace: failed to load code evolution: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to load code evolution",
+		})
+	}
+
+	return c.JSON(http.StatusOK, evolution)
+}
+
 // ============================================================
 // Handler: GET /decision-trace/event
 // ============================================================
@@ -522,7 +1003,7 @@ func GetDecisionTraceEvent(c echo.Context) error {
 	}
 
 	// Verify ownership (unless admin)
-	if event.UserID != claims.UserID && !isAdminClaims(claims) {
+	if !canAccessUserData(claims, event.UserID) {
 		return c.JSON(http.StatusForbidden, map[string]string{
 			"error": "Access denied",
 		})
@@ -532,3 +1013,42 @@ func GetDecisionTraceEvent(c echo.Context) error {
 		"event": event,
 	})
 }
+
+// ============================================================
+// Handler: GET /admin/decision-trace/recent
+// ============================================================
+
+// recentDTEventLimits bounds the admin live feed page size
+var recentDTEventLimits = paginationLimits{Default: 50, Max: 200}
+
+// GetRecentDecisionTraceEvents returns the newest Run/Submit events across all
+// students for live class monitoring (admin only).
+// Query params: limit (default 50, max 200), offset or page, contentId (optional), include_internal
+func GetRecentDecisionTraceEvents(c echo.Context) error {
+	pagination := parsePagination(c, recentDTEventLimits)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), DefaultQueryTimeout)
+	defer cancel()
+
+	// Exclude internal users unless requested
+	var excludedSupabaseUserIDs []string
+	if c.QueryParam("include_internal") != "true" {
+		var err error
+		excludedSupabaseUserIDs, err = GetInternalSupabaseIDs(ctx, []string{"linkedinorleftout.com"}, nil)
+		if err != nil {
+			c.Logger().Errorf("Failed to get internal user IDs: %v", err)
+		}
+	}
+
+	events, err := database.AppCollections.DecisionTraceEvents.GetRecentEvents(ctx, c.QueryParam("contentId"), excludedSupabaseUserIDs, int64(pagination.Offset), int64(pagination.Limit))
+	if err != nil {
+		c.Logger().Errorf("DecisionTrace: failed to get recent events: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to load recent events",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"events": events,
+	})
+}
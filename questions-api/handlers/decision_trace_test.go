@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gerdinv/questions-api/database"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestValidateDTVisualization(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload *DTVisualizationPayload
+		wantErr bool
+	}{
+		{"nil visualization", nil, false},
+		{"no mermaid text", &DTVisualizationPayload{Kind: strPtr(mermaidKind)}, false},
+		{"valid flowchart", &DTVisualizationPayload{Kind: strPtr(mermaidKind), MermaidText: strPtr("graph TD\n  A-->B")}, false},
+		{"valid with null kind", &DTVisualizationPayload{MermaidText: strPtr("sequenceDiagram\n  A->>B: hi")}, false},
+		{"valid after comments and front matter", &DTVisualizationPayload{MermaidText: strPtr("---\ntitle: x\n---\n%% note\n\nstateDiagram-v2\n  [*] --> S")}, false},
+		{"unknown kind", &DTVisualizationPayload{Kind: strPtr("PLANTUML"), MermaidText: strPtr("graph TD")}, true},
+		{"malformed missing header", &DTVisualizationPayload{MermaidText: strPtr("A-->B")}, true},
+		{"malformed unknown diagram", &DTVisualizationPayload{MermaidText: strPtr("graphs TD\n  A-->B")}, true},
+		{"malformed empty text", &DTVisualizationPayload{MermaidText: strPtr("")}, true},
+		{"malformed only comments", &DTVisualizationPayload{MermaidText: strPtr("%% nothing here\n")}, true},
+		{"malformed unterminated front matter", &DTVisualizationPayload{MermaidText: strPtr("---\ngraph TD")}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateDTVisualization(tt.payload)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateDTVisualization() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConvertDTVisualizationSizeCap(t *testing.T) {
+	const maxBytes = 32
+	atCap := "graph TD\n" + strings.Repeat("x", maxBytes-len("graph TD\n"))
+	overCap := atCap + "x"
+
+	tests := []struct {
+		name        string
+		text        string
+		wantOmitted bool
+	}{
+		{"under cap", "graph TD\n  A-->B", false},
+		{"exactly at cap", atCap, false},
+		{"oversized", overCap, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vis := convertDTVisualization(&DTVisualizationPayload{Kind: strPtr(mermaidKind), MermaidText: strPtr(tt.text)}, maxBytes)
+			if vis.MermaidOmitted != tt.wantOmitted {
+				t.Errorf("MermaidOmitted = %v, want %v", vis.MermaidOmitted, tt.wantOmitted)
+			}
+			if tt.wantOmitted && vis.MermaidText != nil {
+				t.Errorf("oversized mermaidText was stored (%d bytes)", len(*vis.MermaidText))
+			}
+			if !tt.wantOmitted && (vis.MermaidText == nil || *vis.MermaidText != tt.text) {
+				t.Errorf("mermaidText was not stored unchanged")
+			}
+		})
+	}
+}
+
+func TestIsConsecutiveDuplicateDTEventWindow(t *testing.T) {
+	const window = 2 * time.Second
+	base := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	prev := &database.DTDedupClaim{EventType: "RUN", CodeSHA256: "abc", At: base}
+
+	tests := []struct {
+		name      string
+		prev      *database.DTDedupClaim
+		eventType string
+		codeSHA   string
+		now       time.Time
+		window    time.Duration
+		want      bool
+	}{
+		{"same instant", prev, "RUN", "abc", base, window, true},
+		{"just inside window", prev, "RUN", "abc", base.Add(window - time.Millisecond), window, true},
+		{"exactly at window", prev, "RUN", "abc", base.Add(window), window, false},
+		{"just outside window", prev, "RUN", "abc", base.Add(window + time.Millisecond), window, false},
+		{"concurrent copy stamped after now", prev, "RUN", "abc", base.Add(-time.Millisecond), window, true},
+		{"different event type", prev, "SUBMIT", "abc", base.Add(time.Millisecond), window, false},
+		{"different code", prev, "RUN", "def", base.Add(time.Millisecond), window, false},
+		{"no previous event", nil, "RUN", "abc", base, window, false},
+		{"dedup disabled", prev, "RUN", "abc", base, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isConsecutiveDuplicateDTEvent(tt.prev, tt.eventType, tt.codeSHA, tt.now, tt.window); got != tt.want {
+				t.Errorf("isConsecutiveDuplicateDTEvent() at +%v = %v, want %v", tt.now.Sub(base), got, tt.want)
+			}
+		})
+	}
+}
+
+// TestRecordDecisionTraceEventConcurrentDoubleFire needs a real MongoDB; set MONGO_TEST_URI to run it.
+func TestRecordDecisionTraceEventConcurrentDoubleFire(t *testing.T) {
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGO_TEST_URI not set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer client.Disconnect(context.Background())
+
+	db := client.Database(fmt.Sprintf("decision_trace_test_%d", time.Now().UnixNano()))
+	defer func() { _ = db.Drop(context.Background()) }()
+
+	prevCollections := database.AppCollections
+	database.AppCollections = database.NewAppDBCollections(db)
+	defer func() { database.AppCollections = prevCollections }()
+	if err := database.AppCollections.DecisionTraceSessions.EnsureIndexes(ctx); err != nil {
+		t.Fatalf("session indexes: %v", err)
+	}
+
+	payload := DTEventPayload{
+		ContentID:   "7",
+		ContentType: "project",
+		Language:    "python",
+		EventType:   "RUN",
+		CodeText:    "print('hi')",
+	}
+	settings := dtEventSettings{Environment: "development", DedupWindow: 5 * time.Second, MaxMermaidBytes: defaultMaxMermaidBytes}
+
+	// Create the session up front so every copy races on the same document
+	if _, err := recordDecisionTraceEvent(ctx, "user-1", DTEventPayload{
+		ContentID: payload.ContentID, ContentType: payload.ContentType, Language: payload.Language,
+		EventType: "SUBMIT", CodeText: "pass",
+	}, settings); err != nil {
+		t.Fatalf("seed event: %v", err)
+	}
+
+	const copies = 8
+	var wg sync.WaitGroup
+	outcomes := make(chan *dtEventOutcome, copies)
+	errs := make(chan error, copies)
+	for i := 0; i < copies; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			outcome, err := recordDecisionTraceEvent(ctx, "user-1", payload, settings)
+			if err != nil {
+				errs <- err
+				return
+			}
+			outcomes <- outcome
+		}()
+	}
+	wg.Wait()
+	close(outcomes)
+	close(errs)
+	for err := range errs {
+		t.Fatalf("recordDecisionTraceEvent: %v", err)
+	}
+
+	inserted, deduped := 0, 0
+	eventIDs := make(map[primitive.ObjectID]bool)
+	for outcome := range outcomes {
+		eventIDs[outcome.EventID] = true
+		if outcome.Deduped {
+			deduped++
+		} else {
+			inserted++
+		}
+	}
+	if inserted != 1 || deduped != copies-1 {
+		t.Errorf("inserted %d, deduped %d; want 1 and %d", inserted, deduped, copies-1)
+	}
+	if len(eventIDs) != 1 {
+		t.Errorf("copies returned %d different event ids, want 1", len(eventIDs))
+	}
+
+	count, err := db.Collection("decision_trace_events").CountDocuments(ctx, bson.M{"eventType": "RUN"})
+	if err != nil {
+		t.Fatalf("count: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("stored %d RUN events, want 1", count)
+	}
+}
+
+func strPtr(s string) *string { return &s }
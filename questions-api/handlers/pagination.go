@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gerdinv/questions-api/config"
+	"github.com/labstack/echo/v4"
+)
+
+// Built-in page sizes used when PAGINATION_DEFAULT_LIMIT / PAGINATION_MAX_LIMIT are unset
+const (
+	fallbackPageLimit    = 20
+	fallbackMaxPageLimit = 100
+)
+
+// paginationLimits is the default and maximum page size for one endpoint
+type paginationLimits struct {
+	Default int
+	Max     int
+}
+
+// Pagination is a parsed, clamped page window. Page is 1-based and derived from
+// Offset when the caller used offset= instead of page=.
+type Pagination struct {
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+	Page   int `json:"page"`
+}
+
+// HasMore reports whether rows remain after this page out of total
+func (p Pagination) HasMore(total int64) bool {
+	return int64(p.Offset+p.Limit) < total
+}
+
+// defaultPaginationLimits returns the API-wide page sizes from config
+// (PAGINATION_DEFAULT_LIMIT, PAGINATION_MAX_LIMIT), falling back to 20 / 100.
+func defaultPaginationLimits() paginationLimits {
+	cfg := config.GetConfig()
+	limits := paginationLimits{Default: cfg.PaginationDefaultLimit, Max: cfg.PaginationMaxLimit}
+	if limits.Max <= 0 {
+		limits.Max = fallbackMaxPageLimit
+	}
+	if limits.Default <= 0 {
+		limits.Default = fallbackPageLimit
+	}
+	if limits.Default > limits.Max {
+		limits.Default = limits.Max
+	}
+	return limits
+}
+
+// parsePagination reads limit, offset and page query params. A missing limit uses
+// limits.Default and larger ones are clamped to limits.Max; offset wins over page.
+// Like the endpoints did before pagination was centralized, non-numeric or
+// out-of-range values fall back to the defaults (first page) rather than failing.
+func parsePagination(c echo.Context, limits paginationLimits) Pagination {
+	p := Pagination{Limit: limits.Default, Page: 1}
+
+	if limit, err := strconv.Atoi(c.QueryParam("limit")); err == nil && limit >= 1 {
+		p.Limit = limit
+	}
+	if p.Limit > limits.Max {
+		p.Limit = limits.Max
+	}
+
+	if raw := c.QueryParam("offset"); raw != "" {
+		if offset, err := strconv.Atoi(raw); err == nil && offset >= 0 {
+			p.Offset = offset
+			p.Page = offset/p.Limit + 1
+		}
+	} else if page, err := strconv.Atoi(c.QueryParam("page")); err == nil && page >= 1 {
+		p.Page = page
+		p.Offset = (page - 1) * p.Limit
+	}
+	return p
+}
+
+// hasPaginationParams reports whether the caller asked for a window explicitly
+func hasPaginationParams(c echo.Context) bool {
+	return c.QueryParam("limit") != "" || c.QueryParam("offset") != "" || c.QueryParam("page") != ""
+}
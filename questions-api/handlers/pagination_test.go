@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestParsePagination(t *testing.T) {
+	limits := paginationLimits{Default: 20, Max: 100}
+
+	tests := []struct {
+		name  string
+		query string
+		want  Pagination
+	}{
+		{"missing uses default", "", Pagination{Limit: 20, Offset: 0, Page: 1}},
+		{"valid limit", "limit=50", Pagination{Limit: 50, Offset: 0, Page: 1}},
+		{"above max is clamped", "limit=500", Pagination{Limit: 100, Offset: 0, Page: 1}},
+		{"non-numeric limit uses default", "limit=abc", Pagination{Limit: 20, Offset: 0, Page: 1}},
+		{"zero limit uses default", "limit=0", Pagination{Limit: 20, Offset: 0, Page: 1}},
+		{"negative limit uses default", "limit=-5", Pagination{Limit: 20, Offset: 0, Page: 1}},
+		{"page", "limit=10&page=3", Pagination{Limit: 10, Offset: 20, Page: 3}},
+		{"offset", "limit=10&offset=25", Pagination{Limit: 10, Offset: 25, Page: 3}},
+		{"offset wins over page", "limit=10&offset=5&page=4", Pagination{Limit: 10, Offset: 5, Page: 1}},
+		{"bad page uses first page", "page=abc", Pagination{Limit: 20, Offset: 0, Page: 1}},
+		{"zero page uses first page", "page=0", Pagination{Limit: 20, Offset: 0, Page: 1}},
+		{"negative offset uses first page", "offset=-1", Pagination{Limit: 20, Offset: 0, Page: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/?"+tt.query, nil)
+			c := echo.New().NewContext(req, httptest.NewRecorder())
+
+			if got := parsePagination(c, limits); got != tt.want {
+				t.Errorf("parsePagination(%q) = %+v, want %+v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gerdinv/questions-api/database"
+	"github.com/gerdinv/questions-api/shared"
+	"github.com/labstack/echo/v4"
+)
+
+// maxProjectRecommendations caps how many next-project suggestions are returned
+const maxProjectRecommendations = 3
+
+var (
+	// Project catalog cache shared by recommendation requests; the catalog changes rarely
+	projectCatalogCache      []shared.ProjectDocument
+	projectCatalogExpiresAt  time.Time
+	projectCatalogCacheMutex sync.RWMutex
+	projectCatalogDuration   = 5 * time.Minute
+)
+
+// difficultyRank orders difficulties for the "equal or one step harder" heuristic
+var difficultyRank = map[shared.DifficultyType]int{
+	shared.DifficultyEasy:   0,
+	shared.DifficultyMedium: 1,
+	shared.DifficultyHard:   2,
+}
+
+// ProjectRecommendation is one suggested next project
+type ProjectRecommendation struct {
+	ProjectNumber int                   `json:"projectNumber"`
+	Title         string                `json:"title"`
+	Difficulty    shared.DifficultyType `json:"difficulty"`
+	Category      string                `json:"category"`
+	Reason        string                `json:"reason"` // "same_category" | "next_in_sequence"
+}
+
+// getProjectCatalog returns all projects sorted by projectNumber, cached for projectCatalogDuration
+func getProjectCatalog(ctx context.Context) ([]shared.ProjectDocument, error) {
+	projectCatalogCacheMutex.RLock()
+	if projectCatalogCache != nil && time.Now().Before(projectCatalogExpiresAt) {
+		defer projectCatalogCacheMutex.RUnlock()
+		return projectCatalogCache, nil
+	}
+	projectCatalogCacheMutex.RUnlock()
+
+	projectCatalogCacheMutex.Lock()
+	defer projectCatalogCacheMutex.Unlock()
+
+	// Double check
+	if projectCatalogCache != nil && time.Now().Before(projectCatalogExpiresAt) {
+		return projectCatalogCache, nil
+	}
+
+	projects, err := database.ContentCollections.Projects.GetAllProjects(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(projects, func(i, j int) bool {
+		return projects[i].ProjectNumber < projects[j].ProjectNumber
+	})
+
+	projectCatalogCache = projects
+	projectCatalogExpiresAt = time.Now().Add(projectCatalogDuration)
+	return projects, nil
+}
+
+// invalidateProjectCatalog drops the cached catalog (and the shared project title
+// cache) after any project content change
+func invalidateProjectCatalog() {
+	database.InvalidateProjectTitleCache()
+
+	projectCatalogCacheMutex.Lock()
+	defer projectCatalogCacheMutex.Unlock()
+	projectCatalogCache = nil
+}
+
+// recommendNextProjects picks up to limit uncompleted projects after current.
+// First choice: same category at equal or one-step-higher difficulty (closest
+// difficulty, then lowest projectNumber after current). Remaining slots are
+// filled with the next uncompleted projects by projectNumber, wrapping around.
+func recommendNextProjects(current shared.ProjectDocument, catalog []shared.ProjectDocument, completed map[int]bool, limit int) []ProjectRecommendation {
+	currentRank := difficultyRank[current.Difficulty]
+	picked := make(map[int]bool)
+	recommendations := make([]ProjectRecommendation, 0, limit)
+
+	add := func(p shared.ProjectDocument, reason string) {
+		picked[p.ProjectNumber] = true
+		recommendations = append(recommendations, ProjectRecommendation{
+			ProjectNumber: p.ProjectNumber,
+			Title:         p.Title,
+			Difficulty:    p.Difficulty,
+			Category:      p.Category,
+			Reason:        reason,
+		})
+	}
+	eligible := func(p shared.ProjectDocument) bool {
+		return p.ProjectNumber != current.ProjectNumber && !completed[p.ProjectNumber] && !picked[p.ProjectNumber]
+	}
+	// Projects after current come first, then earlier ones (catalog is sorted)
+	afterCurrent := func(p shared.ProjectDocument) bool {
+		return p.ProjectNumber > current.ProjectNumber
+	}
+
+	sameCategory := make([]shared.ProjectDocument, 0)
+	for _, p := range catalog {
+		if !eligible(p) || current.Category == "" || p.Category != current.Category {
+			continue
+		}
+		step := difficultyRank[p.Difficulty] - currentRank
+		if step == 0 || step == 1 {
+			sameCategory = append(sameCategory, p)
+		}
+	}
+	sort.SliceStable(sameCategory, func(i, j int) bool {
+		si := difficultyRank[sameCategory[i].Difficulty]
+		sj := difficultyRank[sameCategory[j].Difficulty]
+		if si != sj {
+			return si < sj
+		}
+		return afterCurrent(sameCategory[i]) && !afterCurrent(sameCategory[j])
+	})
+	for _, p := range sameCategory {
+		if len(recommendations) >= limit {
+			return recommendations
+		}
+		add(p, "same_category")
+	}
+
+	for _, wrap := range []bool{false, true} {
+		for _, p := range catalog {
+			if len(recommendations) >= limit {
+				return recommendations
+			}
+			if afterCurrent(p) == wrap || !eligible(p) {
+				continue
+			}
+			add(p, "next_in_sequence")
+		}
+	}
+	return recommendations
+}
+
+// GetNextRecommendedProjects handles GET /projects/:id/next-recommended
+// Returns an ordered list of uncompleted projects to try after :id
+func GetNextRecommendedProjects(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Request().Context(), DefaultQueryTimeout)
+	defer cancel()
+
+	projectNumber, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid project ID",
+		})
+	}
+
+	user, ok := GetUserClaims(c)
+	if !ok || user.UserID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	catalog, err := getProjectCatalog(ctx)
+	if err != nil {
+		c.Logger().Errorf("Failed to load project catalog: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to fetch projects",
+		})
+	}
+
+	var current *shared.ProjectDocument
+	for i := range catalog {
+		if catalog[i].ProjectNumber == projectNumber {
+			current = &catalog[i]
+			break
+		}
+	}
+	if current == nil {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "Project not found",
+		})
+	}
+
+	completedIDs, err := database.GetCompletedProjectIDsByUser(ctx, user.UserID)
+	if err != nil {
+		c.Logger().Errorf("Failed to get completed projects for %s: %v", user.UserID, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to fetch completed projects",
+		})
+	}
+	completed := make(map[int]bool, len(completedIDs))
+	for _, id := range completedIDs {
+		if n, err := strconv.Atoi(id); err == nil {
+			completed[n] = true
+		}
+	}
+
+	recommendations := recommendNextProjects(*current, catalog, completed, maxProjectRecommendations)
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"projectNumber":   projectNumber,
+		"difficulty":      current.Difficulty,
+		"recommendations": recommendations,
+		"allCompleted":    len(recommendations) == 0,
+	})
+}
@@ -2,10 +2,13 @@ package handlers
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gerdinv/questions-api/config"
@@ -28,6 +31,74 @@ type ProjectListItem struct {
 	TotalTests    int                   `json:"totalTests"`
 	PassedTests   int                   `json:"passedTests"`
 	IsCompleted   bool                  `json:"isCompleted"`
+	AttemptCount  int                   `json:"attemptCount"` // User's total submissions for this project (0 when unauthenticated)
+	// Admin view only: median failed submissions before first pass, over users who passed
+	MedianAttemptsBeforePass *float64 `json:"medianAttemptsBeforePass,omitempty"`
+	// Admin view only: median decision-trace runs before the first fully passing submit, over users who got one
+	MedianRunsBeforeFirstPass *float64 `json:"medianRunsBeforeFirstPass,omitempty"`
+}
+
+const (
+	// projectMediansWindow bounds how far back the admin difficulty medians read submissions and events
+	projectMediansWindow = 90 * 24 * time.Hour
+	// projectMediansTimeout is each difficulty aggregation's own budget
+	projectMediansTimeout = 2 * DefaultQueryTimeout
+	// projectMediansCacheDuration is how long computed medians are served before recomputing
+	projectMediansCacheDuration = 10 * time.Minute
+)
+
+// projectMediansCache holds one per-project median map keyed by include_internal
+type projectMediansCache struct {
+	mu        sync.Mutex
+	medians   map[bool]map[string]float64
+	expiresAt map[bool]time.Time
+}
+
+func newProjectMediansCache() *projectMediansCache {
+	return &projectMediansCache{medians: make(map[bool]map[string]float64), expiresAt: make(map[bool]time.Time)}
+}
+
+// get returns the cached medians for includeInternal, or runs compute over the last
+// projectMediansWindow with its own timeout and caches the result. Errors are not cached.
+func (pc *projectMediansCache) get(ctx context.Context, includeInternal bool, compute func(ctx context.Context, since time.Time) (map[string]float64, error)) (map[string]float64, error) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	if medians, ok := pc.medians[includeInternal]; ok && time.Now().Before(pc.expiresAt[includeInternal]) {
+		return medians, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, projectMediansTimeout)
+	defer cancel()
+
+	medians, err := compute(ctx, time.Now().Add(-projectMediansWindow))
+	if err != nil {
+		return nil, err
+	}
+	pc.medians[includeInternal] = medians
+	pc.expiresAt[includeInternal] = time.Now().Add(projectMediansCacheDuration)
+	return medians, nil
+}
+
+var (
+	medianAttemptsCache = newProjectMediansCache()
+	medianRunsCache     = newProjectMediansCache()
+)
+
+// getMedianAttemptsBeforePass returns cached attempts-before-pass medians, reading
+// browser_submissions on the analytics connection on a miss
+func getMedianAttemptsBeforePass(ctx context.Context, includeInternal bool, excludedSupabaseUserIDs []string) (map[string]float64, error) {
+	return medianAttemptsCache.get(ctx, includeInternal, func(ctx context.Context, since time.Time) (map[string]float64, error) {
+		return database.GetMedianAttemptsBeforePassByProject(ctx, since, excludedSupabaseUserIDs)
+	})
+}
+
+// getMedianRunsBeforeFirstPass returns cached runs-before-first-pass medians, reading
+// decision-trace events on the analytics connection on a miss
+func getMedianRunsBeforeFirstPass(ctx context.Context, includeInternal bool, excludedSupabaseUserIDs []string) (map[string]float64, error) {
+	return medianRunsCache.get(ctx, includeInternal, func(ctx context.Context, since time.Time) (map[string]float64, error) {
+		return database.GetAnalyticsDecisionTraceEventsCollection().GetMedianRunsBeforeFirstPassByProject(ctx, since, excludedSupabaseUserIDs)
+	})
 }
 
 type ProjectDetail struct {
@@ -51,10 +122,16 @@ type ProjectLimits struct {
 
 // GetProjects returns all projects with user progress if authenticated
 func GetProjects(c echo.Context) error {
-	c.Response().Header().Set(
-		"Cache-Control",
-		"public, max-age=300, stale-while-revalidate=86400",
-	)
+	// The admin listing carries class-wide stats and must not be cached publicly
+	adminView := strings.HasPrefix(c.Path(), "/admin/")
+	if adminView {
+		c.Response().Header().Set("Cache-Control", "private, no-store")
+	} else {
+		c.Response().Header().Set(
+			"Cache-Control",
+			"public, max-age=300, stale-while-revalidate=86400",
+		)
+	}
 
 	cfg := config.GetConfig()
 
@@ -85,9 +162,10 @@ func GetProjects(c echo.Context) error {
 
 	// Fetch user submissions if authenticated
 	progressMap := make(map[int]struct {
-		TotalTests  int
-		PassedTests int
-		IsCompleted bool
+		TotalTests   int
+		PassedTests  int
+		IsCompleted  bool
+		AttemptCount int
 	})
 
 	if userId != "" {
@@ -137,6 +215,7 @@ func GetProjects(c echo.Context) error {
 				}
 
 				current := progressMap[projectNum]
+				current.AttemptCount++
 
 				// Track highest passed tests
 				if sub.Result.TestSummary != nil {
@@ -161,6 +240,33 @@ func GetProjects(c echo.Context) error {
 		}
 	}
 
+	// Class-wide difficulty signal for the admin catalog; best effort. Each query
+	// gets its own timeout so a slow aggregation only drops its own column.
+	var medianAttempts, medianRuns map[string]float64
+	if adminView {
+		// Admin-only flag: on the public /projects route it is ignored, never a 403
+		includeInternal := c.QueryParam("include_internal") == "true"
+		var excludedSupabaseUserIDs []string
+		if !includeInternal {
+			idsCtx, cancel := context.WithTimeout(c.Request().Context(), DefaultQueryTimeout)
+			excludedSupabaseUserIDs, err = GetInternalSupabaseIDs(idsCtx, []string{"linkedinorleftout.com"}, nil)
+			cancel()
+			if err != nil {
+				c.Logger().Errorf("Failed to get internal user IDs: %v", err)
+			}
+		}
+
+		medianAttempts, err = getMedianAttemptsBeforePass(c.Request().Context(), includeInternal, excludedSupabaseUserIDs)
+		if err != nil {
+			c.Logger().Warnf("Failed to aggregate attempts before pass: %v", err)
+		}
+
+		medianRuns, err = getMedianRunsBeforeFirstPass(c.Request().Context(), includeInternal, excludedSupabaseUserIDs)
+		if err != nil {
+			c.Logger().Warnf("Failed to aggregate runs before first pass: %v", err)
+		}
+	}
+
 	// Build response with progress data
 	projectList := make([]ProjectListItem, len(projects))
 	for i, p := range projects {
@@ -178,6 +284,16 @@ func GetProjects(c echo.Context) error {
 			TotalTests:    progress.TotalTests,
 			PassedTests:   progress.PassedTests,
 			IsCompleted:   progress.IsCompleted,
+			AttemptCount:  progress.AttemptCount,
+		}
+		if median, ok := medianAttempts[strconv.Itoa(p.ProjectNumber)]; ok {
+			projectList[i].MedianAttemptsBeforePass = &median
+		}
+		// Decision-trace contentId may be the project number or its Mongo id
+		if median, ok := medianRuns[strconv.Itoa(p.ProjectNumber)]; ok {
+			projectList[i].MedianRunsBeforeFirstPass = &median
+		} else if median, ok := medianRuns[p.ID.Hex()]; ok {
+			projectList[i].MedianRunsBeforeFirstPass = &median
 		}
 	}
 
@@ -239,6 +355,25 @@ func CreateProject(c echo.Context) error {
 		})
 	}
 
+	payload.Tags = normalizeProjectTags(payload.Tags)
+	if errs := validateProjectPayload(payload); len(errs) > 0 {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"errors":  errs,
+		})
+	}
+
+	// Dry run: report what would be created without touching the content DB
+	if isDryRun(c) {
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"success":  true,
+			"dryRun":   true,
+			"action":   "create",
+			"project":  payload,
+			"warnings": checkProjectTestHarness(payload),
+		})
+	}
+
 	// Admin content creation - write to content DB
 	projectId, err := database.ContentCollections.Projects.CreateProject(c.Request().Context(), payload)
 	if err != nil {
@@ -248,6 +383,18 @@ func CreateProject(c echo.Context) error {
 			"error":   err.Error(),
 		})
 	}
+	invalidateProjectCatalog()
+
+	if payload.RequiredPasses > database.DefaultRequiredPasses || payload.MinTestCount > database.DefaultMinTestCount {
+		if err := database.SetProjectCompletionSettings(c.Request().Context(), projectId, &payload.RequiredPasses, &payload.MinTestCount); err != nil {
+			c.Logger().Errorf("CreateProject: failed to set completion settings on %s: %v", projectId, err)
+			return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"id":      projectId,
+				"error":   err.Error(),
+			})
+		}
+	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"success": true,
@@ -255,6 +402,292 @@ func CreateProject(c echo.Context) error {
 	})
 }
 
+// maxProjectImportItems caps how many projects one import request may carry
+const maxProjectImportItems = 200
+
+// projectImportItem is a ProjectPayload with the explicit projectNumber it is stored under
+type projectImportItem struct {
+	ProjectNumber *int `json:"projectNumber"`
+	shared.ProjectPayload
+}
+
+// projectImportItemResult is the per-item outcome of an import
+type projectImportItemResult struct {
+	Index         int      `json:"index"`
+	ProjectNumber *int     `json:"projectNumber"`
+	Status        string   `json:"status"` // "created" | "updated" | "failed" | "valid" (dry run)
+	Errors        []string `json:"errors,omitempty"`
+}
+
+// ImportProjects handles POST /admin/projects/import
+// Validates every item, then upserts the valid ones keyed on projectNumber in one
+// bulk write. Invalid items are reported as failed and never written; the rest
+// still go through (partial success), so callers must check per-item status.
+func ImportProjects(c echo.Context) error {
+	var items []projectImportItem
+	if err := c.Bind(&items); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request data: expected a JSON array of projects",
+		})
+	}
+	if len(items) == 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "No projects to import",
+		})
+	}
+	if len(items) > maxProjectImportItems {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": fmt.Sprintf("Too many projects: max %d per import", maxProjectImportItems),
+		})
+	}
+
+	results := make([]projectImportItemResult, len(items))
+	imports := make([]database.ProjectImport, 0, len(items))
+	importIndexes := make([]int, 0, len(items)) // imports[i] came from items[importIndexes[i]]
+	seen := make(map[int]int)
+
+	for i := range items {
+		item := &items[i]
+		item.Tags = normalizeProjectTags(item.Tags)
+		results[i] = projectImportItemResult{Index: i, ProjectNumber: item.ProjectNumber}
+
+		errs := validateProjectPayload(item.ProjectPayload)
+		if item.ProjectNumber == nil {
+			errs = append(errs, "projectNumber is required")
+		} else if *item.ProjectNumber < 0 {
+			errs = append(errs, "projectNumber must not be negative")
+		} else if first, dup := seen[*item.ProjectNumber]; dup {
+			errs = append(errs, fmt.Sprintf("projectNumber %d duplicates item %d", *item.ProjectNumber, first))
+		} else {
+			seen[*item.ProjectNumber] = i
+		}
+
+		if len(errs) > 0 {
+			results[i].Status = database.ProjectImportFailed
+			results[i].Errors = errs
+			continue
+		}
+		results[i].Status = "valid"
+		imports = append(imports, database.ProjectImport{ProjectNumber: *item.ProjectNumber, Payload: item.ProjectPayload})
+		importIndexes = append(importIndexes, i)
+	}
+
+	if !isDryRun(c) && len(imports) > 0 {
+		ctx, cancel := context.WithTimeout(c.Request().Context(), DefaultQueryTimeout)
+		defer cancel()
+
+		written, err := database.BulkUpsertProjects(ctx, imports)
+		if err != nil {
+			c.Logger().Errorf("ImportProjects: bulk write failed: %v", err)
+			return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"error":   "Failed to import projects",
+			})
+		}
+		for j, r := range written {
+			i := importIndexes[j]
+			results[i].Status = r.Status
+			if r.Error != "" {
+				results[i].Errors = []string{r.Error}
+			}
+		}
+		invalidateProjectCatalog()
+	}
+
+	counts := map[string]int{}
+	for _, r := range results {
+		counts[r.Status]++
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": counts[database.ProjectImportFailed] == 0,
+		"dryRun":  isDryRun(c),
+		"created": counts[database.ProjectImportCreated],
+		"updated": counts[database.ProjectImportUpdated],
+		"failed":  counts[database.ProjectImportFailed],
+		"results": results,
+	})
+}
+
+// ExportProjects handles GET /admin/projects/export?category=
+// Streams the catalog as a JSON array in the POST /admin/projects/import item shape,
+// so it can be re-imported into another environment as-is.
+func ExportProjects(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 2*DefaultQueryTimeout)
+	defer cancel()
+
+	category := strings.TrimSpace(c.QueryParam("category"))
+	cursor, err := database.OpenProjectExportCursor(ctx, category)
+	if err != nil {
+		c.Logger().Errorf("ExportProjects: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to export projects",
+		})
+	}
+	defer cursor.Close(ctx)
+
+	filename := fmt.Sprintf("projects-export-%s.json", time.Now().Format("2006-01-02"))
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, echo.MIMEApplicationJSONCharsetUTF8)
+	res.Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filename))
+	res.WriteHeader(http.StatusOK)
+
+	// Headers are sent; from here on errors can only be logged and the array cut short
+	enc := json.NewEncoder(res)
+	if _, err := res.Write([]byte("[")); err != nil {
+		return nil
+	}
+	count := 0
+	for cursor.Next(ctx) {
+		var project shared.ProjectDocument
+		if err := cursor.Decode(&project); err != nil {
+			c.Logger().Warnf("ExportProjects: skipping malformed project: %v", err)
+			continue
+		}
+		if count > 0 {
+			if _, err := res.Write([]byte(",")); err != nil {
+				return nil
+			}
+		}
+		if err := enc.Encode(projectExportItem(project)); err != nil {
+			c.Logger().Errorf("ExportProjects: write failed after %d projects: %v", count, err)
+			return nil
+		}
+		count++
+		res.Flush()
+	}
+	if err := cursor.Err(); err != nil {
+		c.Logger().Errorf("ExportProjects: cursor error after %d projects: %v", count, err)
+	}
+	_, _ = res.Write([]byte("]\n"))
+	return nil
+}
+
+// StreamProjectSubmissions handles GET /admin/projects/:id/submissions/stream
+// Streams every submission for a project as NDJSON (one BrowserSubmissionDocument per
+// line, oldest first) straight from the cursor, so large projects are never buffered.
+// Query params:
+//   - environment: only this environment ("production", "staging", "development")
+//   - passed: "true" or "false" to filter on the pass flag
+//   - include_code: include files and userTestsCode (default false)
+//   - include_internal: include internal users (default false)
+func StreamProjectSubmissions(c echo.Context) error {
+	idStr := c.Param("id")
+	if _, err := strconv.Atoi(idStr); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid project ID",
+		})
+	}
+
+	filter := database.ProjectSubmissionStreamFilter{
+		Environment: strings.TrimSpace(c.QueryParam("environment")),
+		IncludeCode: c.QueryParam("include_code") == "true",
+	}
+	switch c.QueryParam("passed") {
+	case "":
+	case "true", "false":
+		passed := c.QueryParam("passed") == "true"
+		filter.Passed = &passed
+	default:
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "passed must be 'true' or 'false'",
+		})
+	}
+
+	// No overall timeout: the stream lasts as long as the client keeps reading
+	ctx := c.Request().Context()
+
+	if c.QueryParam("include_internal") != "true" {
+		lookupCtx, cancel := context.WithTimeout(ctx, DefaultQueryTimeout)
+		excluded, err := GetInternalSupabaseIDs(lookupCtx, []string{"linkedinorleftout.com"}, nil)
+		cancel()
+		if err != nil {
+			c.Logger().Errorf("Failed to get internal user IDs: %v", err)
+		}
+		filter.ExcludedSupabaseUserIDs = excluded
+	}
+
+	cursor, err := database.OpenProjectSubmissionsCursor(ctx, idStr, filter)
+	if err != nil {
+		c.Logger().Errorf("StreamProjectSubmissions: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to fetch submissions",
+		})
+	}
+	defer cursor.Close(ctx)
+
+	filename := fmt.Sprintf("project-%s-submissions-%s.ndjson", idStr, time.Now().Format("2006-01-02"))
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "application/x-ndjson")
+	res.Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filename))
+	res.WriteHeader(http.StatusOK)
+
+	// Headers are sent; from here on errors can only be logged and the stream cut short.
+	// json.Encoder terminates each document with a newline.
+	enc := json.NewEncoder(res)
+	count := 0
+	for cursor.Next(ctx) {
+		var sub database.BrowserSubmissionDocument
+		if err := cursor.Decode(&sub); err != nil {
+			c.Logger().Warnf("StreamProjectSubmissions: skipping malformed submission: %v", err)
+			continue
+		}
+		if err := enc.Encode(sub); err != nil {
+			c.Logger().Errorf("StreamProjectSubmissions: write failed after %d submissions: %v", count, err)
+			return nil
+		}
+		count++
+		if count%100 == 0 {
+			res.Flush()
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		c.Logger().Errorf("StreamProjectSubmissions: cursor error after %d submissions: %v", count, err)
+	}
+	res.Flush()
+	return nil
+}
+
+// projectExportItem converts a stored project to the import item shape
+func projectExportItem(p shared.ProjectDocument) projectImportItem {
+	projectNumber := p.ProjectNumber
+	return projectImportItem{
+		ProjectNumber: &projectNumber,
+		ProjectPayload: shared.ProjectPayload{
+			Title:          p.Title,
+			Description:    p.Description,
+			Difficulty:     p.Difficulty,
+			Instructions:   p.Instructions,
+			StarterFiles:   p.StarterFiles,
+			TestFile:       p.TestFile,
+			Category:       p.Category,
+			Tags:           p.Tags,
+			RequiredPasses: p.RequiredPasses,
+			MinTestCount:   p.MinTestCount,
+		},
+	}
+}
+
+// projectUpdatePayload is a ProjectPayload whose completion settings are pointers,
+// so UpdateProject can tell an omitted field from an explicit value
+type projectUpdatePayload struct {
+	shared.ProjectPayload
+	RequiredPasses *int `json:"requiredPasses"`
+	MinTestCount   *int `json:"minTestCount"`
+}
+
+// withCompletionSettings returns the embedded payload with any provided completion settings applied
+func (p projectUpdatePayload) withCompletionSettings() shared.ProjectPayload {
+	payload := p.ProjectPayload
+	if p.RequiredPasses != nil {
+		payload.RequiredPasses = *p.RequiredPasses
+	}
+	if p.MinTestCount != nil {
+		payload.MinTestCount = *p.MinTestCount
+	}
+	return payload
+}
+
 // UpdateProject handles admin project updates
 func UpdateProject(c echo.Context) error {
 	idStr := c.Param("id")
@@ -265,12 +698,13 @@ func UpdateProject(c echo.Context) error {
 		})
 	}
 
-	var payload shared.ProjectPayload
-	if err := c.Bind(&payload); err != nil {
+	var body projectUpdatePayload
+	if err := c.Bind(&body); err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{
 			"error": "Invalid request data",
 		})
 	}
+	payload := body.withCompletionSettings()
 
 	// Verify project exists before updating
 	// Query by projectNumber, not _id
@@ -281,6 +715,26 @@ func UpdateProject(c echo.Context) error {
 		})
 	}
 
+	payload.Tags = normalizeProjectTags(payload.Tags)
+	if errs := validateProjectPayload(payload); len(errs) > 0 {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"errors":  errs,
+		})
+	}
+
+	// Dry run: report which fields would change without touching the content DB
+	if isDryRun(c) {
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"success":       true,
+			"dryRun":        true,
+			"action":        "update",
+			"projectNumber": projectNumber,
+			"changedFields": projectPayloadChanges(project, payload, body.RequiredPasses != nil, body.MinTestCount != nil),
+			"warnings":      checkProjectTestHarness(payload),
+		})
+	}
+
 	// Admin content update - write to content DB
 	err = database.ContentCollections.Projects.UpdateProject(c.Request().Context(), projectNumber, payload)
 	if err != nil {
@@ -289,12 +743,171 @@ func UpdateProject(c echo.Context) error {
 			"error":   err.Error(),
 		})
 	}
+	invalidateProjectCatalog()
+
+	// Only touch completion settings the body carries, so a PUT without them keeps the current values
+	if err := database.SetProjectCompletionSettings(c.Request().Context(), idStr, body.RequiredPasses, body.MinTestCount); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"success": false,
+			"error":   err.Error(),
+		})
+	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"success": true,
 	})
 }
 
+// validProjectTag matches a normalized tag (lowercase, max 32 chars)
+var validProjectTag = regexp.MustCompile(`^[a-z0-9][a-z0-9 _+#.-]{0,31}$`)
+
+// normalizeProjectTags trims, lowercases and dedupes tags, preserving first-seen order.
+// Empty tags are dropped; character validation happens in validateProjectPayload.
+func normalizeProjectTags(tags []string) []string {
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.Join(strings.Fields(tag), " "))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
+
+// GetProjectTags handles GET /projects/tags
+// Returns the distinct normalized tag set with the number of projects using each tag
+func GetProjectTags(c echo.Context) error {
+	c.Response().Header().Set(
+		"Cache-Control",
+		"public, max-age=300, stale-while-revalidate=86400",
+	)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), DefaultQueryTimeout)
+	defer cancel()
+
+	tags, err := database.GetProjectTagCounts(ctx)
+	if err != nil {
+		c.Logger().Errorf("Failed to fetch project tags: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to fetch project tags",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"tags": tags,
+	})
+}
+
+// isDryRun reports whether the request asked for ?dryRun=true.
+// Dry runs validate and preview only; they never write to the database.
+func isDryRun(c echo.Context) bool {
+	v, _ := strconv.ParseBool(c.QueryParam("dryRun"))
+	return v
+}
+
+// validateProjectPayload checks the fields required for a runnable project
+func validateProjectPayload(payload shared.ProjectPayload) []string {
+	errs := make([]string, 0)
+	if strings.TrimSpace(payload.Title) == "" {
+		errs = append(errs, "title is required")
+	}
+	switch payload.Difficulty {
+	case shared.DifficultyEasy, shared.DifficultyMedium, shared.DifficultyHard:
+	default:
+		errs = append(errs, fmt.Sprintf("difficulty must be one of easy, medium, hard (got %q)", payload.Difficulty))
+	}
+	if len(payload.StarterFiles) == 0 {
+		errs = append(errs, "starterFiles must contain at least one file")
+	}
+	for name := range payload.StarterFiles {
+		if strings.TrimSpace(name) == "" || strings.Contains(name, "..") || strings.HasPrefix(name, "/") {
+			errs = append(errs, fmt.Sprintf("invalid starter file name %q", name))
+		}
+	}
+	if strings.TrimSpace(payload.TestFile.Filename) == "" {
+		errs = append(errs, "testFile.filename is required")
+	} else if _, clash := payload.StarterFiles[payload.TestFile.Filename]; clash {
+		errs = append(errs, fmt.Sprintf("testFile.filename %q collides with a starter file", payload.TestFile.Filename))
+	}
+	for _, tag := range payload.Tags {
+		if !validProjectTag.MatchString(tag) {
+			errs = append(errs, fmt.Sprintf("tag %q may only contain letters, digits, spaces, '-', '_', '+', '#' or '.'", tag))
+		}
+	}
+	if payload.RequiredPasses < 0 {
+		errs = append(errs, "requiredPasses must not be negative")
+	}
+	if payload.MinTestCount < 0 {
+		errs = append(errs, "minTestCount must not be negative")
+	}
+	if strings.TrimSpace(payload.TestFile.Content) == "" {
+		errs = append(errs, "testFile.content is required")
+	}
+	return errs
+}
+
+// checkProjectTestHarness returns non-fatal warnings about the test file,
+// e.g. no discoverable test functions or no import of a starter module
+func checkProjectTestHarness(payload shared.ProjectPayload) []string {
+	warnings := make([]string, 0)
+	content := payload.TestFile.Content
+	if !strings.Contains(content, "def test") {
+		warnings = append(warnings, "testFile contains no test functions (expected \"def test...\")")
+	}
+	importsStarter := false
+	for name := range payload.StarterFiles {
+		module := strings.TrimSuffix(name, ".py")
+		if strings.Contains(content, "import "+module) || strings.Contains(content, "from "+module+" ") {
+			importsStarter = true
+			break
+		}
+	}
+	if !importsStarter && len(payload.StarterFiles) > 0 {
+		warnings = append(warnings, "testFile does not import any starter file module")
+	}
+	return warnings
+}
+
+// projectPayloadChanges lists the top-level fields an update would modify.
+// requiredPasses and minTestCount are only compared when the update body carries them.
+func projectPayloadChanges(existing *shared.ProjectDocument, payload shared.ProjectPayload, hasRequiredPasses, hasMinTestCount bool) []string {
+	changed := make([]string, 0)
+	if existing.Title != payload.Title {
+		changed = append(changed, "title")
+	}
+	if existing.Description != payload.Description {
+		changed = append(changed, "description")
+	}
+	if existing.Difficulty != payload.Difficulty {
+		changed = append(changed, "difficulty")
+	}
+	if existing.Instructions != payload.Instructions {
+		changed = append(changed, "instructions")
+	}
+	if fmt.Sprint(existing.StarterFiles) != fmt.Sprint(payload.StarterFiles) {
+		changed = append(changed, "starterFiles")
+	}
+	if existing.TestFile != payload.TestFile {
+		changed = append(changed, "testFile")
+	}
+	if existing.Category != payload.Category {
+		changed = append(changed, "category")
+	}
+	if strings.Join(existing.Tags, ",") != strings.Join(payload.Tags, ",") {
+		changed = append(changed, "tags")
+	}
+	if hasRequiredPasses && existing.RequiredPasses != payload.RequiredPasses {
+		changed = append(changed, "requiredPasses")
+	}
+	if hasMinTestCount && existing.MinTestCount != payload.MinTestCount {
+		changed = append(changed, "minTestCount")
+	}
+	return changed
+}
+
 // DeleteProject handles admin project deletion
 func DeleteProject(c echo.Context) error {
 	idStr := c.Param("id")
@@ -314,6 +927,26 @@ func DeleteProject(c echo.Context) error {
 		})
 	}
 
+	// Refuse to orphan module content unless the admin confirms with ?force=true
+	references, err := database.ContentCollections.Modules.FindModulesReferencing(c.Request().Context(), project.ID)
+	if err != nil {
+		c.Logger().Errorf("DeleteProject: failed to check referencing modules for project %d: %v", projectNumber, err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"success": false,
+			"error":   "Failed to check modules referencing this project",
+		})
+	}
+	if len(references) > 0 {
+		if c.QueryParam("force") != "true" {
+			return c.JSON(http.StatusConflict, map[string]interface{}{
+				"success": false,
+				"error":   "Project is referenced by modules; pass force=true to delete anyway",
+				"modules": references,
+			})
+		}
+		c.Logger().Warnf("DeleteProject: force-deleting project %d referenced by %d modules", projectNumber, len(references))
+	}
+
 	// Admin content deletion - write to content DB
 	err = database.ContentCollections.Projects.DeleteProject(c.Request().Context(), projectNumber)
 	if err != nil {
@@ -322,12 +955,100 @@ func DeleteProject(c echo.Context) error {
 			"error":   err.Error(),
 		})
 	}
+	invalidateProjectCatalog()
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
-		"success": true,
+		"success":         true,
+		"orphanedModules": references,
+	})
+}
+
+// GetProjectReferencingModules handles GET /admin/projects/:id/referencing-modules
+// Returns modules whose content embeds the project (content refId == project _id)
+func GetProjectReferencingModules(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Request().Context(), DefaultQueryTimeout)
+	defer cancel()
+
+	projectNumber, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid project ID",
+		})
+	}
+
+	project, err := database.ContentCollections.Projects.GetProjectByNumber(ctx, projectNumber)
+	if err != nil || project == nil {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "Project not found",
+		})
+	}
+
+	references, err := database.ContentCollections.Modules.FindModulesReferencing(ctx, project.ID)
+	if err != nil {
+		c.Logger().Errorf("Failed to find modules referencing project %d: %v", projectNumber, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to fetch referencing modules",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"projectNumber": projectNumber,
+		"projectId":     project.ID.Hex(),
+		"modules":       references,
 	})
 }
 
+// GetProjectEditorSignals handles GET /admin/projects/:id/editor-signals
+// Query params:
+//   - thresholdMs: paste-to-submit delta counted as suspicious (default 30000)
+//   - include_internal: include internal users (default false)
+func GetProjectEditorSignals(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Request().Context(), DefaultQueryTimeout)
+	defer cancel()
+
+	idStr := c.Param("id")
+	if _, err := strconv.Atoi(idStr); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid project ID",
+		})
+	}
+
+	thresholdMs := database.DefaultSuspiciousPasteSubmitMs
+	if raw := c.QueryParam("thresholdMs"); raw != "" {
+		parsed, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || parsed <= 0 {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "thresholdMs must be a positive integer",
+			})
+		}
+		thresholdMs = parsed
+	}
+
+	// Exclude internal users unless requested
+	var excludedSupabaseUserIDs []string
+	if c.QueryParam("include_internal") != "true" {
+		var err error
+		excludedSupabaseUserIDs, err = GetInternalSupabaseIDs(ctx, []string{"linkedinorleftout.com"}, nil)
+		if err != nil {
+			c.Logger().Errorf("Failed to get internal user IDs: %v", err)
+		}
+	}
+
+	stats, err := database.GetEditorSignalStatsByProject(ctx, idStr, thresholdMs, excludedSupabaseUserIDs)
+	if err != nil {
+		c.Logger().Errorf("Failed to aggregate editor signals for project %s: %v", idStr, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to fetch editor signals",
+		})
+	}
+
+	if stats.PassedWithStarterSubmissions, err = database.CountPassedWithStarterSubmissions(ctx, idStr, excludedSupabaseUserIDs); err != nil {
+		c.Logger().Warnf("Failed to count passed-with-starter submissions for project %s: %v", idStr, err)
+	}
+
+	return c.JSON(http.StatusOK, stats)
+}
+
 // GetProjectSubmissions returns all submissions for a specific project
 func GetProjectSubmissions(c echo.Context) error {
 	cfg := config.GetConfig()
@@ -378,16 +1099,20 @@ func GetProjectSubmissions(c echo.Context) error {
 	}
 	defer cursor.Close(ctx)
 
-	// Decode all submissions
-	var submissions []database.BrowserSubmissionDocument
-	if err := cursor.All(ctx, &submissions); err != nil {
+	// Decode submissions one by one so a single malformed document doesn't fail the request
+	submissions, skipped, err := database.DecodeAllResilient[database.BrowserSubmissionDocument](ctx, cursor)
+	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{
 			"error": "Failed to decode submissions",
 		})
 	}
+	if skipped > 0 {
+		c.Logger().Warnf("[GetProjectSubmissions] Skipped %d malformed submissions for user %s, project %s", skipped, userId, idStr)
+	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"submissions":           submissions,
+		"skipped":               skipped,
 		"runnerContractVersion": cfg.RunnerContractVersion,
 	})
 }
@@ -455,19 +1180,23 @@ func GetUserProjectSubmissions(c echo.Context) error {
 	}
 	defer cursor.Close(ctx)
 
-	// Decode all submissions
-	var submissions []database.BrowserSubmissionDocument
-	if err := cursor.All(ctx, &submissions); err != nil {
+	// Decode submissions one by one so a single malformed document doesn't fail the request
+	submissions, skipped, err := database.DecodeAllResilient[database.BrowserSubmissionDocument](ctx, cursor)
+	if err != nil {
 		c.Logger().Errorf("[GetUserProjectSubmissions] Failed to decode submissions: %v", err)
 		return c.JSON(http.StatusInternalServerError, map[string]string{
 			"error": "Failed to decode submissions",
 		})
 	}
+	if skipped > 0 {
+		c.Logger().Warnf("[GetUserProjectSubmissions] Skipped %d malformed submissions", skipped)
+	}
 
 	c.Logger().Infof("[GetUserProjectSubmissions] Found %d submissions for user %s, project %s", len(submissions), email, projectIdStr)
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"submissions":           submissions,
+		"skipped":               skipped,
 		"projectTitle":          projectTitle,
 		"runnerContractVersion": cfg.RunnerContractVersion,
 	})
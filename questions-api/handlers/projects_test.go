@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestProjectUpdatePayloadCompletionSettingsPresence(t *testing.T) {
+	tests := []struct {
+		name               string
+		body               string
+		wantRequiredPasses *int
+		wantMinTestCount   *int
+	}{
+		{"omitted", `{"title":"Stack"}`, nil, nil},
+		{"explicit values", `{"requiredPasses":3,"minTestCount":5}`, intPtr(3), intPtr(5)},
+		{"explicit zero clears", `{"requiredPasses":0,"minTestCount":0}`, intPtr(0), intPtr(0)},
+		{"only min test count", `{"minTestCount":4}`, nil, intPtr(4)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body projectUpdatePayload
+			if err := json.Unmarshal([]byte(tt.body), &body); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			if !equalIntPtr(body.RequiredPasses, tt.wantRequiredPasses) {
+				t.Errorf("RequiredPasses = %v, want %v", body.RequiredPasses, tt.wantRequiredPasses)
+			}
+			if !equalIntPtr(body.MinTestCount, tt.wantMinTestCount) {
+				t.Errorf("MinTestCount = %v, want %v", body.MinTestCount, tt.wantMinTestCount)
+			}
+			payload := body.withCompletionSettings()
+			if tt.wantMinTestCount != nil && payload.MinTestCount != *tt.wantMinTestCount {
+				t.Errorf("payload.MinTestCount = %d, want %d", payload.MinTestCount, *tt.wantMinTestCount)
+			}
+		})
+	}
+}
+
+func TestProjectMediansCache(t *testing.T) {
+	cache := newProjectMediansCache()
+	calls := 0
+	var gotSince time.Time
+	compute := func(ctx context.Context, since time.Time) (map[string]float64, error) {
+		calls++
+		gotSince = since
+		if _, ok := ctx.Deadline(); !ok {
+			t.Error("compute ran without its own deadline")
+		}
+		if calls == 1 {
+			return nil, errors.New("timeout")
+		}
+		return map[string]float64{"7": 2}, nil
+	}
+
+	if _, err := cache.get(context.Background(), false, compute); err == nil {
+		t.Fatal("expected the first compute error to be returned")
+	}
+	if age := time.Since(gotSince); age < projectMediansWindow-time.Minute || age > projectMediansWindow+time.Minute {
+		t.Errorf("since is %v ago, want about %v", age, projectMediansWindow)
+	}
+	for i := 0; i < 2; i++ {
+		medians, err := cache.get(context.Background(), false, compute)
+		if err != nil || medians["7"] != 2 {
+			t.Fatalf("get() = %v, %v", medians, err)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("compute ran %d times, want 2 (errors are not cached, results are)", calls)
+	}
+
+	if _, err := cache.get(context.Background(), true, compute); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 {
+		t.Errorf("include_internal=true should have its own cache entry; compute ran %d times", calls)
+	}
+}
+
+func intPtr(n int) *int { return &n }
+
+func equalIntPtr(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
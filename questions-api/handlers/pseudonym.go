@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// pseudonymSalt is random per server process: pseudonyms stay stable across refreshes
+// and pages while the server runs, but can't be recomputed from a known email.
+var pseudonymSalt = newPseudonymSalt()
+
+func newPseudonymSalt() []byte {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		id := primitive.NewObjectID()
+		return id[:]
+	}
+	return b
+}
+
+// pseudonymizer maps student identities to stable, non-reversible display names
+// for screen-sharing. scope (e.g. the viewing admin's id) is mixed into the salt so
+// different viewers see unrelated pseudonyms.
+type pseudonymizer struct {
+	key []byte
+}
+
+func newPseudonymizer(scope string) pseudonymizer {
+	mac := hmac.New(sha256.New, pseudonymSalt)
+	mac.Write([]byte(scope))
+	return pseudonymizer{key: mac.Sum(nil)}
+}
+
+// token returns 8 hex chars derived from the lowercased identity
+func (p pseudonymizer) token(identity string) string {
+	mac := hmac.New(sha256.New, p.key)
+	mac.Write([]byte(strings.ToLower(strings.TrimSpace(identity))))
+	return hex.EncodeToString(mac.Sum(nil))[:8]
+}
+
+// Name returns a display name like "Student 3f9a1c2b"
+func (p pseudonymizer) Name(identity string) string {
+	return "Student " + p.token(identity)
+}
+
+// Email returns a placeholder address on the reserved .invalid TLD
+func (p pseudonymizer) Email(identity string) string {
+	return "student-" + p.token(identity) + "@anonymized.invalid"
+}
@@ -0,0 +1,258 @@
+package handlers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gerdinv/questions-api/config"
+	"github.com/gerdinv/questions-api/database"
+	"github.com/gerdinv/questions-api/shared"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// maxReferralBodyBytes bounds the intake body read for signature verification
+	maxReferralBodyBytes = 64 << 10
+	// Public (unsigned) submissions allowed per client IP / per email
+	referralIPLimit     = 5
+	referralIPWindow    = 10 * time.Minute
+	referralEmailLimit  = 3
+	referralEmailWindow = 24 * time.Hour
+	// Field length caps
+	maxReferralFieldLen    = 500
+	maxReferralLongTextLen = 5000
+	// maxLimiterKeys triggers a sweep of expired limiter keys
+	maxLimiterKeys = 10000
+)
+
+// referralSignatureHeader carries "sha256=<hex HMAC-SHA256 of the raw body>" keyed by REFERRAL_WEBHOOK_SECRET
+const referralSignatureHeader = "X-Referral-Signature"
+
+// slidingWindowLimiter counts hits per key within a trailing window
+type slidingWindowLimiter struct {
+	mu     sync.Mutex
+	hits   map[string][]time.Time
+	limit  int
+	window time.Duration
+}
+
+func newSlidingWindowLimiter(limit int, window time.Duration) *slidingWindowLimiter {
+	return &slidingWindowLimiter{hits: make(map[string][]time.Time), limit: limit, window: window}
+}
+
+// Allow records a hit for key and reports whether it is within the limit.
+// Rejected hits are not recorded, so a blocked client recovers once the window passes.
+func (l *slidingWindowLimiter) Allow(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	cutoff := time.Now().Add(-l.window)
+	if len(l.hits) > maxLimiterKeys {
+		l.pruneBefore(cutoff)
+	}
+	recent := l.hits[key][:0]
+	for _, t := range l.hits[key] {
+		if t.After(cutoff) {
+			recent = append(recent, t)
+		}
+	}
+	if len(recent) >= l.limit {
+		l.hits[key] = recent
+		return false
+	}
+	l.hits[key] = append(recent, time.Now())
+	return true
+}
+
+// pruneBefore drops keys with no hits after cutoff so one-off clients don't accumulate
+func (l *slidingWindowLimiter) pruneBefore(cutoff time.Time) {
+	for key, hits := range l.hits {
+		if len(hits) == 0 || !hits[len(hits)-1].After(cutoff) {
+			delete(l.hits, key)
+		}
+	}
+}
+
+var (
+	referralIPLimiter    = newSlidingWindowLimiter(referralIPLimit, referralIPWindow)
+	referralEmailLimiter = newSlidingWindowLimiter(referralEmailLimit, referralEmailWindow)
+)
+
+// verifyReferralSignature checks a "sha256=<hex>" HMAC of body against secret in constant time
+func verifyReferralSignature(body []byte, header, secret string) bool {
+	provided, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(header), "sha256="))
+	if err != nil || len(provided) == 0 {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(provided, mac.Sum(nil))
+}
+
+// validateReferralPayload trims the payload in place and returns field-keyed errors
+func validateReferralPayload(p *ReferralApplicationPayload) map[string]string {
+	errs := make(map[string]string)
+
+	short := map[string]*string{
+		"fullName": &p.FullName, "email": &p.Email, "targetCompany": &p.TargetCompany, "role": &p.Role,
+		"profession": &p.Profession, "school": &p.School, "phoneNumber": &p.PhoneNumber, "address": &p.Address,
+		"linkedInUrl": &p.LinkedInURL, "jobUrl": &p.JobURL, "resumeUrl": &p.ResumeURL,
+		"notionPageId": &p.NotionPageID, "notionUrl": &p.NotionURL,
+	}
+	for name, v := range short {
+		*v = strings.TrimSpace(*v)
+		if len(*v) > maxReferralFieldLen {
+			errs[name] = fmt.Sprintf("must be at most %d characters", maxReferralFieldLen)
+		}
+	}
+	for name, v := range map[string]*string{"motivation": &p.Motivation, "additionalInfo": &p.AdditionalInfo} {
+		*v = strings.TrimSpace(*v)
+		if len(*v) > maxReferralLongTextLen {
+			errs[name] = fmt.Sprintf("must be at most %d characters", maxReferralLongTextLen)
+		}
+	}
+
+	if p.FullName == "" {
+		errs["fullName"] = "required"
+	}
+	if p.TargetCompany == "" {
+		errs["targetCompany"] = "required"
+	}
+	if p.Email == "" {
+		errs["email"] = "required"
+	} else if err := validateEmail(p.Email); err != nil {
+		errs["email"] = "must be a valid email address"
+	}
+
+	if p.LinkedInURL != "" {
+		if host, ok := httpURLHost(p.LinkedInURL); !ok || (host != "linkedin.com" && !strings.HasSuffix(host, ".linkedin.com")) {
+			errs["linkedInUrl"] = "must be an http(s) linkedin.com URL"
+		}
+	}
+	for name, v := range map[string]string{"jobUrl": p.JobURL, "resumeUrl": p.ResumeURL, "notionUrl": p.NotionURL} {
+		if _, ok := httpURLHost(v); v != "" && !ok {
+			errs[name] = "must be an http(s) URL"
+		}
+	}
+	return errs
+}
+
+// httpURLHost returns the lowercase host of an absolute http(s) URL
+func httpURLHost(raw string) (string, bool) {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return "", false
+	}
+	return strings.ToLower(u.Hostname()), true
+}
+
+// SubmitReferralApplication handles POST /referrals - public referral intake.
+// Requests carrying a valid X-Referral-Signature come from the integration and skip
+// rate limits; an invalid signature is rejected. Unsigned requests are rate-limited
+// per client IP and per email.
+func SubmitReferralApplication(c echo.Context) error {
+	body, err := io.ReadAll(io.LimitReader(c.Request().Body, maxReferralBodyBytes+1))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "Invalid request body"})
+	}
+	if len(body) > maxReferralBodyBytes {
+		return c.JSON(http.StatusRequestEntityTooLarge, echo.Map{"error": "Request body too large"})
+	}
+
+	source := "referral_form"
+	if signature := c.Request().Header.Get(referralSignatureHeader); signature != "" {
+		secret := config.GetConfig().ReferralWebhookSecret
+		if secret == "" || !verifyReferralSignature(body, signature, secret) {
+			c.Logger().Warnf("Invalid referral intake signature from %s", c.RealIP())
+			return c.JSON(http.StatusUnauthorized, echo.Map{"error": "Invalid signature"})
+		}
+		source = "referral_integration"
+	}
+
+	// Limits apply before validation so invalid submissions count too and can't be
+	// used to probe the validator for free
+	tooMany := func() error {
+		return c.JSON(http.StatusTooManyRequests, echo.Map{"error": "Too many referral applications; try again later"})
+	}
+	unsigned := source == "referral_form"
+	if unsigned && !referralIPLimiter.Allow(c.RealIP()) {
+		return tooMany()
+	}
+
+	var payload ReferralApplicationPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "Invalid request body"})
+	}
+	normalizedEmail := shared.NormalizeEmail(payload.Email)
+	if unsigned && normalizedEmail != "" && !referralEmailLimiter.Allow(normalizedEmail) {
+		return tooMany()
+	}
+	if errs := validateReferralPayload(&payload); len(errs) > 0 {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"error":  "Invalid referral application",
+			"fields": errs,
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), DefaultQueryTimeout)
+	defer cancel()
+	now := time.Now()
+
+	app := shared.ReferralApplicationDocument{
+		FullName:          payload.FullName,
+		Email:             payload.Email,
+		TargetCompany:     payload.TargetCompany,
+		Role:              payload.Role,
+		Profession:        payload.Profession,
+		School:            payload.School,
+		PhoneNumber:       payload.PhoneNumber,
+		Address:           payload.Address,
+		LinkedInURL:       payload.LinkedInURL,
+		JobURL:            payload.JobURL,
+		ResumeURL:         payload.ResumeURL,
+		Motivation:        payload.Motivation,
+		AdditionalInfo:    payload.AdditionalInfo,
+		NotionPageID:      payload.NotionPageID,
+		NotionURL:         payload.NotionURL,
+		Source:            source,
+		Status:            database.ReferralStatusPending,
+		MatchedBy:         "none",
+		MatchConfidence:   "none",
+		NeedsManualReview: true,
+		ReviewReason:      "No email match found - potential new user or different email",
+		SubmittedAt:       now,
+		UpdatedAt:         now,
+	}
+
+	// Same email match as the webhook; fuzzy matching is left to POST /admin/referrals/:id/match
+	if userID := lookupAppUserID(ctx, normalizedEmail); userID != nil {
+		app.UserID = userID
+		app.MatchedBy = "email"
+		app.MatchConfidence = "high"
+		app.MatchedAt = &now
+		app.NeedsManualReview = false
+		app.ReviewReason = ""
+	}
+
+	insertedID, err := database.AppCollections.ReferralApplications.CreateReferralApplication(ctx, app)
+	if err != nil {
+		c.Logger().Errorf("Failed to create referral application: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Failed to create referral application"})
+	}
+
+	return c.JSON(http.StatusCreated, echo.Map{
+		"id":      insertedID,
+		"status":  app.Status,
+		"matched": app.UserID != nil,
+	})
+}
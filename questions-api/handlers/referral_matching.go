@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/gerdinv/questions-api/config"
+	"github.com/gerdinv/questions-api/database"
+	"github.com/gerdinv/questions-api/internal/clients/supabase"
+	"github.com/gerdinv/questions-api/shared"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// referralNameMatchThreshold is the minimum name similarity (0-1) for a name-only match
+const referralNameMatchThreshold = 0.85
+
+// maxReferralMatchCandidates caps the near-miss users returned for manual review
+const maxReferralMatchCandidates = 5
+
+// referralMatchCandidate is a Supabase user whose name resembles the applicant's
+type referralMatchCandidate struct {
+	SupabaseUserID string  `json:"supabaseUserId"`
+	Email          string  `json:"email"`
+	FullName       string  `json:"fullName"`
+	Similarity     float64 `json:"similarity"`
+}
+
+// matchReferralApplication matches an application to a Supabase user: first by normalized
+// email when it is a valid address (high confidence, no review; anything else would be
+// taken for a Supabase UUID), then by fuzzy full name against every Supabase user
+// (low confidence, always flagged for review). Ambiguous or missing name matches stay
+// unmatched and flagged. Candidates are the closest names, for the reviewer.
+func matchReferralApplication(ctx context.Context, app shared.ReferralApplicationDocument) (database.ReferralMatch, []referralMatchCandidate, error) {
+	candidates := make([]referralMatchCandidate, 0)
+
+	if email := shared.NormalizeEmail(app.Email); email != "" && validateEmail(email) == nil {
+		supabaseID, err := ResolveSupabaseUserID(ctx, email)
+		if err == nil {
+			return database.ReferralMatch{
+				UserID:          lookupAppUserID(ctx, email),
+				SupabaseUserID:  supabaseID,
+				MatchedBy:       "email",
+				MatchConfidence: "high",
+			}, candidates, nil
+		}
+		if !errors.Is(err, ErrSupabaseUserNotFound) {
+			return database.ReferralMatch{}, nil, err
+		}
+	}
+
+	unmatched := database.ReferralMatch{
+		MatchedBy:         "none",
+		MatchConfidence:   "none",
+		NeedsManualReview: true,
+		ReviewReason:      "No email or name match found - potential new user or different email",
+	}
+	applicantName := normalizePersonName(app.FullName)
+	if applicantName == "" {
+		return unmatched, candidates, nil
+	}
+
+	cfg := config.GetConfig()
+	client, err := supabase.NewAdminClient(cfg.SupabaseUrl, cfg.SupabaseServiceRoleKey)
+	if err != nil {
+		return database.ReferralMatch{}, nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return database.ReferralMatch{}, nil, err
+	}
+	users, err := client.GetAllUsers()
+	if err != nil {
+		return database.ReferralMatch{}, nil, fmt.Errorf("failed to list Supabase users: %w", err)
+	}
+
+	for _, u := range users {
+		name := supabaseUserFullName(u)
+		similarity := nameSimilarity(applicantName, normalizePersonName(name))
+		if similarity < referralNameMatchThreshold {
+			continue
+		}
+		candidates = append(candidates, referralMatchCandidate{
+			SupabaseUserID: u.ID,
+			Email:          u.Email,
+			FullName:       name,
+			Similarity:     math.Round(similarity*100) / 100,
+		})
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].Similarity > candidates[j].Similarity
+	})
+	if len(candidates) > maxReferralMatchCandidates {
+		candidates = candidates[:maxReferralMatchCandidates]
+	}
+
+	switch len(candidates) {
+	case 0:
+		return unmatched, candidates, nil
+	case 1:
+		best := candidates[0]
+		return database.ReferralMatch{
+			UserID:            lookupAppUserID(ctx, best.Email),
+			SupabaseUserID:    best.SupabaseUserID,
+			MatchedBy:         "name",
+			MatchConfidence:   "low",
+			NeedsManualReview: true,
+			ReviewReason:      fmt.Sprintf("Matched by name only (similarity %.2f) to %s - confirm before referring", best.Similarity, best.Email),
+		}, candidates, nil
+	default:
+		unmatched.ReviewReason = fmt.Sprintf("%d users have a similar name - pick one manually", len(candidates))
+		return unmatched, candidates, nil
+	}
+}
+
+// lookupAppUserID returns the app users._id for an email, or nil if there is none
+func lookupAppUserID(ctx context.Context, email string) *primitive.ObjectID {
+	user, err := database.AppCollections.Users.GetUserByEmailNormalized(ctx, shared.NormalizeEmail(email))
+	if err != nil || user == nil {
+		return nil
+	}
+	return &user.ID
+}
+
+// supabaseUserFullName reads the display name Supabase keeps in user_metadata
+func supabaseUserFullName(u supabase.User) string {
+	for _, key := range []string{"full_name", "name"} {
+		if name, ok := u.UserMetadata[key].(string); ok && strings.TrimSpace(name) != "" {
+			return strings.TrimSpace(name)
+		}
+	}
+	return ""
+}
+
+// normalizePersonName lowercases, drops punctuation and sorts the name's words,
+// so "Doe, Jane" and "jane doe" normalize to the same string.
+func normalizePersonName(name string) string {
+	cleaned := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return ' '
+	}, name)
+	words := strings.Fields(cleaned)
+	sort.Strings(words)
+	return strings.Join(words, " ")
+}
+
+// nameSimilarity is 1 - levenshtein(a, b) / max(len(a), len(b)) over runes; 0 if either is empty
+func nameSimilarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+	if len(ra) == 0 || len(rb) == 0 {
+		return 0
+	}
+
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			best := prev[j] + 1 // deletion
+			if curr[j-1]+1 < best {
+				best = curr[j-1] + 1 // insertion
+			}
+			if prev[j-1]+cost < best {
+				best = prev[j-1] + cost // substitution
+			}
+			curr[j] = best
+		}
+		prev, curr = curr, prev
+	}
+	return 1 - float64(prev[len(rb)])/float64(longest)
+}
+
+// MatchReferralApplication handles POST /admin/referrals/:id/match - (re)match an application to a user (admin only)
+func MatchReferralApplication(c echo.Context) error {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "Invalid referral application id"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 3*DefaultQueryTimeout)
+	defer cancel()
+
+	app, err := database.AppCollections.ReferralApplications.GetReferralApplicationByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, database.ErrReferralNotFound) {
+			return c.JSON(http.StatusNotFound, echo.Map{"error": "Referral application not found"})
+		}
+		c.Logger().Errorf("Failed to load referral application %s: %v", id.Hex(), err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Failed to load referral application"})
+	}
+
+	match, candidates, err := matchReferralApplication(ctx, *app)
+	if err != nil {
+		c.Logger().Errorf("Failed to match referral application %s: %v", id.Hex(), err)
+		return c.JSON(http.StatusBadGateway, echo.Map{"error": "Failed to look up users in Supabase"})
+	}
+
+	updated, err := database.AppCollections.ReferralApplications.SetReferralMatch(ctx, id, match)
+	if err != nil {
+		if errors.Is(err, database.ErrReferralNotFound) {
+			return c.JSON(http.StatusNotFound, echo.Map{"error": "Referral application not found"})
+		}
+		c.Logger().Errorf("Failed to save referral match %s: %v", id.Hex(), err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Failed to save referral match"})
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"application": updated,
+		"matched":     match.SupabaseUserID != "",
+		"candidates":  candidates,
+	})
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/gerdinv/questions-api/shared"
+)
+
+func TestNormalizePersonName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Jane Doe", "doe jane"},
+		{"Doe, Jane", "doe jane"},
+		{"  jane   DOE ", "doe jane"},
+		{"Mary-Jane O'Neil", "jane mary neil o"},
+		{"José Álvarez", "josé álvarez"},
+		{"Jane Doe 2nd", "2nd doe jane"},
+		{"", ""},
+		{" ,.- ", ""},
+	}
+	for _, tt := range tests {
+		if got := normalizePersonName(tt.name); got != tt.want {
+			t.Errorf("normalizePersonName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNameSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"doe jane", "doe jane", 1},
+		{"doe jane", "doe jan", 1 - 1.0/8},   // one deletion
+		{"doe jane", "doe jone", 1 - 1.0/8},  // one substitution
+		{"doe jane", "doe janet", 1 - 1.0/9}, // one insertion, longer side counts
+		{"doe jane", "smith bob", 0},         // nothing lines up
+		{"josé", "jose", 1 - 1.0/4},          // runes, not bytes
+		{"abc", "", 0},
+		{"", "", 0},
+	}
+	for _, tt := range tests {
+		got := nameSimilarity(tt.a, tt.b)
+		if math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("nameSimilarity(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+		if reverse := nameSimilarity(tt.b, tt.a); math.Abs(reverse-got) > 1e-9 {
+			t.Errorf("nameSimilarity is not symmetric for %q, %q: %v vs %v", tt.a, tt.b, got, reverse)
+		}
+	}
+}
+
+func TestMatchReferralApplicationSkipsInvalidEmail(t *testing.T) {
+	// No full name, so nothing past the email step needs Supabase or config
+	match, _, err := matchReferralApplication(context.Background(), shared.ReferralApplicationDocument{Email: "jane.doe"})
+	if err != nil {
+		t.Fatalf("matchReferralApplication: %v", err)
+	}
+	if match.MatchedBy != "none" || match.SupabaseUserID != "" || !match.NeedsManualReview {
+		t.Errorf("invalid email matched as %+v, want unmatched and flagged for review", match)
+	}
+}
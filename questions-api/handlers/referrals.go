@@ -2,13 +2,17 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gerdinv/questions-api/config"
 	"github.com/gerdinv/questions-api/database"
 	"github.com/gerdinv/questions-api/shared"
 	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // ReferralApplicationPayload is the expected request body for creating a referral application
@@ -131,12 +135,40 @@ func CreateReferralApplication(c echo.Context) error {
 	})
 }
 
-// GetReferralApplications handles GET /admin/referrals - list referral applications (admin only)
+// GetReferralApplications handles GET /admin/referrals - list and filter referral applications (admin only)
+// Query params: status (comma-separated), needsManualReview, targetCompany, order (desc|asc), limit/offset/page
 func GetReferralApplications(c echo.Context) error {
-	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(c.Request().Context(), DefaultQueryTimeout)
+	defer cancel()
+
+	page := parsePagination(c, defaultPaginationLimits())
 
-	// Get pending applications with limit
-	apps, err := database.AppCollections.ReferralApplications.GetPendingReferralApplications(ctx, 100)
+	filter := database.ReferralApplicationFilter{
+		TargetCompany: strings.TrimSpace(c.QueryParam("targetCompany")),
+		Skip:          int64(page.Offset),
+		Limit:         int64(page.Limit),
+	}
+	for _, status := range strings.Split(c.QueryParam("status"), ",") {
+		if status = strings.ToLower(strings.TrimSpace(status)); status != "" {
+			filter.Statuses = append(filter.Statuses, status)
+		}
+	}
+	if raw := c.QueryParam("needsManualReview"); raw != "" {
+		needsReview, err := strconv.ParseBool(raw)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": "needsManualReview must be true or false"})
+		}
+		filter.NeedsManualReview = &needsReview
+	}
+	switch strings.ToLower(c.QueryParam("order")) {
+	case "", "desc":
+	case "asc":
+		filter.OldestFirst = true
+	default:
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "order must be asc or desc"})
+	}
+
+	apps, total, err := database.AppCollections.ReferralApplications.ListReferralApplications(ctx, filter)
 	if err != nil {
 		c.Logger().Errorf("Failed to fetch referral applications: %v", err)
 		return c.JSON(http.StatusInternalServerError, echo.Map{
@@ -144,7 +176,74 @@ func GetReferralApplications(c echo.Context) error {
 		})
 	}
 
-	return c.JSON(http.StatusOK, apps)
+	return c.JSON(http.StatusOK, echo.Map{
+		"applications": apps,
+		"total":        total,
+		"limit":        page.Limit,
+		"offset":       page.Offset,
+		"hasMore":      page.HasMore(total),
+	})
+}
+
+// ReferralApplicationUpdatePayload is the request body for PATCH /admin/referrals/:id.
+// Omitted fields are unchanged; an empty assignedReferrer or reviewReason clears it.
+type ReferralApplicationUpdatePayload struct {
+	Status           *string `json:"status"`
+	AssignedReferrer *string `json:"assignedReferrer"`
+	ReviewReason     *string `json:"reviewReason"`
+}
+
+// UpdateReferralApplication handles PATCH /admin/referrals/:id - advance status and assignment (admin only)
+func UpdateReferralApplication(c echo.Context) error {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "Invalid referral application id"})
+	}
+
+	var payload ReferralApplicationUpdatePayload
+	if err := c.Bind(&payload); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "Invalid request body"})
+	}
+	if payload.Status == nil && payload.AssignedReferrer == nil && payload.ReviewReason == nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "Provide at least one of status, assignedReferrer, reviewReason"})
+	}
+
+	update := database.ReferralApplicationUpdate{}
+	if payload.Status != nil {
+		status := strings.ToLower(strings.TrimSpace(*payload.Status))
+		update.Status = &status
+	}
+	if payload.AssignedReferrer != nil {
+		referrer := strings.TrimSpace(*payload.AssignedReferrer)
+		update.AssignedReferrer = &referrer
+	}
+	if payload.ReviewReason != nil {
+		reason := strings.TrimSpace(*payload.ReviewReason)
+		update.ReviewReason = &reason
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), DefaultQueryTimeout)
+	defer cancel()
+
+	app, err := database.AppCollections.ReferralApplications.UpdateReferralApplication(ctx, id, update)
+	if err != nil {
+		switch {
+		case errors.Is(err, database.ErrReferralNotFound):
+			return c.JSON(http.StatusNotFound, echo.Map{"error": "Referral application not found"})
+		case errors.Is(err, database.ErrInvalidReferralStatus):
+			return c.JSON(http.StatusBadRequest, echo.Map{
+				"error": "Invalid status. Must be one of: pending, in_review, referred, placed, rejected, withdrawn",
+			})
+		case errors.Is(err, database.ErrReferralStatusTransition):
+			return c.JSON(http.StatusUnprocessableEntity, echo.Map{"error": err.Error()})
+		case errors.Is(err, database.ErrReferralStatusConflict):
+			return c.JSON(http.StatusConflict, echo.Map{"error": "Referral application changed while updating; reload and retry"})
+		}
+		c.Logger().Errorf("Failed to update referral application %s: %v", id.Hex(), err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Failed to update referral application"})
+	}
+
+	return c.JSON(http.StatusOK, app)
 }
 
 // GetReferralApplicationsNeedingReview handles GET /admin/referrals/review - get apps needing manual review
@@ -161,3 +260,19 @@ func GetReferralApplicationsNeedingReview(c echo.Context) error {
 
 	return c.JSON(http.StatusOK, apps)
 }
+
+// GetReferralPipelineStats handles GET /admin/referrals/stats - referral pipeline summary (admin only)
+func GetReferralPipelineStats(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Request().Context(), DefaultQueryTimeout)
+	defer cancel()
+
+	stats, err := database.AppCollections.ReferralApplications.GetReferralPipelineStats(ctx)
+	if err != nil {
+		c.Logger().Errorf("Failed to fetch referral pipeline stats: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{
+			"error": "Failed to fetch referral pipeline stats",
+		})
+	}
+
+	return c.JSON(http.StatusOK, stats)
+}
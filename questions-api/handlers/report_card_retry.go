@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gerdinv/questions-api/database"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// failedGenerationTTL bounds how long a failed create can be retried without reloading sessions
+	failedGenerationTTL = 30 * time.Minute
+	// maxGenerationAttempts is how many failed attempts (create + retries) before a clearer error
+	maxGenerationAttempts = 3
+)
+
+// failedGeneration is the context of a create job whose Gemini call failed after
+// sessions were loaded, so a retry can reuse the prompt and signals as-is.
+type failedGeneration struct {
+	model            string
+	prompt           string
+	sessionWindow    int64
+	sessionCountUsed int
+	signals          sessionSignals
+	attempts         int
+	expiresAt        time.Time
+}
+
+var (
+	// Keyed by Supabase userId; one pending failed generation per user
+	failedGenerations      = make(map[string]*failedGeneration)
+	failedGenerationsMutex sync.Mutex
+)
+
+// recordFailedGeneration stores (or bumps) the user's failed generation and returns the
+// attempt count. Attempts carry over from gen (a retry that failed again) or from an
+// unexpired entry with the same prompt. Expired entries of all users are swept here so
+// generations that are never retried don't accumulate.
+func recordFailedGeneration(userID string, gen failedGeneration) int {
+	failedGenerationsMutex.Lock()
+	defer failedGenerationsMutex.Unlock()
+
+	now := time.Now()
+	for id, existing := range failedGenerations {
+		if now.After(existing.expiresAt) {
+			delete(failedGenerations, id)
+		}
+	}
+
+	existing, ok := failedGenerations[userID]
+	if ok && existing.prompt == gen.prompt && existing.attempts > gen.attempts {
+		gen.attempts = existing.attempts
+	}
+	gen.attempts++
+	gen.expiresAt = now.Add(failedGenerationTTL)
+	failedGenerations[userID] = &gen
+	return gen.attempts
+}
+
+// takeFailedGeneration removes and returns the user's unexpired failed generation, so
+// concurrent retries can't both run it; put it back with restoreFailedGeneration or
+// recordFailedGeneration if the retry does not complete.
+func takeFailedGeneration(userID string) (failedGeneration, bool) {
+	failedGenerationsMutex.Lock()
+	defer failedGenerationsMutex.Unlock()
+
+	gen, ok := failedGenerations[userID]
+	if !ok {
+		return failedGeneration{}, false
+	}
+	delete(failedGenerations, userID)
+	if time.Now().After(gen.expiresAt) {
+		return failedGeneration{}, false
+	}
+	return *gen, true
+}
+
+// restoreFailedGeneration puts back a taken generation unchanged, unless a newer
+// failure was recorded for the user in the meantime
+func restoreFailedGeneration(userID string, gen failedGeneration) {
+	failedGenerationsMutex.Lock()
+	defer failedGenerationsMutex.Unlock()
+
+	if _, ok := failedGenerations[userID]; !ok {
+		failedGenerations[userID] = &gen
+	}
+}
+
+func clearFailedGeneration(userID string) {
+	failedGenerationsMutex.Lock()
+	delete(failedGenerations, userID)
+	failedGenerationsMutex.Unlock()
+}
+
+// generationFailedResponse reports a failed Gemini call; after maxGenerationAttempts
+// the message stops suggesting another retry.
+func generationFailedResponse(c echo.Context, attempts int, genErr error) error {
+	if attempts >= maxGenerationAttempts {
+		return c.JSON(http.StatusServiceUnavailable, map[string]interface{}{
+			"error":     fmt.Sprintf("Report generation has failed %d times in a row; Gemini appears to be unavailable. Try again later or create the report with manualParagraph.", attempts),
+			"attempts":  attempts,
+			"retryable": false,
+		})
+	}
+	return c.JSON(http.StatusBadGateway, map[string]interface{}{
+		"error":     fmt.Sprintf("Failed to generate paragraph analysis: %v", genErr),
+		"attempts":  attempts,
+		"retryable": true,
+	})
+}
+
+// handleRetryReportCardJob re-runs the user's last failed generation with the same
+// prompt, model and session window, without reloading user_sessions. The generation is
+// taken out of the map for the duration, so a double-clicked retry gets a 404 instead
+// of a second Gemini call and a duplicate report card.
+func handleRetryReportCardJob(c echo.Context, ctx context.Context, userID, email string) error {
+	gen, ok := takeFailedGeneration(userID)
+	if !ok {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "No failed report generation to retry; run a create job instead"})
+	}
+
+	keys := geminiKeys()
+	if keys.Len() == 0 {
+		restoreFailedGeneration(userID, gen)
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "GEMINI_API_KEYS or GEMINI_API_KEY is not configured"})
+	}
+
+	paragraph, usedModel, err := generateWithModelFallback(ctx, keys, reportModelChain(gen.model), gen.prompt)
+	if err != nil {
+		attempts := recordFailedGeneration(userID, gen)
+		c.Logger().Warnf("Report card retry %d failed for %s: %v", attempts, userID, err)
+		return generationFailedResponse(c, attempts, err)
+	}
+
+	now := time.Now()
+	entry := database.ReportCardEntry{
+		ReportID:  randomHexID(),
+		Paragraph: paragraph,
+		Status:    "active",
+		Source: map[string]interface{}{
+			"job":              "retry",
+			"sessionWindow":    gen.sessionWindow,
+			"sessionCountUsed": gen.sessionCountUsed,
+			"createdVia":       "llm",
+			"attempts":         gen.attempts + 1,
+		},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	for k, v := range estimateGeminiUsage(usedModel, gen.prompt, paragraph) {
+		entry.Source[k] = v
+	}
+	if usedModel != gen.model {
+		entry.Source["requestedModel"] = gen.model
+	}
+
+	if err := database.AppendReportCard(ctx, userID, email, entry); err != nil {
+		restoreFailedGeneration(userID, gen)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to save report card"})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":        "ok",
+		"job":           "retry",
+		"report":        entry,
+		"signals":       gen.signals,
+		"sessionWindow": gen.sessionWindow,
+	})
+}
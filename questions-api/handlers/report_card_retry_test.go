@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+)
+
+func resetFailedGenerations(t *testing.T) {
+	failedGenerationsMutex.Lock()
+	failedGenerations = make(map[string]*failedGeneration)
+	failedGenerationsMutex.Unlock()
+	t.Cleanup(func() {
+		failedGenerationsMutex.Lock()
+		failedGenerations = make(map[string]*failedGeneration)
+		failedGenerationsMutex.Unlock()
+	})
+}
+
+func TestTakeFailedGenerationIsExclusive(t *testing.T) {
+	resetFailedGenerations(t)
+	recordFailedGeneration("u1", failedGeneration{prompt: "p"})
+
+	gen, ok := takeFailedGeneration("u1")
+	if !ok || gen.prompt != "p" {
+		t.Fatalf("first take = %+v, %v", gen, ok)
+	}
+	if _, ok := takeFailedGeneration("u1"); ok {
+		t.Fatal("a second concurrent retry must not get the same generation")
+	}
+}
+
+func TestFailedRetryKeepsAttemptCount(t *testing.T) {
+	resetFailedGenerations(t)
+	if got := recordFailedGeneration("u1", failedGeneration{prompt: "p"}); got != 1 {
+		t.Fatalf("create failure attempts = %d, want 1", got)
+	}
+
+	gen, _ := takeFailedGeneration("u1")
+	if got := recordFailedGeneration("u1", gen); got != 2 {
+		t.Errorf("failed retry attempts = %d, want 2", got)
+	}
+	gen, _ = takeFailedGeneration("u1")
+	if got := recordFailedGeneration("u1", gen); got != 3 {
+		t.Errorf("second failed retry attempts = %d, want 3", got)
+	}
+}
+
+func TestRestoreFailedGenerationDoesNotClobberNewer(t *testing.T) {
+	resetFailedGenerations(t)
+	recordFailedGeneration("u1", failedGeneration{prompt: "old"})
+	taken, _ := takeFailedGeneration("u1")
+
+	recordFailedGeneration("u1", failedGeneration{prompt: "new"})
+	restoreFailedGeneration("u1", taken)
+	if gen, _ := takeFailedGeneration("u1"); gen.prompt != "new" {
+		t.Errorf("restored generation replaced a newer failure: got prompt %q", gen.prompt)
+	}
+
+	restoreFailedGeneration("u1", taken)
+	if gen, ok := takeFailedGeneration("u1"); !ok || gen.prompt != "old" {
+		t.Errorf("restore into an empty slot = %+v, %v", gen, ok)
+	}
+}
+
+func TestRecordFailedGenerationSweepsExpired(t *testing.T) {
+	resetFailedGenerations(t)
+	recordFailedGeneration("stale", failedGeneration{prompt: "p"})
+	failedGenerations["stale"].expiresAt = time.Now().Add(-time.Second)
+
+	recordFailedGeneration("u1", failedGeneration{prompt: "p"})
+	if _, ok := failedGenerations["stale"]; ok {
+		t.Error("expired generation of another user was not swept")
+	}
+	if len(failedGenerations) != 1 {
+		t.Errorf("%d generations pending, want 1", len(failedGenerations))
+	}
+}
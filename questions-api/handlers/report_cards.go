@@ -5,18 +5,27 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log"
+	"math"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/gerdinv/questions-api/config"
 	"github.com/gerdinv/questions-api/database"
+	"github.com/gerdinv/questions-api/internal/clients/gemini"
+	"github.com/gerdinv/questions-api/internal/metrics"
+	"github.com/gerdinv/questions-api/shared"
 	"github.com/labstack/echo/v4"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -25,6 +34,21 @@ import (
 
 const defaultReportModel = "gemini-3-pro-preview"
 const defaultSessionsDir = "../.user_sessions"
+const defaultSessionWindow int64 = 12
+const defaultMaxSessionWindow int64 = 50
+const interpretSessionWindow int64 = 20
+
+// recencyHalfLifeDays is how many days older than the newest session a session must be
+// for its weight to halve in recency-weighted signals.
+const recencyHalfLifeDays = 14.0
+
+// geminiPricing is USD per million tokens (input, output) used for usage estimates.
+// Unknown models are priced as defaultReportModel.
+var geminiPricing = map[string][2]float64{
+	"gemini-3-pro-preview": {2.00, 12.00},
+	"gemini-2.5-pro":       {1.25, 10.00},
+	"gemini-2.5-flash":     {0.30, 2.50},
+}
 
 const paragraphSystemPrompt = `You are a rigorous Computer Science Professor. 
 You are reviewing the work of a student based on "Session Artifacts".
@@ -45,7 +69,7 @@ Trust but verify the "Grader Notes".
 Output Style:
 - Direct, second-person ("You tend to..."). 
 - Professional but critical.
-- Cite specific sessions to back up your claims.`
+- Cite specific sessions to back up your claims, by "projectTitle" when present (e.g. "the Linked List session").`
 
 type reportCardsJobRequest struct {
 	Job             string `json:"job"`
@@ -55,8 +79,11 @@ type reportCardsJobRequest struct {
 	ManualParagraph string `json:"manualParagraph,omitempty"`
 	PromptContext   string `json:"promptContext,omitempty"`
 	RevisionReason  string `json:"revisionReason,omitempty"`
-	Action          string `json:"action,omitempty"` // manage action: list|get|archive
+	Action          string `json:"action,omitempty"`    // manage action: list|get|archive|archive-all|archive-before|share|unshare
+	ShareWith       string `json:"shareWith,omitempty"` // recipient userId or email for share/unshare
 	IncludeArchived bool   `json:"includeArchived,omitempty"`
+	RecencyWeighted bool   `json:"recencyWeighted,omitempty"` // create/interpret: also return signals weighted toward recent sessions
+	Before          string `json:"before,omitempty"`          // manage:archive-before cutoff (RFC3339 or YYYY-MM-DD), exclusive
 }
 
 type sessionSignals struct {
@@ -64,10 +91,20 @@ type sessionSignals struct {
 	FullPassRate       float64 `json:"fullPassRate"`
 	AverageRuns        float64 `json:"averageRuns"`
 	NarrativeFlagCount int     `json:"narrativeFlagCount"`
+	// Set only when the request asked for recency weighting; the fields above stay unweighted
+	RecencyWeighted *recencyWeightedSignals `json:"recencyWeighted,omitempty"`
+}
+
+// recencyWeightedSignals are FullPassRate and AverageRuns with each session weighted
+// by 0.5^(ageDays/HalfLifeDays), where age is measured from the newest session's startedAt.
+type recencyWeightedSignals struct {
+	HalfLifeDays float64 `json:"halfLifeDays"`
+	FullPassRate float64 `json:"fullPassRate"`
+	AverageRuns  float64 `json:"averageRuns"`
 }
 
 // ReportCardsJob handles POST /report-cards/jobs.
-// Jobs: create, revise, interpret, manage.
+// Jobs: create, revise, interpret, manage, retry.
 func ReportCardsJob(c echo.Context) error {
 	user, ok := GetUserClaims(c)
 	if !ok || user.UserID == "" {
@@ -85,6 +122,14 @@ func ReportCardsJob(c echo.Context) error {
 	ctx := c.Request().Context()
 	job := strings.ToLower(strings.TrimSpace(req.Job))
 
+	// Kill switch: block only jobs that would call Gemini. Manual paragraphs,
+	// revisions (always manual) and read-only/deterministic jobs keep working.
+	if !reportCardsEnabled() && jobRequiresGeneration(job, req) {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{
+			"error": "Report card generation is temporarily disabled (REPORT_CARDS_ENABLED=false). Provide manualParagraph to create a report without generation.",
+		})
+	}
+
 	switch job {
 	case "create":
 		return handleCreateReportCardJob(c, ctx, user.UserID, user.Email, req)
@@ -94,38 +139,148 @@ func ReportCardsJob(c echo.Context) error {
 		return handleInterpretReportCardJob(c, ctx, user.UserID, user.Email, req)
 	case "manage":
 		return handleManageReportCardJob(c, ctx, user.UserID, user.Email, req)
+	case "retry":
+		return handleRetryReportCardJob(c, ctx, user.UserID, user.Email)
 	default:
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Unsupported job"})
 	}
 }
 
 // GetMyReportCards handles GET /report-cards/me.
+// Without limit/offset/page the whole document is returned (legacy shape); with
+// any of them, reports are returned newest first in a page window.
 func GetMyReportCards(c echo.Context) error {
 	user, ok := GetUserClaims(c)
 	if !ok || user.UserID == "" {
 		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
 	}
 
-	doc, err := database.GetUserReportCards(c.Request().Context(), user.UserID, user.Email)
+	paginated := hasPaginationParams(c)
+	pagination := parsePagination(c, defaultPaginationLimits())
+
+	doc, err := database.GetUserReportCardsOrEmpty(c.Request().Context(), user.UserID, user.Email)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return c.JSON(http.StatusOK, map[string]interface{}{
-				"userId":  user.UserID,
-				"email":   user.Email,
-				"reports": []database.ReportCardEntry{},
-			})
-		}
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch report cards"})
 	}
-	return c.JSON(http.StatusOK, doc)
+	if !paginated {
+		return c.JSON(http.StatusOK, doc)
+	}
+
+	reports := make([]database.ReportCardEntry, len(doc.Reports))
+	copy(reports, doc.Reports)
+	sort.SliceStable(reports, func(i, j int) bool {
+		return reports[i].CreatedAt.After(reports[j].CreatedAt)
+	})
+	total := int64(len(reports))
+	start := pagination.Offset
+	if start > len(reports) {
+		start = len(reports)
+	}
+	end := start + pagination.Limit
+	if end > len(reports) {
+		end = len(reports)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"userId":  doc.UserID,
+		"email":   doc.Email,
+		"reports": reports[start:end],
+		"total":   total,
+		"limit":   pagination.Limit,
+		"offset":  pagination.Offset,
+		"hasMore": pagination.HasMore(total),
+	})
+}
+
+// GetReportCardsSharedWithMe handles GET /report-cards/shared-with-me.
+// Returns active report cards other users shared with the caller's userId or email.
+func GetReportCardsSharedWithMe(c echo.Context) error {
+	user, ok := GetUserClaims(c)
+	if !ok || user.UserID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+	}
+
+	recipientIDs := []string{user.UserID}
+	if email := database.NormalizeShareRecipient(user.Email); email != "" {
+		recipientIDs = append(recipientIDs, email)
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), DefaultQueryTimeout)
+	defer cancel()
+
+	shared, err := database.GetReportCardsSharedWith(ctx, recipientIDs)
+	if err != nil {
+		c.Logger().Errorf("failed to fetch shared report cards: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch shared report cards"})
+	}
+
+	// Never surface the caller's own cards, even if they listed themselves
+	reports := make([]database.SharedReportCard, 0, len(shared))
+	for _, s := range shared {
+		if s.OwnerUserID == user.UserID {
+			continue
+		}
+		reports = append(reports, s)
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"reports": reports, "count": len(reports)})
+}
+
+// GetReportCardReliabilityOverview handles GET /admin/report-cards/reliability-overview.
+func GetReportCardReliabilityOverview(c echo.Context) error {
+	overview, err := database.GetNarrativeReliabilityOverview(c.Request().Context())
+	if err != nil {
+		c.Logger().Errorf("Failed to build narrative reliability overview: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch reliability overview"})
+	}
+	return c.JSON(http.StatusOK, overview)
+}
+
+// reportCardsEnabled reads the REPORT_CARDS_ENABLED kill switch on every call so it
+// can be flipped without a redeploy. Unset means enabled.
+func reportCardsEnabled() bool {
+	switch strings.ToLower(strings.TrimSpace(config.GetConfig().ReportCardsEnabled)) {
+	case "0", "false", "no", "n", "off":
+		return false
+	default:
+		return true
+	}
+}
+
+// allowedReportModels returns the REPORT_CARD_ALLOWED_MODELS allowlist.
+// defaultReportModel is always allowed.
+func allowedReportModels() map[string]bool {
+	allowed := map[string]bool{defaultReportModel: true}
+	for _, name := range strings.Split(config.GetConfig().ReportCardAllowedModels, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			allowed[name] = true
+		}
+	}
+	return allowed
+}
+
+// resolveReportModel returns the model to call for a request, falling back to
+// defaultReportModel when none is given. Models outside the allowlist are rejected.
+func resolveReportModel(requested string) (string, error) {
+	model := strings.TrimSpace(requested)
+	if model == "" {
+		return defaultReportModel, nil
+	}
+	if !allowedReportModels()[model] {
+		return "", fmt.Errorf("model %q is not allowed", model)
+	}
+	return model, nil
+}
+
+// jobRequiresGeneration reports whether a job would call the LLM.
+// Only create without a manualParagraph does; revise always takes a manual paragraph.
+func jobRequiresGeneration(job string, req reportCardsJobRequest) bool {
+	return job == "retry" || (job == "create" && strings.TrimSpace(req.ManualParagraph) == "")
 }
 
 func handleCreateReportCardJob(c echo.Context, ctx context.Context, userID, email string, req reportCardsJobRequest) error {
 	paragraph := strings.TrimSpace(req.ManualParagraph)
-	window := req.SessionWindow
-	if window <= 0 {
-		window = 12
-	}
+	window := clampSessionWindow(req.SessionWindow)
+	var usage map[string]interface{}
 
 	sessions, err := loadUserSessionsFromDisk(userID, window)
 	if err != nil {
@@ -133,19 +288,38 @@ func handleCreateReportCardJob(c echo.Context, ctx context.Context, userID, emai
 	}
 
 	signals := computeSessionSignals(sessions)
+	if req.RecencyWeighted {
+		signals.RecencyWeighted = computeRecencyWeightedSignals(sessions, recencyHalfLifeDays)
+	}
 	if paragraph == "" {
-		apiKey := strings.TrimSpace(os.Getenv("GEMINI_API_KEY"))
-		if apiKey == "" {
-			return c.JSON(http.StatusBadRequest, map[string]string{"error": "manualParagraph is required when GEMINI_API_KEY is not configured"})
+		keys := geminiKeys()
+		if keys.Len() == 0 {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "manualParagraph is required when GEMINI_API_KEYS or GEMINI_API_KEY is not configured"})
 		}
-		model := req.Model
-		if model == "" {
-			model = defaultReportModel
+		model, err := resolveReportModel(req.Model)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
 		}
 
-		paragraph, err = generateParagraphAnalysis(ctx, apiKey, model, buildParagraphPrompt(signals, sessions, req.PromptContext))
+		projectTitles := lookupSessionProjectTitles(ctx, sessions)
+		prompt := buildParagraphPrompt(signals, sessions, req.PromptContext, projectTitles)
+		var usedModel string
+		paragraph, usedModel, err = generateWithModelFallback(ctx, keys, reportModelChain(model), prompt)
 		if err != nil {
-			return c.JSON(http.StatusBadGateway, map[string]string{"error": fmt.Sprintf("Failed to generate paragraph analysis: %v", err)})
+			// Keep the loaded context so job=retry can regenerate without reloading sessions
+			attempts := recordFailedGeneration(userID, failedGeneration{
+				model:            model,
+				prompt:           prompt,
+				sessionWindow:    window,
+				sessionCountUsed: len(sessions),
+				signals:          signals,
+			})
+			return generationFailedResponse(c, attempts, err)
+		}
+		clearFailedGeneration(userID)
+		usage = estimateGeminiUsage(usedModel, prompt, paragraph)
+		if usedModel != model {
+			usage["requestedModel"] = model
 		}
 	}
 
@@ -167,16 +341,97 @@ func handleCreateReportCardJob(c echo.Context, ctx context.Context, userID, emai
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
+	for k, v := range usage {
+		entry.Source[k] = v
+	}
 
 	if err := database.AppendReportCard(ctx, userID, email, entry); err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to save report card"})
 	}
 
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":        "ok",
+		"job":           "create",
+		"report":        entry,
+		"signals":       signals,
+		"sessionWindow": window,
+	})
+}
+
+// GetReportCardUsage handles GET /admin/report-cards/usage?from=&to=.
+// Defaults to the last 30 days when no range is given.
+func GetReportCardUsage(c echo.Context) error {
+	to := time.Now()
+	if t, err := parseTimeQueryParam(c, "to"); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	} else if t != nil {
+		to = *t
+	}
+	from := to.AddDate(0, 0, -30)
+	if t, err := parseTimeQueryParam(c, "from"); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	} else if t != nil {
+		from = *t
+	}
+	if !from.Before(to) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "from must be before to"})
+	}
+
+	rows, err := database.GetReportCardUsage(c.Request().Context(), from, to)
+	if err != nil {
+		c.Logger().Errorf("Failed to aggregate report card usage: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch report card usage"})
+	}
+
+	llmReports := 0
+	manualReports := 0
+	totalCost := 0.0
+	for _, r := range rows {
+		switch r.CreatedVia {
+		case "llm":
+			llmReports += r.ReportCount
+		case "manual":
+			manualReports += r.ReportCount
+		}
+		totalCost += r.EstimatedCostUsd
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"from":                  from,
+		"to":                    to,
+		"llmReports":            llmReports,
+		"manualReports":         manualReports,
+		"estimatedTotalCostUsd": totalCost,
+		"byModel":               rows,
+	})
+}
+
+// CleanupOrphanedReportCards handles POST /admin/report-cards/cleanup.
+// Query params:
+//   - action: archive (default) | remove
+//   - dryRun: true to list affected entries without writing
+func CleanupOrphanedReportCards(c echo.Context) error {
+	action := strings.ToLower(strings.TrimSpace(c.QueryParam("action")))
+	if action == "" {
+		action = "archive"
+	}
+	if action != "archive" && action != "remove" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "action must be archive or remove"})
+	}
+	dryRun := isDryRun(c)
+
+	cleaned, err := database.CleanupOrphanedReportCards(c.Request().Context(), action == "remove", dryRun)
+	if err != nil {
+		c.Logger().Errorf("Failed to clean up orphaned report cards: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to clean up report cards"})
+	}
+
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"status":  "ok",
-		"job":     "create",
-		"report":  entry,
-		"signals": signals,
+		"dryRun":  dryRun,
+		"action":  action,
+		"count":   len(cleaned),
+		"cleaned": cleaned,
 	})
 }
 
@@ -218,11 +473,15 @@ func handleInterpretReportCardJob(c echo.Context, ctx context.Context, userID, e
 		return c.JSON(http.StatusNotFound, map[string]string{"error": "Report not found"})
 	}
 
-	sessions, err := loadUserSessionsFromDisk(userID, 20)
+	window := clampSessionWindow(interpretSessionWindow)
+	sessions, err := loadUserSessionsFromDisk(userID, window)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to load user_sessions"})
 	}
 	signals := computeSessionSignals(sessions)
+	if req.RecencyWeighted {
+		signals.RecencyWeighted = computeRecencyWeightedSignals(sessions, recencyHalfLifeDays)
+	}
 
 	interpreted := deterministicInterpretReport(*report, signals)
 	updated, err := database.SetReportInterpretedCard(ctx, userID, email, report.ReportID, interpreted)
@@ -234,10 +493,11 @@ func handleInterpretReportCardJob(c echo.Context, ctx context.Context, userID, e
 	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
-		"status":      "ok",
-		"job":         "interpret",
-		"report":      updated,
-		"interpreted": interpreted,
+		"status":        "ok",
+		"job":           "interpret",
+		"report":        updated,
+		"interpreted":   interpreted,
+		"sessionWindow": window,
 	})
 }
 
@@ -247,11 +507,8 @@ func handleManageReportCardJob(c echo.Context, ctx context.Context, userID, emai
 		action = "list"
 	}
 
-	doc, err := database.GetUserReportCards(ctx, userID, email)
+	doc, err := database.GetUserReportCardsOrEmpty(ctx, userID, email)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return c.JSON(http.StatusOK, map[string]interface{}{"status": "ok", "job": "manage", "reports": []database.ReportCardEntry{}})
-		}
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to load report cards"})
 	}
 
@@ -294,11 +551,88 @@ func handleManageReportCardJob(c echo.Context, ctx context.Context, userID, emai
 			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to archive report"})
 		}
 		return c.JSON(http.StatusOK, map[string]interface{}{"status": "ok", "job": "manage", "action": "archive", "report": updated})
+	case "archive-all", "archive-before":
+		var before *time.Time
+		if action == "archive-before" {
+			cutoff, err := parseTimeValue("before", req.Before)
+			if err != nil {
+				return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+			}
+			if cutoff == nil {
+				return c.JSON(http.StatusBadRequest, map[string]string{"error": "before is required for manage:archive-before"})
+			}
+			before = cutoff
+		}
+		archived, err := database.ArchiveReportCards(ctx, userID, email, before)
+		if err != nil {
+			c.Logger().Errorf("Failed to bulk archive report cards for %s: %v", userID, err)
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to archive reports"})
+		}
+		return c.JSON(http.StatusOK, map[string]interface{}{"status": "ok", "job": "manage", "action": action, "archived": archived})
+	case "share", "unshare":
+		if req.ReportID == "" {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "reportId is required for manage:" + action})
+		}
+		recipient := database.NormalizeShareRecipient(req.ShareWith)
+		if recipient == "" {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "shareWith is required for manage:" + action})
+		}
+		if recipient == userID || strings.EqualFold(recipient, email) {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Cannot share a report with yourself"})
+		}
+		updated, err := database.SetReportShared(ctx, userID, email, req.ReportID, recipient, action == "share")
+		if err != nil {
+			if err == mongo.ErrNoDocuments || err == database.ErrReportNotFound {
+				return c.JSON(http.StatusNotFound, map[string]string{"error": "Report not found"})
+			}
+			if err == database.ErrTooManyShares {
+				return c.JSON(http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("A report can be shared with at most %d recipients", database.MaxReportCardShares)})
+			}
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to update report sharing"})
+		}
+		return c.JSON(http.StatusOK, map[string]interface{}{"status": "ok", "job": "manage", "action": action, "report": updated})
 	default:
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Unsupported manage action"})
 	}
 }
 
+// clampSessionWindow applies the default window to non-positive requests and caps
+// large ones at REPORT_CARD_MAX_SESSION_WINDOW to bound prompt size and query cost.
+func clampSessionWindow(requested int64) int64 {
+	maxWindow := int64(config.GetConfig().ReportCardMaxSessionWindow)
+	if maxWindow <= 0 {
+		maxWindow = defaultMaxSessionWindow
+	}
+	window := requested
+	if window <= 0 {
+		window = defaultSessionWindow
+	}
+	if window > maxWindow {
+		window = maxWindow
+	}
+	return window
+}
+
+// estimateGeminiUsage approximates token counts (~4 chars per token) and cost for one
+// generation so usage can be aggregated later from the report's source map.
+func estimateGeminiUsage(model, prompt, output string) map[string]interface{} {
+	promptTokens := int64((len(paragraphSystemPrompt) + len(prompt) + 3) / 4)
+	outputTokens := int64((len(output) + 3) / 4)
+	pricing, ok := geminiPricing[model]
+	if !ok {
+		pricing = geminiPricing[defaultReportModel]
+	}
+	cost := float64(promptTokens)/1e6*pricing[0] + float64(outputTokens)/1e6*pricing[1]
+	return map[string]interface{}{
+		"model":                 model,
+		"promptChars":           len(prompt),
+		"estimatedPromptTokens": promptTokens,
+		"estimatedOutputTokens": outputTokens,
+		"estimatedCostUsd":      cost,
+		"generatedAt":           time.Now(),
+	}
+}
+
 func pickReportForInterpret(reports []database.ReportCardEntry, reportID string, includeArchived bool) (*database.ReportCardEntry, bool) {
 	if reportID != "" {
 		for i := range reports {
@@ -340,41 +674,18 @@ func computeSessionSignals(sessions []database.SessionArtifactDocument) sessionS
 	totalRuns := 0.0
 	fullPass := 0
 	narrativeFlags := 0
+	claimPhrases := shared.NarrativeClaimPhrases(config.GetConfig().NarrativeClaimPhrases)
 
 	for _, s := range sessions {
-		runCount := numFromMap(s.Summary, "runCount")
-		if runCount == 0 {
-			runCount = float64(len(anySliceFromMap(s.Summary, "runOutcomes")))
-		}
-		totalRuns += runCount
+		totalRuns += sessionRunCount(s)
 
-		outcomes := anySliceFromMap(s.Summary, "runOutcomes")
-		if len(outcomes) > 0 {
-			if last, ok := outcomes[len(outcomes)-1].(map[string]interface{}); ok {
-				testsPassed := numFromMap(last, "testsPassed")
-				testsTotal := numFromMap(last, "testsTotal")
-				if testsTotal > 0 && testsPassed == testsTotal {
-					fullPass++
-				}
-			}
+		lastPassed := sessionFullyPassed(s)
+		if lastPassed {
+			fullPass++
 		}
 
-		narrative := strings.ToLower(strings.TrimSpace(strFromNestedMap(s.Summary, "narratives", "narrative")))
-		if narrative != "" {
-			claimsAllPass := strings.Contains(narrative, "all tests passed") || strings.Contains(narrative, "full pass")
-			if claimsAllPass {
-				passed := false
-				if len(outcomes) > 0 {
-					if last, ok := outcomes[len(outcomes)-1].(map[string]interface{}); ok {
-						testsPassed := numFromMap(last, "testsPassed")
-						testsTotal := numFromMap(last, "testsTotal")
-						passed = testsTotal > 0 && testsPassed == testsTotal
-					}
-				}
-				if !passed {
-					narrativeFlags++
-				}
-			}
+		if narrativeClaimsFullPass(s, claimPhrases) && !lastPassed {
+			narrativeFlags++
 		}
 	}
 
@@ -390,9 +701,217 @@ func computeSessionSignals(sessions []database.SessionArtifactDocument) sessionS
 	}
 }
 
+// computeRecencyWeightedSignals weights each session by 0.5^(ageDays/halfLifeDays), with
+// age measured back from the newest session's startedAt (not from now, so a student
+// returning after a break is not discounted), and returns the weighted FullPassRate
+// (sum of w*passed / sum of w) and AverageRuns (sum of w*runs / sum of w).
+// Sessions without any timestamp get weight 1, like the newest session.
+func computeRecencyWeightedSignals(sessions []database.SessionArtifactDocument, halfLifeDays float64) *recencyWeightedSignals {
+	weighted := &recencyWeightedSignals{HalfLifeDays: halfLifeDays}
+	if len(sessions) == 0 || halfLifeDays <= 0 {
+		return weighted
+	}
+
+	var newest time.Time
+	for _, s := range sessions {
+		if t := sessionStartedAt(s); t.After(newest) {
+			newest = t
+		}
+	}
+
+	totalWeight, passWeight, runWeight := 0.0, 0.0, 0.0
+	for _, s := range sessions {
+		weight := 1.0
+		if t := sessionStartedAt(s); !t.IsZero() {
+			ageDays := newest.Sub(t).Hours() / 24
+			weight = math.Pow(0.5, ageDays/halfLifeDays)
+		}
+		totalWeight += weight
+		runWeight += weight * sessionRunCount(s)
+		if sessionFullyPassed(s) {
+			passWeight += weight
+		}
+	}
+	if totalWeight == 0 {
+		return weighted
+	}
+
+	weighted.FullPassRate = passWeight / totalWeight
+	weighted.AverageRuns = runWeight / totalWeight
+	return weighted
+}
+
+// sessionStartedAt returns summary.startedAt (epoch ms), falling back to createdAt.
+// Zero when neither is set.
+func sessionStartedAt(s database.SessionArtifactDocument) time.Time {
+	if ms := numFromMap(s.Summary, "startedAt"); ms > 0 {
+		return time.UnixMilli(int64(ms))
+	}
+	return s.CreatedAt
+}
+
+// sessionRunCount is summary.runCount, or the number of runOutcomes when it is missing.
+func sessionRunCount(s database.SessionArtifactDocument) float64 {
+	runCount := numFromMap(s.Summary, "runCount")
+	if runCount == 0 {
+		runCount = float64(len(anySliceFromMap(s.Summary, "runOutcomes")))
+	}
+	return runCount
+}
+
+// sessionFullyPassed reports whether the session's last run passed every test.
+func sessionFullyPassed(s database.SessionArtifactDocument) bool {
+	return runOutcomeFullyPassed(lastRunOutcome(anySliceFromMap(s.Summary, "runOutcomes")))
+}
+
+// narrativeClaimsFullPass reports whether the session's grader narrative claims every test passed.
+func narrativeClaimsFullPass(s database.SessionArtifactDocument, claimPhrases []string) bool {
+	narrative := strings.ToLower(strings.TrimSpace(strFromNestedMap(s.Summary, "narratives", "narrative")))
+	return narrative != "" && shared.NarrativeClaimsFullPass(narrative, claimPhrases)
+}
+
+// sessionDiscrepancy is one session where the grader narrative disagrees with the test evidence.
+type sessionDiscrepancy struct {
+	SessionID       string    `json:"sessionId"`
+	ProjectID       string    `json:"projectId,omitempty"`
+	CreatedAt       time.Time `json:"createdAt"`
+	Kinds           []string  `json:"kinds"` // "false_confidence" | "regression"
+	Narrative       string    `json:"narrative"`
+	ClaimedAllPass  bool      `json:"claimedAllPass"`
+	LastTestsPassed int       `json:"lastTestsPassed"`
+	LastTestsTotal  int       `json:"lastTestsTotal"`
+	BestTestsPassed int       `json:"bestTestsPassed"`
+}
+
+// findSessionDiscrepancies re-scans sessions with the same false-confidence check
+// as computeSessionSignals (narrative claims a full pass, last run did not pass)
+// plus a regression check (the last run passed fewer tests than an earlier one
+// while the narrative claims success). Sessions without a narrative are skipped.
+func findSessionDiscrepancies(sessions []database.SessionArtifactDocument) []sessionDiscrepancy {
+	claimPhrases := shared.NarrativeClaimPhrases(config.GetConfig().NarrativeClaimPhrases)
+	discrepancies := make([]sessionDiscrepancy, 0)
+
+	for _, s := range sessions {
+		narrative := strings.TrimSpace(strFromNestedMap(s.Summary, "narratives", "narrative"))
+		if narrative == "" {
+			continue
+		}
+		claimsAllPass := narrativeClaimsFullPass(s, claimPhrases)
+
+		outcomes := anySliceFromMap(s.Summary, "runOutcomes")
+		last := lastRunOutcome(outcomes)
+		lastPassed := numFromMap(last, "testsPassed")
+		bestPassed := lastPassed
+		for _, o := range outcomes {
+			if passed := numFromMap(anyToStringMap(o), "testsPassed"); passed > bestPassed {
+				bestPassed = passed
+			}
+		}
+
+		kinds := make([]string, 0, 2)
+		if claimsAllPass && !runOutcomeFullyPassed(last) {
+			kinds = append(kinds, "false_confidence")
+		}
+		if claimsAllPass && bestPassed > lastPassed {
+			kinds = append(kinds, "regression")
+		}
+		if len(kinds) == 0 {
+			continue
+		}
+
+		discrepancies = append(discrepancies, sessionDiscrepancy{
+			SessionID:       s.SessionID,
+			ProjectID:       s.ProjectID,
+			CreatedAt:       s.CreatedAt,
+			Kinds:           kinds,
+			Narrative:       narrative,
+			ClaimedAllPass:  claimsAllPass,
+			LastTestsPassed: int(lastPassed),
+			LastTestsTotal:  int(numFromMap(last, "testsTotal")),
+			BestTestsPassed: int(bestPassed),
+		})
+	}
+	return discrepancies
+}
+
+// GetReportCardDiscrepancies handles GET /report-cards/discrepancies?sessionWindow=&userId=.
+// Lists the sessions behind narrativeFlagCount. Admins may pass userId to inspect a student.
+func GetReportCardDiscrepancies(c echo.Context) error {
+	user, ok := GetUserClaims(c)
+	if !ok || user.UserID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+	}
+
+	targetUserID, ok := targetUserIDParam(c, user)
+	if !ok {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "Only admins can view other users' discrepancies"})
+	}
+	if targetUserID != user.UserID {
+		resolvedID, err := ResolveSupabaseUserID(c.Request().Context(), targetUserID)
+		if err != nil {
+			if errors.Is(err, ErrSupabaseUserNotFound) {
+				return c.JSON(http.StatusNotFound, map[string]string{"error": "User not found"})
+			}
+			c.Logger().Errorf("Failed to resolve Supabase ID for %s: %v", targetUserID, err)
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to resolve user"})
+		}
+		targetUserID = resolvedID
+	}
+
+	requestedWindow, _ := strconv.ParseInt(c.QueryParam("sessionWindow"), 10, 64)
+	window := clampSessionWindow(requestedWindow)
+
+	sessions, err := loadUserSessionsFromDisk(targetUserID, window)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to load user_sessions"})
+	}
+
+	signals := computeSessionSignals(sessions)
+	if c.QueryParam("recencyWeighted") == "true" {
+		signals.RecencyWeighted = computeRecencyWeightedSignals(sessions, recencyHalfLifeDays)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"userId":        targetUserID,
+		"sessionWindow": window,
+		"signals":       signals,
+		"discrepancies": findSessionDiscrepancies(sessions),
+	})
+}
+
 // ... (omitted structs are unchanged)
 
-func buildParagraphPrompt(signals sessionSignals, sessions []database.SessionArtifactDocument, extraContext string) string {
+// lookupSessionProjectTitles resolves the projectIds (projectNumber strings) of the
+// given sessions to project titles with a single batch query. Lookup failures only
+// cost the titles, so they are logged and an empty map is returned.
+func lookupSessionProjectTitles(ctx context.Context, sessions []database.SessionArtifactDocument) map[string]string {
+	titles := make(map[string]string)
+	seen := make(map[int]bool)
+	numbers := make([]int, 0)
+	for _, s := range sessions {
+		n, err := strconv.Atoi(strings.TrimSpace(s.ProjectID))
+		if err != nil || seen[n] {
+			continue
+		}
+		seen[n] = true
+		numbers = append(numbers, n)
+	}
+	if len(numbers) == 0 {
+		return titles
+	}
+
+	projects, err := database.GetProjectsByNumbers(ctx, numbers)
+	if err != nil {
+		log.Printf("report cards: failed to look up project titles: %v", err)
+		return titles
+	}
+	for n, p := range projects {
+		titles[strconv.Itoa(n)] = p.Title
+	}
+	return titles
+}
+
+func buildParagraphPrompt(signals sessionSignals, sessions []database.SessionArtifactDocument, extraContext string, projectTitles map[string]string) string {
 	// We want to send the FULL session details to Gemini.
 	// We will serialize the entire SessionArtifactDocument (or the relevant parts).
 	// To save *some* tokens, we might omit empty fields, but for now, full detail is better.
@@ -409,6 +928,9 @@ func buildParagraphPrompt(signals sessionSignals, sessions []database.SessionArt
 			// Note: This can be large. If we hit limits, we might need to truncate `testOutput` or file content.
 			"artifact": s.Artifact,
 		}
+		if title, ok := projectTitles[strings.TrimSpace(s.ProjectID)]; ok && title != "" {
+			item["projectTitle"] = title
+		}
 		data = append(data, item)
 	}
 
@@ -427,7 +949,120 @@ func buildParagraphPrompt(signals sessionSignals, sessions []database.SessionArt
 	return "Analyize these student sessions:\n\n" + string(b)
 }
 
-func generateParagraphAnalysis(ctx context.Context, apiKey, model, prompt string) (string, error) {
+// geminiStatusError is returned for non-2xx Gemini responses so callers can react to 429s.
+type geminiStatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *geminiStatusError) Error() string {
+	return fmt.Sprintf("gemini request failed (%d): %s", e.StatusCode, e.Body)
+}
+
+var (
+	geminiKeyPool     *gemini.KeyPool
+	geminiKeyPoolOnce sync.Once
+)
+
+// geminiKeys returns the process-wide key pool, built once from the environment.
+func geminiKeys() *gemini.KeyPool {
+	geminiKeyPoolOnce.Do(func() {
+		geminiKeyPool = gemini.NewKeyPoolFromEnv()
+	})
+	return geminiKeyPool
+}
+
+// generateWithKeyRotation tries keys round-robin, putting a key into cooldown and
+// moving to the next one when Gemini answers 429. Other errors are returned as-is.
+func generateWithKeyRotation(ctx context.Context, keys *gemini.KeyPool, model, prompt string) (string, error) {
+	var lastErr error
+	for attempt := 0; attempt < keys.Len(); attempt++ {
+		apiKey, ok := keys.Next()
+		if !ok {
+			break
+		}
+		text, err := generateParagraphAnalysis(ctx, apiKey, model, prompt)
+		var statusErr *geminiStatusError
+		if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusTooManyRequests {
+			keys.MarkRateLimited(apiKey, gemini.DefaultCooldown)
+			lastErr = err
+			continue
+		}
+		return text, err
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("all Gemini API keys are cooling down after rate limits")
+	}
+	return "", lastErr
+}
+
+// reportModelChain returns the requested model followed by REPORT_CARD_FALLBACK_MODELS
+// (comma-separated, in order), without duplicates. Every entry goes through
+// resolveReportModel, so models outside REPORT_CARD_ALLOWED_MODELS are dropped.
+func reportModelChain(model string) []string {
+	chain := make([]string, 0)
+	seen := make(map[string]bool)
+	candidates := append([]string{model}, strings.Split(config.GetConfig().ReportCardFallbackModels, ",")...)
+	for _, name := range candidates {
+		if name = strings.TrimSpace(name); name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		resolved, err := resolveReportModel(name)
+		if err != nil {
+			log.Printf("Report card model chain: skipping %s: %v", name, err)
+			continue
+		}
+		chain = append(chain, resolved)
+	}
+	return chain
+}
+
+// isModelUnavailableError reports whether Gemini rejected the model itself (unknown,
+// gated, unsupported or overloaded) rather than the key. 429s are not model errors:
+// they are retried on the same model with another key.
+func isModelUnavailableError(err error) bool {
+	var statusErr *geminiStatusError
+	if !errors.As(err, &statusErr) {
+		return false
+	}
+	switch statusErr.StatusCode {
+	case http.StatusNotFound, http.StatusForbidden, http.StatusServiceUnavailable:
+		return true
+	case http.StatusBadRequest:
+		return strings.Contains(strings.ToLower(statusErr.Body), "model")
+	default:
+		return false
+	}
+}
+
+// generateWithModelFallback runs generateWithKeyRotation on each model of the chain in
+// order, moving on only when the model is unavailable. It returns the model that
+// produced the text; any other error (including exhausted rate limits) stops the chain.
+func generateWithModelFallback(ctx context.Context, keys *gemini.KeyPool, models []string, prompt string) (string, string, error) {
+	if len(models) == 0 {
+		return "", "", fmt.Errorf("no allowed report card model to call")
+	}
+	var lastErr error
+	for i, model := range models {
+		text, err := generateWithKeyRotation(ctx, keys, model, prompt)
+		if err == nil {
+			return text, model, nil
+		}
+		lastErr = err
+		if !isModelUnavailableError(err) {
+			break
+		}
+		if i < len(models)-1 {
+			log.Printf("Report card model %s unavailable, falling back to %s: %v", model, models[i+1], err)
+		}
+	}
+	return "", "", lastErr
+}
+
+func generateParagraphAnalysis(ctx context.Context, apiKey, model, prompt string) (_ string, err error) {
+	defer func() { metrics.RecordGeminiCall(model, err) }()
+
 	endpoint := fmt.Sprintf(
 		"https://generativelanguage.googleapis.com/v1beta/models/%s:generateContent?key=%s",
 		url.PathEscape(model),
@@ -463,7 +1098,7 @@ func generateParagraphAnalysis(ctx context.Context, apiKey, model, prompt string
 
 	body, _ := io.ReadAll(resp.Body)
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return "", fmt.Errorf("gemini request failed (%d): %s", resp.StatusCode, string(body))
+		return "", &geminiStatusError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 
 	var parsed struct {
@@ -668,17 +1303,36 @@ func anySliceFromMap(m map[string]interface{}, key string) []interface{} {
 	}
 }
 
+// anyToStringMap normalizes a decoded document to map[string]interface{}.
+// JSON files yield Go maps; Mongo decoding can yield bson.M or bson.D for nested documents.
 func anyToStringMap(v interface{}) map[string]interface{} {
 	switch t := v.(type) {
 	case map[string]interface{}:
 		return t
 	case bson.M:
 		return map[string]interface{}(t)
+	case bson.D:
+		return t.Map()
 	default:
 		return nil
 	}
 }
 
+// lastRunOutcome returns the final run outcome as a map, whatever its decoded type.
+func lastRunOutcome(outcomes []interface{}) map[string]interface{} {
+	if len(outcomes) == 0 {
+		return nil
+	}
+	return anyToStringMap(outcomes[len(outcomes)-1])
+}
+
+// runOutcomeFullyPassed reports whether a run outcome passed every test.
+func runOutcomeFullyPassed(outcome map[string]interface{}) bool {
+	testsPassed := numFromMap(outcome, "testsPassed")
+	testsTotal := numFromMap(outcome, "testsTotal")
+	return testsTotal > 0 && testsPassed == testsTotal
+}
+
 func loadUserSessionsFromDisk(userID string, limit int64) ([]database.SessionArtifactDocument, error) {
 	sessionsDir := strings.TrimSpace(os.Getenv("REPORT_CARDS_SESSIONS_DIR"))
 	if sessionsDir == "" {
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/gerdinv/questions-api/database"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestComputeRecencyWeightedSignals(t *testing.T) {
+	newest := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+
+	// session starts ageDays before newest; its only run passes or fails every test.
+	session := func(ageDays float64, passed bool, runs int) database.SessionArtifactDocument {
+		testsPassed := 0
+		if passed {
+			testsPassed = 3
+		}
+		startedAt := newest.Add(-time.Duration(ageDays * float64(day)))
+		return database.SessionArtifactDocument{Summary: bson.M{
+			"startedAt":   float64(startedAt.UnixMilli()),
+			"runCount":    runs,
+			"runOutcomes": []interface{}{bson.M{"testsPassed": testsPassed, "testsTotal": 3}},
+		}}
+	}
+
+	tests := []struct {
+		name         string
+		sessions     []database.SessionArtifactDocument
+		halfLifeDays float64
+		wantPassRate float64
+		wantAvgRuns  float64
+	}{
+		{"no sessions", nil, 7, 0, 0},
+		{"weighting disabled", []database.SessionArtifactDocument{session(0, true, 2)}, 0, 0, 0},
+		{"single session has weight 1", []database.SessionArtifactDocument{session(0, true, 2)}, 7, 1, 2},
+		{
+			// weights 1, 0.5, 0.25: pass = 1.25/1.75, runs = (2 + 2 + 2)/1.75
+			name:         "halves every half-life",
+			sessions:     []database.SessionArtifactDocument{session(0, true, 2), session(7, false, 4), session(14, true, 8)},
+			halfLifeDays: 7,
+			wantPassRate: 1.25 / 1.75,
+			wantAvgRuns:  6 / 1.75,
+		},
+		{
+			// weights 1 and 0.5^(3.5/7) = 1/sqrt(2)
+			name:         "fractional half-life",
+			sessions:     []database.SessionArtifactDocument{session(0, false, 1), session(3.5, true, 3)},
+			halfLifeDays: 7,
+			wantPassRate: (1 / math.Sqrt2) / (1 + 1/math.Sqrt2),
+			wantAvgRuns:  (1 + 3/math.Sqrt2) / (1 + 1/math.Sqrt2),
+		},
+		{
+			// age is measured from the newest session, not now, so old-but-equal ages weigh the same
+			name:         "ages relative to newest session",
+			sessions:     []database.SessionArtifactDocument{session(100, true, 1), session(100, false, 3)},
+			halfLifeDays: 7,
+			wantPassRate: 0.5,
+			wantAvgRuns:  2,
+		},
+		{
+			// an untimestamped session weighs 1 like the newest; 28 days is 4 half-lives = 1/16
+			name: "missing timestamp weighs 1",
+			sessions: []database.SessionArtifactDocument{
+				session(0, false, 1),
+				session(28, true, 1),
+				{Summary: bson.M{"runOutcomes": []interface{}{bson.M{"testsPassed": 3, "testsTotal": 3}}}},
+			},
+			halfLifeDays: 7,
+			wantPassRate: (1.0/16 + 1) / (2 + 1.0/16),
+			wantAvgRuns:  (1 + 1.0/16 + 1) / (2 + 1.0/16),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := computeRecencyWeightedSignals(tt.sessions, tt.halfLifeDays)
+			if got.HalfLifeDays != tt.halfLifeDays {
+				t.Errorf("HalfLifeDays = %v, want %v", got.HalfLifeDays, tt.halfLifeDays)
+			}
+			if math.Abs(got.FullPassRate-tt.wantPassRate) > 1e-9 {
+				t.Errorf("FullPassRate = %v, want %v", got.FullPassRate, tt.wantPassRate)
+			}
+			if math.Abs(got.AverageRuns-tt.wantAvgRuns) > 1e-9 {
+				t.Errorf("AverageRuns = %v, want %v", got.AverageRuns, tt.wantAvgRuns)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gerdinv/questions-api/config"
+	"github.com/gerdinv/questions-api/internal/clients/supabase"
+)
+
+// ErrSupabaseUserNotFound is returned when an email has no matching Supabase account
+var ErrSupabaseUserNotFound = errors.New("no Supabase user with that email")
+
+var (
+	// Cache map: Key is "<supabase url>|<lowercase email>", Value is (UUID, expiration)
+	supabaseIDByEmailCache      = make(map[string]supabaseIDCacheEntry)
+	supabaseIDByEmailCacheMutex sync.RWMutex
+	supabaseIDCacheDuration     = 10 * time.Minute
+)
+
+type supabaseIDCacheEntry struct {
+	id        string
+	expiresAt time.Time
+}
+
+// ResolveSupabaseUserID returns the canonical Supabase UUID for an admin-supplied
+// identifier. Anything without "@" is treated as a UUID already and returned as-is;
+// emails are looked up via the Supabase admin API and cached briefly.
+// Returns ErrSupabaseUserNotFound when the email has no account.
+func ResolveSupabaseUserID(ctx context.Context, identifier string) (string, error) {
+	identifier = strings.TrimSpace(identifier)
+	if !strings.Contains(identifier, "@") {
+		return identifier, nil
+	}
+	email := strings.ToLower(identifier)
+
+	cfg := config.GetConfig()
+	client, err := supabase.NewAdminClient(cfg.SupabaseUrl, cfg.SupabaseServiceRoleKey)
+	if err != nil {
+		return "", err
+	}
+	cacheKey := client.GetURL() + "|" + email
+
+	supabaseIDByEmailCacheMutex.RLock()
+	entry, ok := supabaseIDByEmailCache[cacheKey]
+	supabaseIDByEmailCacheMutex.RUnlock()
+	if ok && time.Now().Before(entry.expiresAt) {
+		return entry.id, nil
+	}
+
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	user, err := client.FindUserByEmail(email)
+	if err != nil {
+		if errors.Is(err, supabase.ErrUserNotFound) {
+			return "", ErrSupabaseUserNotFound
+		}
+		return "", err
+	}
+
+	// Only hits are cached so a freshly signed-up user is found on the next request
+	supabaseIDByEmailCacheMutex.Lock()
+	supabaseIDByEmailCache[cacheKey] = supabaseIDCacheEntry{
+		id:        user.ID,
+		expiresAt: time.Now().Add(supabaseIDCacheDuration),
+	}
+	supabaseIDByEmailCacheMutex.Unlock()
+
+	return user.ID, nil
+}
@@ -14,6 +14,7 @@ import (
 type TelemetryEvent struct {
 	Event      string                 `json:"event"`
 	Properties map[string]interface{} `json:"properties,omitempty"`
+	Tags       map[string]string      `json:"tags,omitempty"` // e.g. {"feature": "hints", "experiment": "onboarding_v2", "variant": "b"}
 	Timestamp  int64                  `json:"timestamp,omitempty"`
 	UserID     string                 `json:"userId,omitempty"`
 	SessionID  string                 `json:"sessionId,omitempty"`
@@ -64,6 +65,7 @@ func CreateTelemetryEvent(c echo.Context) error {
 	doc := database.RunnerEventDocument{
 		Event:           event.Event,
 		Properties:      event.Properties,
+		Tags:            database.NormalizeEventTags(event.Tags),
 		UserID:          user.UserID, // STRICT: Always use JWT UUID
 		Email:           user.Email,  // Metadata only
 		EmailNormalized: strings.ToLower(strings.TrimSpace(user.Email)),
@@ -75,7 +77,7 @@ func CreateTelemetryEvent(c echo.Context) error {
 	}
 
 	// For runner_result events, we might want to do additional processing
-	if event.Event == "runner_result" {
+	if event.Event == database.EventRunnerResult {
 		// Log important metrics
 		if props := event.Properties; props != nil {
 			c.Logger().Infof("Runner result: exitCode=%v, duration=%v, mode=%v, problemId=%v",
@@ -84,7 +86,7 @@ func CreateTelemetryEvent(c echo.Context) error {
 	}
 
 	// Insert into MongoDB
-	err := database.CreateRunnerEvent(&doc)
+	err := database.CreateRunnerEvent(c.Request().Context(), &doc)
 	if err != nil {
 		// Don't fail the request if telemetry fails
 		c.Logger().Errorf("Failed to save telemetry event: %v", err)
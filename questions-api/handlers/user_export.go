@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gerdinv/questions-api/database"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// userDataExport is the bundle returned by GET /users/me/export
+type userDataExport struct {
+	ExportedAt            time.Time                               `json:"exportedAt"`
+	UserID                string                                  `json:"userId"`
+	Email                 string                                  `json:"email"`
+	BrowserSubmissions    []database.BrowserSubmissionDocument    `json:"browserSubmissions"`
+	ReportCards           *database.UserReportCardsDocument       `json:"reportCards"`
+	DecisionTraceSessions []database.DecisionTraceSessionDocument `json:"decisionTraceSessions"`
+	DecisionTraceEvents   []database.DecisionTraceEventDocument   `json:"decisionTraceEvents"`
+}
+
+// ExportMyData handles GET /users/me/export
+// Returns every record we hold for the authenticated user as a downloadable JSON file.
+// Scoped strictly to the JWT identity; there is no way to request another user's data here.
+func ExportMyData(c echo.Context) error {
+	claims, ok := GetUserClaims(c)
+	if !ok || claims.UserID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), DefaultQueryTimeout)
+	defer cancel()
+
+	export := userDataExport{
+		ExportedAt: time.Now().UTC(),
+		UserID:     claims.UserID,
+		Email:      claims.Email,
+	}
+
+	// Submissions - by UUID, plus legacy rows keyed only by the account email
+	submissions, err := database.GetSubmissionsByUser(ctx, claims.UserID, "", 0)
+	if err == nil && claims.Email != "" {
+		var legacy []database.BrowserSubmissionDocument
+		legacy, err = database.GetSubmissionsByUser(ctx, claims.Email, "", 0)
+		submissions = mergeSubmissionsByID(submissions, legacy)
+	}
+	if err != nil {
+		c.Logger().Errorf("ExportMyData: failed to fetch submissions for %s: %v", claims.UserID, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to export submissions",
+		})
+	}
+	if submissions == nil {
+		submissions = []database.BrowserSubmissionDocument{}
+	}
+	export.BrowserSubmissions = submissions
+
+	reportCards, err := database.GetUserReportCardsOrEmpty(ctx, claims.UserID, claims.Email)
+	if err != nil {
+		c.Logger().Errorf("ExportMyData: failed to fetch report cards for %s: %v", claims.UserID, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to export report cards",
+		})
+	}
+	export.ReportCards = reportCards
+
+	sessions, err := database.AppCollections.DecisionTraceSessions.FindSessionsByUser(ctx, claims.UserID)
+	if err != nil {
+		c.Logger().Errorf("ExportMyData: failed to fetch decision trace sessions for %s: %v", claims.UserID, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to export decision trace sessions",
+		})
+	}
+	export.DecisionTraceSessions = sessions
+
+	events, err := database.AppCollections.DecisionTraceEvents.FindEventsByUser(ctx, claims.UserID)
+	if err != nil {
+		c.Logger().Errorf("ExportMyData: failed to fetch decision trace events for %s: %v", claims.UserID, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to export decision trace events",
+		})
+	}
+	export.DecisionTraceEvents = events
+
+	// Stream the bundle straight to the response as an attachment
+	filename := fmt.Sprintf("user-data-export-%s.json", export.ExportedAt.Format("2006-01-02"))
+	c.Response().Header().Set(echo.HeaderContentType, echo.MIMEApplicationJSONCharsetUTF8)
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filename))
+	c.Response().WriteHeader(http.StatusOK)
+
+	enc := json.NewEncoder(c.Response())
+	enc.SetIndent("", "  ")
+	return enc.Encode(export)
+}
+
+// mergeSubmissionsByID combines submission lists read by different identity keys,
+// keeping one copy per _id, newest first
+func mergeSubmissionsByID(lists ...[]database.BrowserSubmissionDocument) []database.BrowserSubmissionDocument {
+	seen := make(map[primitive.ObjectID]bool)
+	merged := make([]database.BrowserSubmissionDocument, 0)
+	for _, list := range lists {
+		for _, s := range list {
+			if seen[s.ID] {
+				continue
+			}
+			seen[s.ID] = true
+			merged = append(merged, s)
+		}
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].CreatedAt.After(merged[j].CreatedAt)
+	})
+	return merged
+}
@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gerdinv/questions-api/database"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMergeSubmissionsByID(t *testing.T) {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	sub := func(id primitive.ObjectID, daysAgo int) database.BrowserSubmissionDocument {
+		return database.BrowserSubmissionDocument{ID: id, CreatedAt: base.AddDate(0, 0, -daysAgo)}
+	}
+	a, b, c := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+
+	byUUID := []database.BrowserSubmissionDocument{sub(a, 0), sub(b, 2)}
+	byEmail := []database.BrowserSubmissionDocument{sub(b, 2), sub(c, 1)} // b matched both keys
+
+	got := mergeSubmissionsByID(byUUID, byEmail)
+	want := []primitive.ObjectID{a, c, b}
+	if len(got) != len(want) {
+		t.Fatalf("merged %d submissions, want %d", len(got), len(want))
+	}
+	for i, id := range want {
+		if got[i].ID != id {
+			t.Errorf("merged[%d] = %s, want %s (newest first)", i, got[i].ID.Hex(), id.Hex())
+		}
+	}
+
+	if got := mergeSubmissionsByID(nil, nil); got == nil || len(got) != 0 {
+		t.Errorf("merging nothing = %#v, want an empty slice", got)
+	}
+}
@@ -0,0 +1,76 @@
+package gemini
+
+import (
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCooldown is how long a rate-limited key is skipped before being retried
+const DefaultCooldown = 60 * time.Second
+
+// KeyPool hands out Gemini API keys round-robin, skipping keys that are cooling
+// down after a 429 so load spreads across per-key rate limits.
+type KeyPool struct {
+	mu            sync.Mutex
+	keys          []string
+	next          int
+	cooldownUntil map[string]time.Time
+}
+
+// NewKeyPool creates a pool from the given keys, ignoring blanks and duplicates
+func NewKeyPool(keys []string) *KeyPool {
+	seen := make(map[string]bool, len(keys))
+	clean := make([]string, 0, len(keys))
+	for _, k := range keys {
+		k = strings.TrimSpace(k)
+		if k == "" || seen[k] {
+			continue
+		}
+		seen[k] = true
+		clean = append(clean, k)
+	}
+	return &KeyPool{
+		keys:          clean,
+		cooldownUntil: make(map[string]time.Time),
+	}
+}
+
+// NewKeyPoolFromEnv reads the comma-separated GEMINI_API_KEYS list, falling back
+// to the single GEMINI_API_KEY when the list is not set
+func NewKeyPoolFromEnv() *KeyPool {
+	if list := strings.TrimSpace(os.Getenv("GEMINI_API_KEYS")); list != "" {
+		return NewKeyPool(strings.Split(list, ","))
+	}
+	return NewKeyPool([]string{os.Getenv("GEMINI_API_KEY")})
+}
+
+// Len returns the number of configured keys
+func (p *KeyPool) Len() int {
+	return len(p.keys)
+}
+
+// Next returns the next key not in cooldown. ok is false when every key is cooling down.
+func (p *KeyPool) Next() (key string, ok bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	for i := 0; i < len(p.keys); i++ {
+		k := p.keys[(p.next+i)%len(p.keys)]
+		if until, cooling := p.cooldownUntil[k]; cooling && now.Before(until) {
+			continue
+		}
+		p.next = (p.next + i + 1) % len(p.keys)
+		return k, true
+	}
+	return "", false
+}
+
+// MarkRateLimited puts key into cooldown for the given duration
+func (p *KeyPool) MarkRateLimited(key string, cooldown time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.cooldownUntil[key] = time.Now().Add(cooldown)
+}
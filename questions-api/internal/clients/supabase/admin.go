@@ -2,11 +2,16 @@ package supabase
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 )
 
+// ErrUserNotFound is returned when no Supabase user has the requested email
+var ErrUserNotFound = errors.New("supabase user not found")
+
 // Client is the Supabase admin client
 type Client struct {
 	url            string
@@ -111,6 +116,56 @@ func (c *Client) GetAllUsers() ([]User, error) {
 	return allUsers, nil
 }
 
+// FindUserByEmail returns the user whose email matches exactly (case-insensitive).
+// GoTrue's filter param is a substring match, so results are re-checked here.
+func (c *Client) FindUserByEmail(email string) (*User, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return nil, ErrUserNotFound
+	}
+
+	perPage := 50
+	for page := 1; ; page++ {
+		req, err := http.NewRequest("GET", fmt.Sprintf("%s/auth/v1/admin/users", c.url), nil)
+		if err != nil {
+			return nil, err
+		}
+
+		q := req.URL.Query()
+		q.Add("filter", email)
+		q.Add("page", fmt.Sprintf("%d", page))
+		q.Add("per_page", fmt.Sprintf("%d", perPage))
+		req.URL.RawQuery = q.Encode()
+
+		c.addHeaders(req)
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		var response ListUsersResponse
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("supabase api returned status %d", resp.StatusCode)
+		}
+		err = json.NewDecoder(resp.Body).Decode(&response)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range response.Users {
+			if strings.ToLower(response.Users[i].Email) == email {
+				return &response.Users[i], nil
+			}
+		}
+		if len(response.Users) < perPage {
+			return nil, ErrUserNotFound
+		}
+	}
+}
+
 func (c *Client) addHeaders(req *http.Request) {
 	req.Header.Set("Authorization", "Bearer "+c.serviceRoleKey)
 	req.Header.Set("apikey", c.serviceRoleKey)
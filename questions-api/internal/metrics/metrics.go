@@ -0,0 +1,121 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	// HTTPRequestsTotal counts handled requests per route and status code.
+	HTTPRequestsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "questions_api_http_requests_total",
+			Help: "Total HTTP requests handled, labeled by method, route and status code.",
+		},
+		[]string{"method", "route", "status"},
+	)
+
+	// HTTPRequestDuration tracks request latency per route.
+	HTTPRequestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "questions_api_http_request_duration_seconds",
+			Help:    "HTTP request latency in seconds, labeled by method and route.",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"method", "route"},
+	)
+
+	// GeminiCallsTotal counts Gemini generateContent calls by model and outcome.
+	GeminiCallsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "questions_api_gemini_calls_total",
+			Help: "Total Gemini API calls, labeled by model and outcome (success|failure).",
+		},
+		[]string{"model", "outcome"},
+	)
+
+	// MongoErrorsTotal counts failed MongoDB commands by command name.
+	MongoErrorsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "questions_api_mongo_errors_total",
+			Help: "Total failed MongoDB commands, labeled by command name.",
+		},
+		[]string{"command"},
+	)
+)
+
+var registerOnce sync.Once
+
+// Register adds all collectors to the default Prometheus registry.
+// Safe to call more than once; only the first call registers.
+func Register() {
+	registerOnce.Do(func() {
+		prometheus.MustRegister(
+			HTTPRequestsTotal,
+			HTTPRequestDuration,
+			GeminiCallsTotal,
+			MongoErrorsTotal,
+		)
+	})
+}
+
+// Handler serves the Prometheus exposition format for GET /metrics.
+func Handler() echo.HandlerFunc {
+	return echo.WrapHandler(promhttp.Handler())
+}
+
+// Middleware records request count, status and duration for every route.
+// The route label uses the registered path (e.g. /projects/:id) to keep cardinality bounded.
+func Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			err := next(c)
+
+			status := responseStatus(c, err)
+
+			route := c.Path()
+			if route == "" {
+				route = "unmatched"
+			}
+			method := c.Request().Method
+
+			HTTPRequestsTotal.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+			HTTPRequestDuration.WithLabelValues(method, route).Observe(time.Since(start).Seconds())
+			return err
+		}
+	}
+}
+
+// responseStatus is the status the client receives. A returned error has not been
+// written yet (echo's error handler runs after the middleware chain), so it is mapped
+// the way that handler will: an *echo.HTTPError keeps its code, anything else is a 500.
+func responseStatus(c echo.Context, err error) int {
+	if err == nil || c.Response().Committed {
+		return c.Response().Status
+	}
+	if he, ok := err.(*echo.HTTPError); ok {
+		return he.Code
+	}
+	return http.StatusInternalServerError
+}
+
+// RecordGeminiCall increments the Gemini call counter for a finished request.
+func RecordGeminiCall(model string, err error) {
+	outcome := "success"
+	if err != nil {
+		outcome = "failure"
+	}
+	GeminiCallsTotal.WithLabelValues(model, outcome).Inc()
+}
+
+// RecordMongoError increments the Mongo error counter for a failed command.
+func RecordMongoError(command string) {
+	MongoErrorsTotal.WithLabelValues(command).Inc()
+}
@@ -0,0 +1,49 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMiddlewareRecordsClientStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler echo.HandlerFunc
+		want    string
+	}{
+		{"ok", func(c echo.Context) error { return c.NoContent(http.StatusOK) }, "200"},
+		{"written error response", func(c echo.Context) error { return c.JSON(http.StatusBadRequest, echo.Map{"error": "bad"}) }, "400"},
+		{"http error", func(c echo.Context) error { return echo.NewHTTPError(http.StatusNotFound) }, "404"},
+		{"generic error", func(c echo.Context) error { return errors.New("boom") }, "500"},
+		{"panic", func(c echo.Context) error { panic("boom") }, "500"},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			e.Logger.SetOutput(io.Discard)
+			e.Use(Middleware())
+			e.Use(middleware.Recover())
+			route := fmt.Sprintf("/metrics-test/%d", i)
+			e.GET(route, tt.handler)
+
+			before := testutil.ToFloat64(HTTPRequestsTotal.WithLabelValues(http.MethodGet, route, tt.want))
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, route, nil))
+
+			if got := fmt.Sprint(rec.Code); got != tt.want {
+				t.Fatalf("response status = %s, want %s", got, tt.want)
+			}
+			if after := testutil.ToFloat64(HTTPRequestsTotal.WithLabelValues(http.MethodGet, route, tt.want)); after != before+1 {
+				t.Errorf("requests_total{status=%q} went from %v to %v, want +1", tt.want, before, after)
+			}
+		})
+	}
+}
@@ -1,11 +1,19 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/gerdinv/questions-api/config"
 	"github.com/gerdinv/questions-api/database"
+	"github.com/gerdinv/questions-api/internal/metrics"
 	"github.com/gerdinv/questions-api/routes"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
@@ -18,6 +26,9 @@ func main() {
 	// This must happen before any call to config.GetConfig()
 	config.Init(envExampleContract)
 
+	// Register Prometheus collectors before anything can record into them
+	metrics.Register()
+
 	// Connect to MongoDB
 	database.ConnectMongoDB()
 
@@ -31,12 +42,19 @@ func main() {
 
 	e := echo.New()
 
+	// Client IP for rate limits and telemetry: the right-most X-Forwarded-For entry
+	// not from a private/loopback proxy (App Runner's load balancer appends the real
+	// client). Without this, c.RealIP() trusts whatever X-Forwarded-For the client sends.
+	e.IPExtractor = echo.ExtractIPFromXFFHeader()
+
 	// CRITICAL: CORS must be the FIRST middleware to handle preflight OPTIONS requests
 	// before any other middleware can interfere or return errors
 	routes.ConfigureCORS(e)
 
 	// Configure other middleware AFTER CORS
 	e.Use(middleware.Logger())
+	// Metrics wraps Recover so requests that panic are still counted (as 500s)
+	e.Use(metrics.Middleware())
 	e.Use(middleware.Recover())
 
 	// Register routes
@@ -49,6 +67,36 @@ func main() {
 		port = 1323
 	}
 
-	log.Printf("🚀 Starting server on port %d", port)
-	e.Logger.Fatal(e.Start(fmt.Sprintf(":%d", port)))
+	// Cancelled on SIGINT/SIGTERM; background workers stop when it is done
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	var workers sync.WaitGroup
+	if cfg.DecisionTraceReaperEnabled {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			database.RunStaleSessionReaper(ctx,
+				time.Duration(cfg.DecisionTraceReaperIntervalMinutes)*time.Minute,
+				time.Duration(cfg.DecisionTraceSessionMaxIdleMinutes)*time.Minute,
+			)
+		}()
+	}
+
+	go func() {
+		log.Printf("🚀 Starting server on port %d", port)
+		if err := e.Start(fmt.Sprintf(":%d", port)); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			e.Logger.Fatal(err)
+		}
+	}()
+
+	<-ctx.Done()
+	log.Println("🛑 Shutting down server...")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := e.Shutdown(shutdownCtx); err != nil {
+		log.Printf("⚠️  Server shutdown error: %v", err)
+	}
+	workers.Wait()
 }
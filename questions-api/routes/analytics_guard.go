@@ -0,0 +1,29 @@
+package routes
+
+import (
+	"github.com/gerdinv/questions-api/database"
+	"github.com/labstack/echo/v4"
+)
+
+// Response headers identifying which database analytics numbers were read from
+const (
+	HeaderAnalyticsDB  = "X-Analytics-DB"
+	HeaderAnalyticsEnv = "X-Analytics-Env"
+)
+
+// AnalyticsSourceMiddleware labels analytics responses with the app DB and NODE_ENV
+// they were computed from. A production process pointed at a dev database never
+// gets this far: ConnectMongoDB refuses to start.
+func AnalyticsSourceMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			setAnalyticsSourceHeaders(c)
+			return next(c)
+		}
+	}
+}
+
+func setAnalyticsSourceHeaders(c echo.Context) {
+	c.Response().Header().Set(HeaderAnalyticsDB, database.GetActiveAppDBName())
+	c.Response().Header().Set(HeaderAnalyticsEnv, database.GetActiveNodeEnv())
+}
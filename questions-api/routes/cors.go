@@ -58,6 +58,8 @@ func ConfigureCORS(e *echo.Echo) {
 		},
 		ExposeHeaders: []string{
 			"X-Runner-Contract-Version",
+			HeaderAnalyticsDB,
+			HeaderAnalyticsEnv,
 		},
 		AllowCredentials: true,
 		MaxAge:           86400, // 24 hours
@@ -6,6 +6,7 @@ import (
 
 	"github.com/gerdinv/questions-api/config"
 	"github.com/gerdinv/questions-api/handlers"
+	"github.com/gerdinv/questions-api/internal/metrics"
 	"github.com/labstack/echo/v4"
 )
 
@@ -32,6 +33,9 @@ func RegisterRoutes(e *echo.Echo) {
 		})
 	})
 
+	// Prometheus scrape endpoint (request, Gemini and Mongo error metrics)
+	e.GET("/metrics", metrics.Handler())
+
 	// Health check with database status (public but limited info)
 	e.GET("/health/db", handlers.GetHealthWithDB)
 
@@ -47,9 +51,13 @@ func RegisterRoutes(e *echo.Echo) {
 	// Webhook endpoint for referral applications (protected by X-Webhook-Secret header)
 	e.POST("/webhooks/referral", handlers.CreateReferralApplication)
 
+	// Public referral intake (rate-limited per IP/email; signed integration requests skip limits)
+	e.POST("/referrals", handlers.SubmitReferralApplication)
+
 	// Public browser-based endpoints (no auth required)
 	e.GET("/problems", handlers.GetProblems)
 	e.GET("/problems/:id", handlers.GetProblemByID)
+	e.GET("/projects/tags", handlers.GetProjectTags) // Distinct normalized tags with usage counts
 	e.GET("/projects/:id", handlers.GetProjectByID)
 	e.GET("/projects", handlers.GetProjects)
 
@@ -61,6 +69,7 @@ func RegisterRoutes(e *echo.Echo) {
 	e.POST("/submissions", handlers.CreateBrowserSubmission, jwtMiddleware)
 	e.POST("/api/submissions", handlers.CreateBrowserSubmission, jwtMiddleware) // Alias for backwards compatibility
 	e.GET("/projects/:id/submissions", handlers.GetProjectSubmissions, jwtMiddleware)
+	e.GET("/projects/:id/next-recommended", handlers.GetNextRecommendedProjects, jwtMiddleware)
 
 	// Telemetry endpoints - JWT required; handler uses GetUserClaims(c) for user ID
 	e.POST("/telemetry", handlers.CreateTelemetryEvent, jwtMiddleware)
@@ -78,12 +87,19 @@ func RegisterRoutes(e *echo.Echo) {
 	e.GET("/api/profiles/me", handlers.GetMyProfile, jwtMiddleware)     // Alias for backwards compatibility
 	e.PATCH("/api/profiles/me", handlers.PatchMyProfile, jwtMiddleware) // Alias for backwards compatibility
 
+	// Self-service data export (JWT-protected, scoped to the caller)
+	e.GET("/users/me/export", handlers.ExportMyData, jwtMiddleware)
+
 	// Report cards endpoints (JWT-protected)
 	e.GET("/report-cards/me", handlers.GetMyReportCards, jwtMiddleware)
+	e.GET("/report-cards/shared-with-me", handlers.GetReportCardsSharedWithMe, jwtMiddleware)
 	e.POST("/report-cards/jobs", handlers.ReportCardsJob, jwtMiddleware)
 	e.GET("/api/report-cards/me", handlers.GetMyReportCards, jwtMiddleware)  // Alias
 	e.POST("/api/report-cards/jobs", handlers.ReportCardsJob, jwtMiddleware) // Alias
 
+	// Narrative-vs-evidence discrepancies behind narrativeFlagCount (admins may pass ?userId=)
+	e.GET("/report-cards/discrepancies", handlers.GetReportCardDiscrepancies, jwtMiddleware)
+
 	// Boss fight endpoints (JWT-protected)
 	e.GET("/boss-fight/start", handlers.StartBossFight, jwtMiddleware)
 	e.GET("/boss-fight/history", handlers.GetBossFightHistory, jwtMiddleware)
@@ -94,8 +110,11 @@ func RegisterRoutes(e *echo.Echo) {
 	// Decision Trace Replay endpoints (JWT-protected)
 	e.POST("/decision-trace/event", handlers.CreateDecisionTraceEvent, jwtMiddleware)
 	e.GET("/decision-trace/session", handlers.GetDecisionTraceSession, jwtMiddleware)
+	e.GET("/decision-trace/session/:id/churn", handlers.GetDecisionTraceSessionChurn, jwtMiddleware)
 	e.GET("/decision-trace/timeline", handlers.GetDecisionTraceTimeline, jwtMiddleware)
 	e.GET("/decision-trace/event", handlers.GetDecisionTraceEvent, jwtMiddleware)
+	e.GET("/decision-trace/latest-event", handlers.GetDecisionTraceLatestEvent, jwtMiddleware)
+	e.GET("/decision-trace/code-evolution", handlers.GetDecisionTraceCodeEvolution, jwtMiddleware)
 
 	// For admin group, still use Group but with proper prefix
 	authGroup := e.Group("") // keep for admin routes
@@ -113,18 +132,33 @@ func RegisterRoutes(e *echo.Echo) {
 	adminGroup.GET("/projects", handlers.GetProjects)        // List all projects for admin
 	adminGroup.GET("/projects/:id", handlers.GetProjectByID) // Get single project for admin
 	adminGroup.POST("/projects", handlers.CreateProject)
+	adminGroup.POST("/projects/import", handlers.ImportProjects) // Bulk upsert keyed on projectNumber
+	adminGroup.GET("/projects/export", handlers.ExportProjects)  // Full catalog in import shape (streamed)
 	adminGroup.PUT("/projects/:id", handlers.UpdateProject)
 	adminGroup.DELETE("/projects/:id", handlers.DeleteProject)
+	adminGroup.GET("/projects/:id/editor-signals", handlers.GetProjectEditorSignals)           // Paste/submit integrity stats
+	adminGroup.GET("/projects/:id/submissions/stream", handlers.StreamProjectSubmissions)      // All submissions as NDJSON
+	adminGroup.GET("/projects/:id/referencing-modules", handlers.GetProjectReferencingModules) // Modules embedding the project
 	adminGroup.GET("/questions", handlers.GetAllQuestions)
-	adminGroup.GET("/metrics", handlers.GetOverallMetricsForAdmin)
-	adminGroup.GET("/metrics/funnel", handlers.GetFunnelMetrics)                                        // Onboarding funnel metrics
-	adminGroup.GET("/submissions/latest", handlers.GetLatestSubmissions)                                // Latest submissions feed
-	adminGroup.GET("/roster", handlers.GetRoster)                                                       // New Supabase-backed roster
-	adminGroup.GET("/users/search", handlers.GetUserSuggestions)                                        // User search endpoint
-	adminGroup.GET("/users/:email/metrics", handlers.GetUserDetailedMetrics)                            // New: detailed user metrics
-	adminGroup.GET("/users/:email/projects/:projectId/submissions", handlers.GetUserProjectSubmissions) // Get submissions for specific user + project
-	adminGroup.POST("/indexes/create", handlers.CreateAnalyticsIndexes)                                 // New: create analytics indexes
-	adminGroup.GET("/metrics/user", handlers.GetMetricsForUser)
+	// Analytics responses carry X-Analytics-DB/Env naming the DB the numbers came from
+	analyticsGuard := AnalyticsSourceMiddleware()
+	adminGroup.GET("/metrics", handlers.GetOverallMetricsForAdmin, analyticsGuard)
+	adminGroup.GET("/metrics/funnel", handlers.GetFunnelMetrics, analyticsGuard)                                        // Onboarding funnel metrics
+	adminGroup.GET("/metrics/sandbox-boot", handlers.GetSandboxBootMetrics, analyticsGuard)                             // Pyodide boot time trends
+	adminGroup.GET("/metrics/pyodide-versions", handlers.GetPyodideVersionMetrics, analyticsGuard)                      // Pyodide version adoption
+	adminGroup.GET("/metrics/activity-heatmap", handlers.GetActivityHeatmap, analyticsGuard)                            // Submissions by weekday x hour
+	adminGroup.GET("/metrics/ran-never-submitted", handlers.GetRanNeverSubmittedUsers, analyticsGuard)                  // Ran a real project, never submitted one
+	adminGroup.GET("/metrics/warmup-to-curriculum", handlers.GetWarmupToCurriculumMetrics, analyticsGuard)              // Time from warmup submit to first real project run
+	adminGroup.GET("/metrics/warmup-by-source", handlers.GetWarmupBySource, analyticsGuard)                             // Warmup run/submit counts per acquisition source tag
+	adminGroup.GET("/submissions/latest", handlers.GetLatestSubmissions, analyticsGuard)                                // Latest submissions feed
+	adminGroup.GET("/roster", handlers.GetRoster, analyticsGuard)                                                       // New Supabase-backed roster
+	adminGroup.GET("/users/search", handlers.GetUserSuggestions)                                                        // User search endpoint
+	adminGroup.GET("/users/:email/metrics", handlers.GetUserDetailedMetrics, analyticsGuard)                            // New: detailed user metrics
+	adminGroup.GET("/users/:email/projects/:projectId/submissions", handlers.GetUserProjectSubmissions, analyticsGuard) // Get submissions for specific user + project
+	adminGroup.POST("/indexes/create", handlers.CreateAnalyticsIndexes)                                                 // New: create analytics indexes
+	adminGroup.GET("/metrics/user", handlers.GetMetricsForUser, analyticsGuard)
+	adminGroup.GET("/metrics/platform", handlers.GetPlatformAnalytics, analyticsGuard) // Platform analytics limited to ?sections=
+	adminGroup.GET("/users/:id/telemetry", handlers.GetUserTelemetry)                  // Raw runner_events for one user (debugging)
 
 	// Beta whitelist management (admin only)
 	adminGroup.POST("/whitelist", handlers.AddToWhitelist)
@@ -134,11 +168,22 @@ func RegisterRoutes(e *echo.Echo) {
 	adminGroup.POST("/users/backfill", handlers.BackfillUsersFromSupabase)
 
 	// Diagnostics (admin only)
-	adminGroup.GET("/diagnostics", handlers.GetDiagnostics)
+	adminGroup.GET("/diagnostics", handlers.GetDiagnostics, analyticsGuard)
+
+	// Report card auditing (admin only)
+	adminGroup.GET("/report-cards/reliability-overview", handlers.GetReportCardReliabilityOverview)
+	adminGroup.GET("/report-cards/usage", handlers.GetReportCardUsage)
+	adminGroup.POST("/report-cards/cleanup", handlers.CleanupOrphanedReportCards) // Archive/remove entries from failed generation
+
+	// Decision trace live feed (admin only)
+	adminGroup.GET("/decision-trace/recent", handlers.GetRecentDecisionTraceEvents)
 
 	// Referral applications management (admin only)
 	adminGroup.GET("/referrals", handlers.GetReferralApplications)
 	adminGroup.GET("/referrals/review", handlers.GetReferralApplicationsNeedingReview)
+	adminGroup.GET("/referrals/stats", handlers.GetReferralPipelineStats)
+	adminGroup.PATCH("/referrals/:id", handlers.UpdateReferralApplication)
+	adminGroup.POST("/referrals/:id/match", handlers.MatchReferralApplication)
 
 	// Public routes
 	e.GET("/question/:number", handlers.GetQuestion)
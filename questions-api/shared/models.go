@@ -321,8 +321,12 @@ type ProjectDocument struct {
 	TestFile      ProjectTestFile    `bson:"testFile" json:"testFile"`
 	Category      string             `bson:"category" json:"category"`
 	Tags          []string           `bson:"tags" json:"tags"`
-	CreatedAt     time.Time          `bson:"createdAt" json:"createdAt"`
-	UpdatedAt     time.Time          `bson:"updatedAt" json:"updatedAt"`
+	// RequiredPasses is how many passing submissions count as "completed" (0 or 1 = first pass)
+	RequiredPasses int `bson:"requiredPasses,omitempty" json:"requiredPasses,omitempty"`
+	// MinTestCount is how many tests a run must report to count as passed (0 or 1 = any)
+	MinTestCount int       `bson:"minTestCount,omitempty" json:"minTestCount,omitempty"`
+	CreatedAt    time.Time `bson:"createdAt" json:"createdAt"`
+	UpdatedAt    time.Time `bson:"updatedAt" json:"updatedAt"`
 }
 
 type ProjectTestFile struct {
@@ -339,6 +343,10 @@ type ProjectPayload struct {
 	TestFile     ProjectTestFile   `json:"testFile"`
 	Category     string            `json:"category"`
 	Tags         []string          `json:"tags"`
+	// RequiredPasses is optional; omitted or 1 keeps single-pass completion
+	RequiredPasses int `json:"requiredPasses,omitempty"`
+	// MinTestCount is optional; omitted or 1 accepts any non-empty test run
+	MinTestCount int `json:"minTestCount,omitempty"`
 }
 
 // Admin Analytics Models
@@ -350,9 +358,11 @@ type UserDetailedMetrics struct {
 	ProjectStats      UserProjectStats        `json:"projectStats"`
 	RecentSubmissions []RecentSubmission      `json:"recentSubmissions"`
 	ProjectAttempts   []ProjectAttemptMetrics `json:"projectAttempts"`
+	RunToSubmitRatio  *float64                `json:"runToSubmitRatio"`
 	LastSeenBrowser   string                  `json:"lastSeenBrowser"`
 	LastSeenOS        string                  `json:"lastSeenOS"`
 	LastSeenDevice    string                  `json:"lastSeenDevice"`
+	LastSeen          *time.Time              `json:"lastSeen"` // Most recent runner event; null if never seen
 }
 
 type UserProjectStats struct {
@@ -383,6 +393,7 @@ type ProjectAttemptMetrics struct {
 	AttemptsBeforePass int                 `json:"attemptsBeforePass"`
 	RunAttempts        int                 `json:"runAttempts"`
 	SubmitAttempts     int                 `json:"submitAttempts"`
+	RunToSubmitRatio   *float64            `json:"runToSubmitRatio"` // runAttempts / submitAttempts; null with no submits
 	Completed          bool                `json:"completed"`
 	AvgExecutionTimeMs int64               `json:"avgExecutionTimeMs"`
 	AvgTTFRMs          int64               `json:"avgTTFRMs"`
@@ -399,10 +410,10 @@ type PlatformAnalytics struct {
 	DAU              int               `json:"dau"`
 	WAU              int               `json:"wau"`
 	MAU              int               `json:"mau"`
-	DAUTrend         []TrendDataPoint  `json:"dauTrend"`
-	WAUTrend         []TrendDataPoint  `json:"wauTrend"`
-	ExecutionMetrics *ExecutionMetrics `json:"executionMetrics"`
-	BrowserAnalytics *BrowserAnalytics `json:"browserAnalytics"`
+	DAUTrend         []TrendDataPoint  `json:"dauTrend,omitempty"`         // Omitted when the trends section is skipped
+	WAUTrend         []TrendDataPoint  `json:"wauTrend,omitempty"`         // Omitted when the trends section is skipped
+	ExecutionMetrics *ExecutionMetrics `json:"executionMetrics,omitempty"` // Omitted when the execution section is skipped
+	BrowserAnalytics *BrowserAnalytics `json:"browserAnalytics,omitempty"` // Omitted when the browser section is skipped
 }
 
 type TrendDataPoint struct {
@@ -434,9 +445,10 @@ type ProjectExecution struct {
 // Browser/Device Analytics Models
 
 type BrowserAnalytics struct {
-	BrowserBreakdown []BrowserStat `json:"browserBreakdown"`
-	OSBreakdown      []OSStat      `json:"osBreakdown"`
-	DeviceBreakdown  []DeviceStat  `json:"deviceBreakdown"`
+	BrowserBreakdown  []BrowserStat  `json:"browserBreakdown"`
+	OSBreakdown       []OSStat       `json:"osBreakdown"`
+	DeviceBreakdown   []DeviceStat   `json:"deviceBreakdown"`
+	LanguageBreakdown []LanguageStat `json:"languageBreakdown"` // From browser_submissions, not telemetry
 }
 
 type BrowserStat struct {
@@ -457,6 +469,12 @@ type DeviceStat struct {
 	Percentage float64 `json:"percentage"`
 }
 
+type LanguageStat struct {
+	Language   string  `json:"language"`
+	Count      int     `json:"count"`
+	Percentage float64 `json:"percentage"`
+}
+
 // Referral Application Models
 
 type ReferralApplicationDocument struct {
@@ -486,6 +504,7 @@ type ReferralApplicationDocument struct {
 
 	// Matching
 	UserID            *primitive.ObjectID `json:"userId,omitempty" bson:"userId,omitempty"`
+	SupabaseUserID    string              `json:"supabaseUserId,omitempty" bson:"supabaseUserId,omitempty"`
 	MatchedBy         string              `json:"matchedBy" bson:"matchedBy"`
 	MatchConfidence   string              `json:"matchConfidence" bson:"matchConfidence"`
 	NeedsManualReview bool                `json:"needsManualReview" bson:"needsManualReview"`
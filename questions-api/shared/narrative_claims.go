@@ -0,0 +1,84 @@
+package shared
+
+import (
+	_ "embed"
+	"strings"
+	"unicode"
+)
+
+// defaultNarrativeClaimPhrases is the embedded list of success-claim phrases.
+//
+//go:embed narrative_claim_phrases.txt
+var defaultNarrativeClaimPhrases string
+
+// NarrativeClaimPhrases returns the lowercase phrases that mark a narrative as
+// claiming a full pass. A non-empty override (NARRATIVE_CLAIM_PHRASES, comma-separated)
+// replaces the embedded defaults.
+func NarrativeClaimPhrases(override string) []string {
+	if override = strings.TrimSpace(override); override != "" {
+		return normalizePhrases(strings.Split(override, ","))
+	}
+	return normalizePhrases(strings.Split(defaultNarrativeClaimPhrases, "\n"))
+}
+
+// claimNegations are words that, right before a phrase, turn the claim around
+// ("not all tests passed", "never fully passing")
+var claimNegations = map[string]bool{
+	"not": true, "never": true, "no": true, "nor": true,
+	"didn't": true, "don't": true, "doesn't": true, "isn't": true, "aren't": true, "wasn't": true, "weren't": true,
+}
+
+// NarrativeClaimsFullPass reports whether the narrative contains any success-claim phrase
+// as whole words, not immediately preceded (within two words) by a negation.
+func NarrativeClaimsFullPass(narrative string, phrases []string) bool {
+	narrative = strings.ToLower(narrative)
+	for _, p := range phrases {
+		for start := 0; ; {
+			i := strings.Index(narrative[start:], p)
+			if i < 0 {
+				break
+			}
+			i += start
+			end := i + len(p)
+			if isWordBoundary(narrative, i-1) && isWordBoundary(narrative, end) && !negatedBefore(narrative[:i]) {
+				return true
+			}
+			start = i + 1
+		}
+	}
+	return false
+}
+
+// isWordBoundary reports whether the byte at i is outside the text or not part of a word
+func isWordBoundary(text string, i int) bool {
+	if i < 0 || i >= len(text) {
+		return true
+	}
+	r := rune(text[i])
+	return r < 0x80 && !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\''
+}
+
+// negatedBefore reports whether one of the last two words of prefix is a negation
+func negatedBefore(prefix string) bool {
+	words := strings.FieldsFunc(strings.ReplaceAll(prefix, "’", "'"), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+	for i := len(words) - 1; i >= 0 && i >= len(words)-2; i-- {
+		if claimNegations[words[i]] {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizePhrases(raw []string) []string {
+	out := make([]string, 0, len(raw))
+	for _, p := range raw {
+		p = strings.ToLower(strings.TrimSpace(p))
+		if p == "" || strings.HasPrefix(p, "#") {
+			continue
+		}
+		out = append(out, p)
+	}
+	return out
+}
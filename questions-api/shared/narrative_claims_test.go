@@ -0,0 +1,45 @@
+package shared
+
+import "testing"
+
+func TestNarrativeClaimsFullPass(t *testing.T) {
+	phrases := NarrativeClaimPhrases("")
+
+	tests := []struct {
+		narrative string
+		want      bool
+	}{
+		{"All tests passed on the final run.", true},
+		{"The student's code passes all tests now", true},
+		{"Everything is green after the fix", true},
+		{"Reached 100% passing by the end", true},
+		{"Not all tests passed yet", false},
+		{"The student did not fully passing tests", false},
+		{"It never passes all tests", false},
+		{"They didn’t pass every test; they never passed every test", false},
+		{"100% of tests in the first file pass", false},
+		{"Not all tests are passing", false},
+		{"Most tests are passing", false},
+		{"Failed twice, but in the end all tests passed", true},
+		{"No errors, and all tests passed", true},
+		{"Full passes are rare; nothing passed", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := NarrativeClaimsFullPass(tt.narrative, phrases); got != tt.want {
+			t.Errorf("NarrativeClaimsFullPass(%q) = %v, want %v", tt.narrative, got, tt.want)
+		}
+	}
+}
+
+func TestNarrativeClaimPhrasesOverride(t *testing.T) {
+	got := NarrativeClaimPhrases(" Solved It , ,all green ")
+	if len(got) != 2 || got[0] != "solved it" || got[1] != "all green" {
+		t.Errorf("NarrativeClaimPhrases(override) = %q", got)
+	}
+	for _, p := range NarrativeClaimPhrases("") {
+		if p == "100% of tests" || p == "tests are passing" {
+			t.Errorf("default phrases still contain partial claim %q", p)
+		}
+	}
+}